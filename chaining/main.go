@@ -34,6 +34,7 @@ func main() {
 		hashTable.set(employee.name, employee.phone)
 	}
 	hashTable.dump()
+	hashTable.stats().dump()
 
 	fmt.Printf("Table contains Sally Owens: %t\n", hashTable.contains("Sally Owens"))
 	fmt.Printf("Table contains Dan Deever: %t\n", hashTable.contains("Dan Deever"))
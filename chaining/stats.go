@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ProbeStats summarizes a set of probe sequence lengths.
+type ProbeStats struct {
+	Count     int
+	Mean      float64
+	Median    int
+	P99       int
+	Max       int
+	Histogram []int // Histogram[n] is the number of probe sequences of length n.
+}
+
+// Stats describes the occupancy and chain lengths of a hash table.
+type Stats struct {
+	NumBuckets   int
+	Live         int
+	EmptyBuckets int
+	LoadFactor   float64 // Live entries divided by the number of buckets.
+
+	Successful   ProbeStats // Entries examined to find each live key.
+	Unsuccessful ProbeStats // Entries examined to miss, one for each bucket.
+
+	ChainLengths []int // ChainLengths[n] is the number of buckets holding n entries.
+	MaxChain     int
+}
+
+// Summarize a set of probe sequence lengths.
+func newProbeStats(lengths []int) ProbeStats {
+	probeStats := ProbeStats{Count: len(lengths)}
+	if len(lengths) == 0 {
+		return probeStats
+	}
+
+	sorted := append([]int(nil), lengths...)
+	sort.Ints(sorted)
+
+	total := 0
+	for _, length := range sorted {
+		total += length
+	}
+	probeStats.Mean = float64(total) / float64(len(sorted))
+	probeStats.Median = percentile(sorted, 50)
+	probeStats.P99 = percentile(sorted, 99)
+	probeStats.Max = sorted[len(sorted)-1]
+
+	probeStats.Histogram = make([]int, probeStats.Max+1)
+	for _, length := range sorted {
+		probeStats.Histogram[length]++
+	}
+	return probeStats
+}
+
+// Return the nearest-rank percentile of a sorted, non-empty slice.
+func percentile(sorted []int, p int) int {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Collect the stats for the table.
+func (hashTable *ChainingHashTable) stats() Stats {
+	stats := Stats{NumBuckets: hashTable.numBuckets}
+
+	var successful []int
+	var unsuccessful []int
+	for _, bucket := range hashTable.buckets {
+		stats.Live += len(bucket)
+		if len(bucket) == 0 {
+			stats.EmptyBuckets++
+		}
+
+		// Record the chain length.
+		for len(stats.ChainLengths) <= len(bucket) {
+			stats.ChainLengths = append(stats.ChainLengths, 0)
+		}
+		stats.ChainLengths[len(bucket)]++
+		if len(bucket) > stats.MaxChain {
+			stats.MaxChain = len(bucket)
+		}
+
+		// Finding the nth entry examines n entries. A miss examines the whole chain.
+		for i := range bucket {
+			successful = append(successful, i+1)
+		}
+		unsuccessful = append(unsuccessful, len(bucket))
+	}
+	if hashTable.numBuckets > 0 {
+		stats.LoadFactor = float64(stats.Live) / float64(hashTable.numBuckets)
+	}
+	stats.Successful = newProbeStats(successful)
+	stats.Unsuccessful = newProbeStats(unsuccessful)
+	return stats
+}
+
+// Display the stats.
+func (stats Stats) dump() {
+	fmt.Printf("Buckets: %d, live: %d, empty buckets: %d, load factor: %.3f\n",
+		stats.NumBuckets, stats.Live, stats.EmptyBuckets, stats.LoadFactor)
+	stats.Successful.dump("Successful lookups")
+	stats.Unsuccessful.dump("Unsuccessful lookups")
+	fmt.Printf("Max chain: %d\n", stats.MaxChain)
+	fmt.Printf("Chain lengths (length:count):")
+	for length, count := range stats.ChainLengths {
+		if count > 0 {
+			fmt.Printf(" %d:%d", length, count)
+		}
+	}
+	fmt.Println()
+}
+
+// Display the probe stats with a label.
+func (probeStats ProbeStats) dump(label string) {
+	fmt.Printf("%s: mean %.3f, median %d, p99 %d, max %d\n",
+		label, probeStats.Mean, probeStats.Median, probeStats.P99, probeStats.Max)
+	fmt.Printf("    Histogram (length:count):")
+	for length, count := range probeStats.Histogram {
+		if count > 0 {
+			fmt.Printf(" %d:%d", length, count)
+		}
+	}
+	fmt.Println()
+}
@@ -61,6 +61,7 @@ func main() {
 	bigHashTable.dumpConcise()
 	fmt.Printf("Average probe sequence length: %f\n",
 		bigHashTable.aveProbeSequenceLength())
+	bigHashTable.stats().dump()
 }
 
 // djb2 hash1 function. See http://www.cse.yorku.ca/~oz/hash.html.
@@ -142,23 +143,45 @@ func (hashTable *DoubleHashTable) find(name string) (int, int) {
 	hash1 := hash1(name) % hashTable.capacity
 	hash2 := hash2(name) % hashTable.capacity
 
+	// A step of 0 would probe the same spot every time.
+	if hash2 == 0 {
+		hash2 = 1
+	}
+
+	// Set deletedIndex to -1. This will be the index of the first deleted item we come across (if we find one).
+	deletedIndex := -1
+
 	// Follow the probe sequence
 	for i := 0; i < hashTable.capacity; i++ {
 		// Calculate a position in the probe sequence
 		index := (hash1 + i*hash2) % hashTable.capacity // Double hashing.
 
-		// If the spot is empty, the key is not in the table
+		// If this spot is empty, then the target is not in the table.
 		if hashTable.employees[index] == nil {
+			// If we found a deleted item earlier, return its index so it can be reused.
+			if deletedIndex >= 0 {
+				return deletedIndex, i + 1
+			}
 			return index, i + 1
 		}
 
-		// If the spot contains the key, return the index of that spot
-		if hashTable.employees[index].name == name {
+		// Remember the first deleted spot. A deleted spot never holds the key.
+		if hashTable.employees[index].deleted {
+			if deletedIndex == -1 {
+				deletedIndex = index
+			}
+		} else if hashTable.employees[index].name == name {
+			// If the spot contains the key, return the index of that spot
 			return index, i + 1
 		}
 	}
 
-	// If the key is not found and the table is full, return -1
+	// If the key is not found and the table is full, reuse a deleted spot if we found one.
+	if deletedIndex >= 0 {
+		return deletedIndex, hashTable.capacity
+	}
+
+	// Otherwise return -1
 	return -1, hashTable.capacity
 }
 
@@ -167,6 +190,9 @@ func (hashTable *DoubleHashTable) probe(name string) int {
 	// Hash the key.
 	hash1 := hash1(name) % hashTable.capacity
 	hash2 := hash2(name) % hashTable.capacity
+	if hash2 == 0 {
+		hash2 = 1
+	}
 
 	fmt.Printf("Probing %s (%d, %d)\n", name, hash1, hash2)
 
@@ -252,12 +278,12 @@ func (hashTable *DoubleHashTable) set(name string, phone string) {
 		panic("Hash table is full")
 	}
 
-	// If the slice entry at the key's index is not nil, change the phone value
-	if hashTable.employees[index] != nil {
-		hashTable.employees[index].phone = phone
-	} else {
-		// If the slice entry at the key's index is nil, create a new Employee struct
+	// If the slice entry at the key's index is nil or deleted, create a new Employee struct
+	if hashTable.employees[index] == nil || hashTable.employees[index].deleted {
 		hashTable.employees[index] = &Employee{name: name, phone: phone}
+	} else {
+		// Otherwise, find found the key. Change the phone value
+		hashTable.employees[index].phone = phone
 	}
 }
 
@@ -276,6 +302,11 @@ func (hashTable *DoubleHashTable) get(name string) string {
 		return ""
 	}
 
+	// If the slice entry at the index is deleted, the key is not in the table
+	if hashTable.employees[index].deleted {
+		return ""
+	}
+
 	// Otherwise, return the phone value of the corresponding slice entry
 	return hashTable.employees[index].phone
 }
@@ -295,6 +326,11 @@ func (hashTable *DoubleHashTable) contains(name string) bool {
 		return false
 	}
 
+	// If the slice entry at the index is deleted, the key is not in the table
+	if hashTable.employees[index].deleted {
+		return false
+	}
+
 	// Otherwise, return true
 	return true
 }
@@ -325,11 +361,16 @@ func (hashTable *DoubleHashTable) aveProbeSequenceLength() float32 {
 	totalLength := 0
 	numValues := 0
 	for _, employee := range hashTable.employees {
-		if employee != nil {
+		if employee != nil && !employee.deleted {
 			_, probeLength := hashTable.find(employee.name)
 			totalLength += probeLength
 			numValues++
 		}
 	}
+
+	// An empty table has no probe sequences to average.
+	if numValues == 0 {
+		return 0
+	}
 	return float32(totalLength) / float32(numValues)
 }
@@ -0,0 +1,284 @@
+package hashtables_test
+
+import (
+	"fmt"
+	"testing"
+
+	"hashtables"
+	"hashtables/workload"
+)
+
+// Sub-benchmark names use key=value parts so benchstat can group and filter them, e.g.
+//
+//	go test -run '^$' -bench . -count 10 > old.txt
+//	benchstat -col /strategy old.txt
+
+const (
+	benchCapacity = 10007
+	benchSeed     = 12345
+	zipfSkew      = 1.1
+)
+
+var benchLoads = []float64{0.1, 0.25, 0.5, 0.75, 0.9, 0.95}
+
+// benchWorkload pairs a key set with an access pattern.
+type benchWorkload struct {
+	name string
+	keys workload.Generator
+	skew float64 // Zipf exponent for accesses, or 0 for uniform accesses.
+}
+
+var benchWorkloads = []benchWorkload{
+	{"uniform", workload.Uniform, 0},
+	{"zipfian", workload.Uniform, zipfSkew},
+	{"sequential", workload.Sequential, 0},
+	{"prefix", workload.CommonPrefix, 0},
+}
+
+// benchCase is one strategy, workload and load factor, with its keys ready to use.
+type benchCase struct {
+	strategy hashtables.Strategy
+	workload benchWorkload
+	load     float64
+	keys     []string // The keys that fill the table to the load factor.
+	misses   []string // Keys that are not in the table.
+	spare    []string // More keys, for inserting while others are deleted.
+	accesses []int    // Indices into keys, in access order.
+}
+
+// Run fn as a sub-benchmark for every strategy, workload and load factor.
+func runCases(b *testing.B, fn func(b *testing.B, c benchCase)) {
+	for _, strategy := range hashtables.Strategies {
+		for _, w := range benchWorkloads {
+			for _, load := range benchLoads {
+				name := fmt.Sprintf("strategy=%s/workload=%s/load=%.2f", strategy.Name, w.name, load)
+				b.Run(name, func(b *testing.B) {
+					// Open addressing tables panic when they run out of slots. Skip those cases.
+					defer func() {
+						if p := recover(); p != nil {
+							b.Skipf("%s table is full at load %.2f: %v", strategy.Name, load, p)
+						}
+					}()
+					fn(b, newBenchCase(strategy, w, load))
+				})
+			}
+		}
+	}
+}
+
+func newBenchCase(strategy hashtables.Strategy, w benchWorkload, load float64) benchCase {
+	numKeys := max(1, int(float64(benchCapacity)*load))
+	all := w.keys(2*numKeys, benchSeed)
+	return benchCase{
+		strategy: strategy,
+		workload: w,
+		load:     load,
+		keys:     all[:numKeys],
+		misses:   workload.Misses(all[:numKeys]),
+		spare:    all[numKeys:],
+		accesses: workload.Accesses(numKeys, 1<<16, benchSeed, w.skew),
+	}
+}
+
+// Build a table holding the case's keys.
+func (c benchCase) fill(b *testing.B) hashtables.Table {
+	b.Helper()
+	table := c.strategy.New(benchCapacity, hashtables.DJB2)
+	for _, key := range c.keys {
+		table.Set(key, key)
+	}
+	return table
+}
+
+func BenchmarkLookupHit(b *testing.B) {
+	runCases(b, func(b *testing.B, c benchCase) {
+		table := c.fill(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			table.Get(c.keys[c.accesses[i%len(c.accesses)]])
+		}
+	})
+}
+
+func BenchmarkLookupMiss(b *testing.B) {
+	runCases(b, func(b *testing.B, c benchCase) {
+		table := c.fill(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			table.Get(c.misses[c.accesses[i%len(c.accesses)]])
+		}
+	})
+}
+
+// Insert into a table that starts small and doubles when the next insert
+// would pass the load factor, so each op includes its share of the resizing.
+func BenchmarkInsertGrow(b *testing.B) {
+	runCases(b, func(b *testing.B, c benchCase) {
+		var table hashtables.Table
+		for i := 0; i < b.N; i++ {
+			if i%len(c.keys) == 0 {
+				b.StopTimer()
+				table = c.strategy.New(16, hashtables.DJB2)
+				b.StartTimer()
+			}
+			if float64(table.Len()+1) > c.load*float64(table.Capacity()) {
+				table.Resize(2*table.Capacity() + 1)
+			}
+			key := c.keys[i%len(c.keys)]
+			table.Set(key, key)
+		}
+	})
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	runCases(b, func(b *testing.B, c benchCase) {
+		table := c.fill(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			table.Set(c.keys[c.accesses[i%len(c.accesses)]], "202-555-0100")
+		}
+	})
+}
+
+// Delete the oldest key and insert a new one, so the table stays at its load factor
+// while open addressing tables collect tombstones.
+func BenchmarkDeleteChurn(b *testing.B) {
+	runCases(b, func(b *testing.B, c benchCase) {
+		table := c.fill(b)
+		pool := append(append([]string(nil), c.keys...), c.spare...)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			table.Delete(pool[i%len(pool)])
+			key := pool[(i+len(c.keys))%len(pool)]
+			table.Set(key, key)
+		}
+	})
+}
+
+// Mix lookups with writes that delete present keys and re-insert missing ones.
+func BenchmarkMixed(b *testing.B) {
+	for _, readPercent := range []int{50, 90, 99} {
+		b.Run(fmt.Sprintf("read=%d", readPercent), func(b *testing.B) {
+			runCases(b, func(b *testing.B, c benchCase) {
+				table := c.fill(b)
+				writes := workload.Accesses(100, len(c.accesses), benchSeed+1, 0)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					j := i % len(c.accesses)
+					key := c.keys[c.accesses[j]]
+					switch {
+					case writes[j] < readPercent:
+						table.Get(key)
+					case table.Contains(key):
+						table.Delete(key)
+					default:
+						table.Set(key, key)
+					}
+				}
+			})
+		})
+	}
+}
+
+// Read through a cache of 90% of the table's capacity with Zipfian accesses to four times
+// as many keys, so it evicts constantly. rebuild=false lets tombstones pile up in
+// open addressing tables.
+func BenchmarkCacheChurn(b *testing.B) {
+	maxEntries := benchCapacity * 9 / 10
+	keys := workload.Uniform(4*maxEntries, benchSeed)
+	accesses := workload.Accesses(len(keys), 1<<16, benchSeed, zipfSkew)
+	for _, strategy := range hashtables.Strategies {
+		for _, policy := range []hashtables.Policy{hashtables.LRU, hashtables.LFU, hashtables.TinyLFU} {
+			for _, rebuild := range []bool{true, false} {
+				name := fmt.Sprintf("strategy=%s/policy=%s/rebuild=%t", strategy.Name, policy, rebuild)
+				b.Run(name, func(b *testing.B) {
+					options := hashtables.CacheOptions[string, string]{
+						Strategy:   strategy.Name,
+						MaxEntries: maxEntries,
+						Capacity:   benchCapacity,
+						Policy:     policy,
+						Keys:       hashtables.StringKeys(hashtables.DJB2),
+					}
+					if !rebuild {
+						options.RebuildAfter = -1
+					}
+					cache, err := hashtables.NewCache(options)
+					if err != nil {
+						b.Fatal(err)
+					}
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						key := keys[accesses[i%len(accesses)]]
+						if _, ok := cache.Get(key); !ok {
+							cache.Set(key, key)
+						}
+					}
+					b.StopTimer()
+					b.ReportMetric(cache.Stats().HitRatio(), "hit-ratio")
+					b.ReportMetric(cache.TableStats().Unsuccessful.Mean, "probes/miss")
+				})
+			}
+		}
+	}
+}
+
+// Compare the chaining table's slice of entry pointers per bucket with the flat layout's
+// single entry array, at loads past 1 where chains get long. build reports the
+// allocations of filling a table from empty, and churn the allocations of steady
+// deletes and inserts, which the flat layout serves from its free list.
+func BenchmarkChainingLayout(b *testing.B) {
+	layouts := []string{"chaining", "flat"}
+	for _, load := range []float64{0.75, 1, 2, 4} {
+		numKeys := int(float64(benchCapacity) * load)
+		all := workload.Uniform(2*numKeys, benchSeed)
+		keys, spare := all[:numKeys], all[numKeys:]
+		misses := workload.Misses(keys)
+		accesses := workload.Accesses(numKeys, 1<<16, benchSeed, 0)
+		fill := func(b *testing.B, layout string) hashtables.Map[string, string] {
+			table, err := hashtables.NewMap[string, string](layout, benchCapacity, hashtables.StringKeys(hashtables.DJB2))
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, key := range keys {
+				table.Set(key, key)
+			}
+			return table
+		}
+		for _, layout := range layouts {
+			prefix := fmt.Sprintf("layout=%s/load=%.2f", layout, load)
+			b.Run(prefix+"/op=build", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					fill(b, layout)
+				}
+			})
+			b.Run(prefix+"/op=hit", func(b *testing.B) {
+				table := fill(b, layout)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					table.Get(keys[accesses[i%len(accesses)]])
+				}
+			})
+			b.Run(prefix+"/op=miss", func(b *testing.B) {
+				table := fill(b, layout)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					table.Get(misses[accesses[i%len(accesses)]])
+				}
+			})
+			b.Run(prefix+"/op=churn", func(b *testing.B) {
+				table := fill(b, layout)
+				pool := append(append([]string(nil), keys...), spare...)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					table.Delete(pool[i%len(pool)])
+					key := pool[(i+len(keys))%len(pool)]
+					table.Set(key, key)
+				}
+			})
+		}
+	}
+}
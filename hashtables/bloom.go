@@ -0,0 +1,161 @@
+package hashtables
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// BloomFilter is a bit array with k bits set for each key. It finds a key's bits with
+// two hash functions and the Kirsch-Mitzenmacher trick, bit i = hash1 + i*hash2, the
+// same sequence double hashing probes, so k hash functions cost two.
+type BloomFilter[K any] struct {
+	bits      packedInts // One bit per position.
+	numBits   int
+	numHashes int
+	count     int
+	keys      KeyHasher[K]
+	hash2     func(key K) int
+}
+
+// Build a Bloom filter sized for this many keys at this false positive rate.
+// The KeyHasher is hash1 and hash2 is the second hash function.
+// A nil hash2 remixes hash1, so it works with any KeyHasher.
+func NewBloomFilter[K any](expected int, falsePositiveRate float64, keys KeyHasher[K],
+	hash2 func(key K) int) (*BloomFilter[K], error) {
+	if expected <= 0 {
+		return nil, fmt.Errorf("expected keys must be positive, got %d", expected)
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, fmt.Errorf("false positive rate must be between 0 and 1, got %g", falsePositiveRate)
+	}
+
+	// The optimal sizes are m = -n ln p / (ln 2)^2 bits and k = m/n ln 2 hashes.
+	numBits := int(math.Ceil(-float64(expected) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	numHashes := max(1, int(math.Round(float64(numBits)/float64(expected)*math.Ln2)))
+	return newBloomFilter(numBits, numHashes, keys, hash2), nil
+}
+
+func newBloomFilter[K any](numBits int, numHashes int, keys KeyHasher[K], hash2 func(key K) int) *BloomFilter[K] {
+	return &BloomFilter[K]{
+		bits:      newPackedInts(numBits, 1),
+		numBits:   numBits,
+		numHashes: numHashes,
+		keys:      keys,
+		hash2:     secondHash(keys, hash2),
+	}
+}
+
+// Return the bit for the key's i-th hash.
+func (filter *BloomFilter[K]) bit(hash1 uint64, hash2 uint64, i int) int {
+	return int((hash1 + uint64(i)*hash2) % uint64(filter.numBits))
+}
+
+// Set the key's bits. A Bloom filter never fills up, so Add always returns true,
+// but the false positive rate grows past the expected number of keys.
+func (filter *BloomFilter[K]) Add(key K) bool {
+	hash1, hash2 := uint64(filter.keys.Hash(key)), uint64(filter.hash2(key))
+	for i := 0; i < filter.numHashes; i++ {
+		filter.bits.set(filter.bit(hash1, hash2, i), 1)
+	}
+	filter.count++
+	return true
+}
+
+// Return false if the key was definitely never added.
+func (filter *BloomFilter[K]) MayContain(key K) bool {
+	hash1, hash2 := uint64(filter.keys.Hash(key)), uint64(filter.hash2(key))
+	for i := 0; i < filter.numHashes; i++ {
+		if filter.bits.get(filter.bit(hash1, hash2, i)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the number of keys added. Keys added twice count twice.
+func (filter *BloomFilter[K]) Len() int {
+	return filter.count
+}
+
+// Return the number of bits.
+func (filter *BloomFilter[K]) Bits() int {
+	return filter.numBits
+}
+
+// Return the number of hashes per key.
+func (filter *BloomFilter[K]) Hashes() int {
+	return filter.numHashes
+}
+
+// Return the false positive rate expected from the fraction of bits that are set.
+func (filter *BloomFilter[K]) FalsePositiveRate() float64 {
+	set := 0
+	for i := 0; i < filter.numBits; i++ {
+		set += int(filter.bits.get(i))
+	}
+	return math.Pow(float64(set)/float64(filter.numBits), float64(filter.numHashes))
+}
+
+// Add every key in another filter to this one. Both filters must have the same
+// size, number of hashes and KeyHasher. Afterward, Len is the sum of the two,
+// which overcounts keys that were in both.
+func (filter *BloomFilter[K]) Union(other *BloomFilter[K]) error {
+	if filter.numBits != other.numBits || filter.numHashes != other.numHashes {
+		return fmt.Errorf("can't union a %d-bit, %d-hash Bloom filter with a %d-bit, %d-hash one",
+			filter.numBits, filter.numHashes, other.numBits, other.numHashes)
+	}
+	if !sameKeyHasher(filter.keys, other.keys) {
+		return errors.New("can't union Bloom filters with different KeyHashers")
+	}
+	for i, word := range other.bits.words {
+		filter.bits.words[i] |= word
+	}
+	filter.count += other.count
+	return nil
+}
+
+// The serialized form starts with these bytes and a version.
+var bloomMagic = []byte("BLM\x01")
+
+// Encode the filter: its size, number of hashes, key count and bits.
+// The KeyHasher and hash2 are not included.
+func (filter *BloomFilter[K]) MarshalBinary() ([]byte, error) {
+	data := append([]byte(nil), bloomMagic...)
+	data = binary.AppendUvarint(data, uint64(filter.numBits))
+	data = binary.AppendUvarint(data, uint64(filter.numHashes))
+	data = binary.AppendUvarint(data, uint64(filter.count))
+	return filter.bits.appendBytes(data, filter.numBits), nil
+}
+
+// Decode a filter written by MarshalBinary. Keys must be hashed with the same
+// KeyHasher and hash2 it was built with.
+func UnmarshalBloomFilter[K any](data []byte, keys KeyHasher[K], hash2 func(key K) int) (*BloomFilter[K], error) {
+	if len(data) < len(bloomMagic) || string(data[:len(bloomMagic)]) != string(bloomMagic) {
+		return nil, errors.New("not a serialized Bloom filter")
+	}
+	data = data[len(bloomMagic):]
+	var fields [3]uint64
+	for i := range fields {
+		value, read := binary.Uvarint(data)
+		if read <= 0 {
+			return nil, errors.New("bad Bloom filter header")
+		}
+		fields[i] = value
+		data = data[read:]
+	}
+	// A build never uses more hashes than bits.
+	if fields[0] == 0 || fields[1] == 0 || fields[1] > fields[0] {
+		return nil, errors.New("bad Bloom filter size")
+	}
+	if !packedByteLenIs(fields[0], 1, len(data)) {
+		return nil, fmt.Errorf("Bloom filter bits: got %d bytes for %d bits", len(data), fields[0])
+	}
+	filter := newBloomFilter(int(fields[0]), int(fields[1]), keys, hash2)
+	filter.count = int(fields[2])
+	if err := filter.bits.readBytes(data, filter.numBits); err != nil {
+		return nil, fmt.Errorf("Bloom filter bits: %w", err)
+	}
+	return filter, nil
+}
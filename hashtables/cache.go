@@ -0,0 +1,414 @@
+package hashtables
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Policy picks which entry a full cache gives up.
+type Policy int
+
+const (
+	// LRU evicts the least recently used entry.
+	LRU Policy = iota
+	// LFU evicts the least frequently used entry, and the least recently used among those.
+	LFU
+	// TinyLFU evicts like LRU, but only admits a new key if a frequency sketch says
+	// it is used more often than the entry it would evict.
+	TinyLFU
+)
+
+var policyNames = []string{"lru", "lfu", "tinylfu"}
+
+func (policy Policy) String() string {
+	if policy < 0 || int(policy) >= len(policyNames) {
+		return fmt.Sprintf("Policy(%d)", int(policy))
+	}
+	return policyNames[policy]
+}
+
+// Parse a policy name.
+func ParsePolicy(name string) (Policy, error) {
+	for i, policyName := range policyNames {
+		if policyName == name {
+			return Policy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown policy %q (have %s)", name, strings.Join(policyNames, ", "))
+}
+
+// CacheOptions configure a Cache.
+type CacheOptions[K any, V any] struct {
+	Strategy   string       // The table strategy. The default is "linear".
+	MaxEntries int          // The most entries the cache holds.
+	Capacity   int          // The table's slots or buckets. The default is MaxEntries * 4/3 + 1.
+	Policy     Policy       // How to choose entries to evict.
+	Keys       KeyHasher[K] // How to hash and compare keys.
+
+	// OnEvict is called with each entry the cache evicts to make room.
+	// It is not called for Delete.
+	OnEvict func(key K, value V)
+
+	// RebuildAfter is how many removals an open addressing table takes before the cache
+	// rebuilds it to clear tombstones. The default 0 means half the slots the cache
+	// never fills, or never if that is none, rather than rebuilding after every eviction.
+	// A negative number means never, to watch probing degrade under churn.
+	RebuildAfter int
+}
+
+// CacheStats counts what a cache has done.
+type CacheStats struct {
+	Hits       int
+	Misses     int
+	Evictions  int
+	Rejections int // New keys that TinyLFU did not admit.
+	Rebuilds   int // Times the table was rebuilt to clear tombstones.
+}
+
+// Return the fraction of lookups that were hits.
+func (stats CacheStats) HitRatio() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+// Cache is a bounded map that evicts entries instead of filling up.
+// Its table maps each key to a node that is also linked into a recency list (LRU and
+// TinyLFU) or into a list for its use count (LFU), so every operation takes constant time.
+type Cache[K any, V any] struct {
+	options      CacheOptions[K, V]
+	table        Map[K, *cacheNode[K, V]]
+	rebuildAfter int
+	removals     int
+	stats        CacheStats
+
+	recency cacheList[K, V] // Most recent first, for LRU and TinyLFU.
+	counts  countNode[K, V] // Sentinel of the use count lists, lowest count first, for LFU.
+	sketch  *frequencySketch
+
+	// The hash code of the key the last Get missed, so the sketch doesn't count
+	// a Set of that key right after as a second use.
+	missHash int
+	missed   bool
+}
+
+// cacheNode is a cached entry and its links.
+type cacheNode[K any, V any] struct {
+	key        K
+	value      V
+	prev, next *cacheNode[K, V]
+	count      *countNode[K, V] // The node's use count list, for LFU.
+}
+
+// cacheList is a circular doubly linked list of nodes with a sentinel root.
+type cacheList[K any, V any] struct {
+	root cacheNode[K, V]
+}
+
+// countNode holds the entries used a given number of times, most recent first.
+type countNode[K any, V any] struct {
+	uses       int
+	entries    cacheList[K, V]
+	prev, next *countNode[K, V]
+}
+
+// Build an empty cache.
+func NewCache[K any, V any](options CacheOptions[K, V]) (*Cache[K, V], error) {
+	if options.MaxEntries <= 0 {
+		return nil, fmt.Errorf("max entries must be positive, got %d", options.MaxEntries)
+	}
+	if options.Keys == nil {
+		return nil, fmt.Errorf("cache needs a KeyHasher")
+	}
+	if options.Strategy == "" {
+		options.Strategy = "linear"
+	}
+	if options.Capacity == 0 {
+		options.Capacity = options.MaxEntries*4/3 + 1
+	}
+	chained := options.Strategy == "chaining" || options.Strategy == "flat"
+	if !chained && options.Capacity < options.MaxEntries {
+		return nil, fmt.Errorf("capacity %d cannot hold %d entries", options.Capacity, options.MaxEntries)
+	}
+	table, err := NewMap[K, *cacheNode[K, V]](options.Strategy, options.Capacity, options.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &Cache[K, V]{options: options, table: table}
+	switch {
+	case chained || options.RebuildAfter < 0:
+		cache.rebuildAfter = -1
+	case options.RebuildAfter == 0:
+		cache.rebuildAfter = (options.Capacity - options.MaxEntries) / 2
+		if cache.rebuildAfter == 0 {
+			cache.rebuildAfter = -1
+		}
+	default:
+		cache.rebuildAfter = options.RebuildAfter
+	}
+	cache.recency.init()
+	cache.counts.next = &cache.counts
+	cache.counts.prev = &cache.counts
+	if options.Policy == TinyLFU {
+		cache.sketch = newFrequencySketch(options.MaxEntries)
+	}
+	return cache, nil
+}
+
+// Return the number of cached entries.
+func (cache *Cache[K, V]) Len() int {
+	return cache.table.Len()
+}
+
+// Return the counts of hits, misses, evictions, rejections and rebuilds so far.
+func (cache *Cache[K, V]) Stats() CacheStats {
+	return cache.stats
+}
+
+// Return the probe statistics of the cache's table.
+func (cache *Cache[K, V]) TableStats() Stats {
+	if table, ok := cache.table.(interface{ Stats() Stats }); ok {
+		return table.Stats()
+	}
+	return Stats{}
+}
+
+// Return the key's value and count a hit, or count a miss.
+func (cache *Cache[K, V]) Get(key K) (V, bool) {
+	node, ok := cache.table.Lookup(key)
+	if cache.sketch != nil {
+		hash := cache.options.Keys.Hash(key)
+		cache.sketch.increment(hash)
+		cache.missHash, cache.missed = hash, !ok
+	}
+	if !ok {
+		cache.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	cache.stats.Hits++
+	cache.touch(node)
+	return node.value, true
+}
+
+// Return true if the key is cached, without counting a use.
+func (cache *Cache[K, V]) Contains(key K) bool {
+	return cache.table.Contains(key)
+}
+
+// Add or update an entry, evicting one if the cache is full.
+// Return false if TinyLFU did not admit a new key.
+func (cache *Cache[K, V]) Set(key K, value V) bool {
+	var hash int
+	if cache.sketch != nil {
+		// Count the use unless the Get just before missed this key and counted it.
+		hash = cache.options.Keys.Hash(key)
+		if !cache.missed || cache.missHash != hash {
+			cache.sketch.increment(hash)
+		}
+		cache.missed = false
+	}
+
+	if node, ok := cache.table.Lookup(key); ok {
+		node.value = value
+		cache.touch(node)
+		return true
+	}
+
+	if cache.table.Len() >= cache.options.MaxEntries {
+		victim := cache.victim()
+		if cache.sketch != nil {
+			// Admit the new key only if it is used more often than the victim.
+			if cache.sketch.estimate(hash) <= cache.sketch.estimate(cache.options.Keys.Hash(victim.key)) {
+				cache.stats.Rejections++
+				return false
+			}
+		}
+		cache.remove(victim)
+		cache.stats.Evictions++
+		if cache.options.OnEvict != nil {
+			cache.options.OnEvict(victim.key, victim.value)
+		}
+	}
+
+	node := &cacheNode[K, V]{key: key, value: value}
+	cache.table.Set(key, node)
+	if cache.options.Policy == LFU {
+		cache.addCounted(node)
+	} else {
+		cache.recency.pushFront(node)
+	}
+	return true
+}
+
+// Remove the key's entry. Return true if it was cached.
+func (cache *Cache[K, V]) Delete(key K) bool {
+	node, ok := cache.table.Lookup(key)
+	if !ok {
+		return false
+	}
+	cache.remove(node)
+	return true
+}
+
+// Return the entry the policy would evict next.
+func (cache *Cache[K, V]) victim() *cacheNode[K, V] {
+	if cache.options.Policy == LFU {
+		return cache.counts.next.entries.back()
+	}
+	return cache.recency.back()
+}
+
+// Take a node out of the table and its list, rebuilding the table
+// once enough tombstones may have built up.
+func (cache *Cache[K, V]) remove(node *cacheNode[K, V]) {
+	cache.table.Delete(node.key)
+	if cache.options.Policy == LFU {
+		count := node.count
+		count.entries.remove(node)
+		if count.entries.empty() {
+			count.unlink()
+		}
+	} else {
+		cache.recency.remove(node)
+	}
+
+	cache.removals++
+	if cache.rebuildAfter > 0 && cache.removals >= cache.rebuildAfter {
+		cache.table.Resize(cache.table.Capacity())
+		cache.removals = 0
+		cache.stats.Rebuilds++
+	}
+}
+
+// Record a use of the node.
+func (cache *Cache[K, V]) touch(node *cacheNode[K, V]) {
+	if cache.options.Policy != LFU {
+		cache.recency.remove(node)
+		cache.recency.pushFront(node)
+		return
+	}
+
+	// Move the node to the list for one more use, making that list if needed.
+	count := node.count
+	next := count.next
+	if next == &cache.counts || next.uses != count.uses+1 {
+		next = &countNode[K, V]{uses: count.uses + 1}
+		next.entries.init()
+		next.linkAfter(count)
+	}
+	count.entries.remove(node)
+	next.entries.pushFront(node)
+	node.count = next
+	if count.entries.empty() {
+		count.unlink()
+	}
+}
+
+// Put a new node in the list for one use.
+func (cache *Cache[K, V]) addCounted(node *cacheNode[K, V]) {
+	first := cache.counts.next
+	if first == &cache.counts || first.uses != 1 {
+		first = &countNode[K, V]{uses: 1}
+		first.entries.init()
+		first.linkAfter(&cache.counts)
+	}
+	first.entries.pushFront(node)
+	node.count = first
+}
+
+func (list *cacheList[K, V]) init() {
+	list.root.next = &list.root
+	list.root.prev = &list.root
+}
+
+func (list *cacheList[K, V]) empty() bool {
+	return list.root.next == &list.root
+}
+
+func (list *cacheList[K, V]) pushFront(node *cacheNode[K, V]) {
+	node.prev = &list.root
+	node.next = list.root.next
+	list.root.next.prev = node
+	list.root.next = node
+}
+
+func (list *cacheList[K, V]) remove(node *cacheNode[K, V]) {
+	node.prev.next = node.next
+	node.next.prev = node.prev
+	node.prev, node.next = nil, nil
+}
+
+// Return the last node or nil if the list is empty.
+func (list *cacheList[K, V]) back() *cacheNode[K, V] {
+	if list.empty() {
+		return nil
+	}
+	return list.root.prev
+}
+
+func (count *countNode[K, V]) linkAfter(prev *countNode[K, V]) {
+	count.prev = prev
+	count.next = prev.next
+	prev.next.prev = count
+	prev.next = count
+}
+
+func (count *countNode[K, V]) unlink() {
+	count.prev.next = count.next
+	count.next.prev = count.prev
+}
+
+// frequencySketch estimates how often hash codes have been seen with a count-min sketch
+// of 4-bit counters. Counts are halved after a sample of increments, so old
+// popularity fades, as in TinyLFU.
+type frequencySketch struct {
+	counters   []uint8
+	mask       int
+	additions  int
+	sampleSize int
+}
+
+const sketchDepth = 4
+
+func newFrequencySketch(maxEntries int) *frequencySketch {
+	width := 16
+	for width < maxEntries {
+		width *= 2
+	}
+	return &frequencySketch{
+		counters:   make([]uint8, sketchDepth*width),
+		mask:       width - 1,
+		sampleSize: 10 * maxEntries,
+	}
+}
+
+// Return the counter index for a hash code in one row.
+func (sketch *frequencySketch) index(hash int, row int) int {
+	return row*(sketch.mask+1) + remix(hash+row*0x5bd1e995)&sketch.mask
+}
+
+func (sketch *frequencySketch) increment(hash int) {
+	for row := 0; row < sketchDepth; row++ {
+		if i := sketch.index(hash, row); sketch.counters[i] < 15 {
+			sketch.counters[i]++
+		}
+	}
+	sketch.additions++
+	if sketch.additions >= sketch.sampleSize {
+		for i := range sketch.counters {
+			sketch.counters[i] /= 2
+		}
+		sketch.additions /= 2
+	}
+}
+
+func (sketch *frequencySketch) estimate(hash int) int {
+	estimate := 15
+	for row := 0; row < sketchDepth; row++ {
+		estimate = min(estimate, int(sketch.counters[sketch.index(hash, row)]))
+	}
+	return estimate
+}
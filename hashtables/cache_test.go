@@ -0,0 +1,144 @@
+package hashtables_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"hashtables"
+)
+
+// Build a cache of strings that records what it evicts.
+func newTestCache(t *testing.T, options hashtables.CacheOptions[string, string]) (*hashtables.Cache[string, string], *[]string) {
+	t.Helper()
+	var evicted []string
+	options.Keys = hashtables.StringKeys(hashtables.DJB2)
+	options.OnEvict = func(key string, value string) {
+		evicted = append(evicted, key)
+	}
+	cache, err := hashtables.NewCache(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache, &evicted
+}
+
+func TestCacheLRU(t *testing.T) {
+	for _, strategy := range hashtables.StrategyNames() {
+		t.Run(strategy, func(t *testing.T) {
+			cache, evicted := newTestCache(t, hashtables.CacheOptions[string, string]{
+				Strategy: strategy, MaxEntries: 3, Policy: hashtables.LRU,
+			})
+			cache.Set("a", "1")
+			cache.Set("b", "2")
+			cache.Set("c", "3")
+			cache.Get("a")
+			cache.Set("b", "22") // Updating is a use too.
+			cache.Set("d", "4")
+			cache.Set("e", "5")
+			if want := []string{"c", "a"}; !reflect.DeepEqual(*evicted, want) {
+				t.Errorf("evicted %v, want %v", *evicted, want)
+			}
+			if value, ok := cache.Get("b"); !ok || value != "22" {
+				t.Errorf("Get(b) = %q, %v", value, ok)
+			}
+			if !cache.Delete("d") || cache.Contains("d") || cache.Len() != 2 {
+				t.Error("Delete did not remove d")
+			}
+			if stats := cache.Stats(); stats.Evictions != 2 || stats.Hits != 2 {
+				t.Errorf("stats %+v", stats)
+			}
+		})
+	}
+}
+
+func TestCacheLFU(t *testing.T) {
+	cache, evicted := newTestCache(t, hashtables.CacheOptions[string, string]{
+		MaxEntries: 3, Policy: hashtables.LFU,
+	})
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+	cache.Set("c", "3")
+	cache.Get("a")
+	cache.Get("a")
+	cache.Get("c")
+	cache.Set("d", "4") // b is the only key used once.
+	cache.Set("e", "5") // Now d is.
+	cache.Get("e")
+	cache.Get("e")
+	cache.Get("c")
+	cache.Set("f", "6") // c and e are used three times and a twice.
+	if want := []string{"b", "d", "a"}; !reflect.DeepEqual(*evicted, want) {
+		t.Errorf("evicted %v, want %v", *evicted, want)
+	}
+
+	// Among keys used equally often, the least recent goes first.
+	cache, evicted = newTestCache(t, hashtables.CacheOptions[string, string]{
+		MaxEntries: 3, Policy: hashtables.LFU,
+	})
+	for _, key := range []string{"a", "b", "c", "d"} {
+		cache.Set(key, key)
+	}
+	if want := []string{"a"}; !reflect.DeepEqual(*evicted, want) {
+		t.Errorf("evicted %v, want %v", *evicted, want)
+	}
+}
+
+func TestCacheTinyLFU(t *testing.T) {
+	cache, evicted := newTestCache(t, hashtables.CacheOptions[string, string]{
+		MaxEntries: 2, Policy: hashtables.TinyLFU,
+	})
+	cache.Set("a", "1")
+	cache.Set("b", "2")
+	cache.Get("a") // b is the victim, used once.
+
+	// A miss followed by a Set is one use, no more than the victim's.
+	if _, ok := cache.Get("x"); ok {
+		t.Fatal("found x before setting it")
+	}
+	if cache.Set("x", "3") || cache.Contains("x") {
+		t.Error("admitted x after one use")
+	}
+
+	// A second use admits it in place of the victim.
+	cache.Get("x")
+	if !cache.Set("x", "3") || !cache.Contains("x") {
+		t.Error("did not admit x after two uses")
+	}
+	if want := []string{"b"}; !reflect.DeepEqual(*evicted, want) {
+		t.Errorf("evicted %v, want %v", *evicted, want)
+	}
+	if stats := cache.Stats(); stats.Rejections != 1 || stats.Evictions != 1 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestCacheRebuild(t *testing.T) {
+	for _, test := range []struct {
+		capacity int
+		rebuilds bool
+	}{
+		{10, false}, // No free slots, so rebuilding would not help.
+		{11, false}, // One free slot.
+		{14, true},
+	} {
+		t.Run(fmt.Sprint(test.capacity), func(t *testing.T) {
+			cache, _ := newTestCache(t, hashtables.CacheOptions[string, string]{
+				MaxEntries: 10, Capacity: test.capacity,
+			})
+			for i := 0; i < 100; i++ {
+				key := fmt.Sprint(i)
+				cache.Set(key, key)
+				if value, ok := cache.Get(key); !ok || value != key {
+					t.Fatalf("Get(%s) = %q, %v", key, value, ok)
+				}
+			}
+			if cache.Len() != 10 {
+				t.Errorf("Len = %d, want 10", cache.Len())
+			}
+			if rebuilds := cache.Stats().Rebuilds; rebuilds > 0 != test.rebuilds {
+				t.Errorf("%d rebuilds", rebuilds)
+			}
+		})
+	}
+}
@@ -0,0 +1,585 @@
+package hashtables
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// BucketKind names how a chaining table stores the entries in each bucket.
+type BucketKind string
+
+const (
+	// BucketSlice scans a slice from the front. Deleting shifts the later entries down.
+	BucketSlice BucketKind = "slice"
+	// BucketList scans a singly linked list. Deleting unlinks one node.
+	BucketList BucketKind = "list"
+	// BucketSorted keeps a slice sorted by hash code and finds keys by binary search.
+	BucketSorted BucketKind = "sorted"
+	// BucketTree scans a slice until it holds more than TreeifyThreshold entries, then
+	// turns it into a balanced tree ordered by hash code, like Java's HashMap. A tree that
+	// shrinks to UntreeifyThreshold entries turns back into a slice. Keys whose full hash
+	// codes collide are kept in order if the table's KeyHasher is a KeyOrderer, and are
+	// scanned otherwise.
+	BucketTree BucketKind = "tree"
+)
+
+// BucketKinds lists the bucket structures a chaining table can use.
+var BucketKinds = []BucketKind{BucketSlice, BucketList, BucketSorted, BucketTree}
+
+const (
+	TreeifyThreshold   = 8
+	UntreeifyThreshold = 6
+)
+
+// Look up a bucket kind by name.
+func ParseBucketKind(name string) (BucketKind, error) {
+	for _, kind := range BucketKinds {
+		if string(kind) == name {
+			return kind, nil
+		}
+	}
+	names := make([]string, len(BucketKinds))
+	for i, kind := range BucketKinds {
+		names[i] = string(kind)
+	}
+	return "", fmt.Errorf("unknown bucket kind %q (have %s)", name, strings.Join(names, ", "))
+}
+
+// chain holds the entries in one bucket of a chaining table.
+// Each entry is added and removed with its key's hash code.
+type chain[K any, V any] interface {
+	len() int
+
+	// find returns the key's entry and the number of entries examined before it,
+	// or nil and -1. If visit is not nil, it is called for each entry examined.
+	find(hash int, key K, keys KeyHasher[K], visit func(entry *Entry[K, V], step int)) (*Entry[K, V], int)
+	add(hash int, entry *Entry[K, V])
+	remove(hash int, entry *Entry[K, V])
+
+	// each calls fn for each entry in the chain's order until fn returns false.
+	each(fn func(entry *Entry[K, V]) bool) bool
+
+	// missLength returns the most entries a search for a missing key examines.
+	missLength() int
+}
+
+// Make an empty chain of this kind for keys hashed and compared by keys.
+func newChain[K any, V any](kind BucketKind, keys KeyHasher[K]) chain[K, V] {
+	switch kind {
+	case BucketList:
+		return &listChain[K, V]{}
+	case BucketSorted:
+		return &sortedChain[K, V]{}
+	case BucketTree:
+		return &treeChain[K, V]{compare: keyOrder(keys)}
+	}
+	return &sliceChain[K, V]{}
+}
+
+// sliceChain is the liveProject's bucket: a slice of entries in the order they were added.
+type sliceChain[K any, V any] struct {
+	entries []*Entry[K, V]
+}
+
+func (c *sliceChain[K, V]) len() int {
+	return len(c.entries)
+}
+
+func (c *sliceChain[K, V]) find(hash int, key K, keys KeyHasher[K],
+	visit func(entry *Entry[K, V], step int)) (*Entry[K, V], int) {
+	for i, entry := range c.entries {
+		if visit != nil {
+			visit(entry, i)
+		}
+		if keys.Equal(entry.Key, key) {
+			return entry, i
+		}
+	}
+	return nil, -1
+}
+
+func (c *sliceChain[K, V]) add(hash int, entry *Entry[K, V]) {
+	c.entries = append(c.entries, entry)
+}
+
+func (c *sliceChain[K, V]) remove(hash int, entry *Entry[K, V]) {
+	for i, e := range c.entries {
+		if e == entry {
+			c.entries = append(c.entries[:i], c.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *sliceChain[K, V]) each(fn func(entry *Entry[K, V]) bool) bool {
+	for _, entry := range c.entries {
+		if !fn(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *sliceChain[K, V]) missLength() int {
+	return len(c.entries)
+}
+
+// listChain is a singly linked list of entries in the order they were added.
+type listChain[K any, V any] struct {
+	head, tail *listNode[K, V]
+	count      int
+}
+
+type listNode[K any, V any] struct {
+	entry *Entry[K, V]
+	next  *listNode[K, V]
+}
+
+func (c *listChain[K, V]) len() int {
+	return c.count
+}
+
+func (c *listChain[K, V]) find(hash int, key K, keys KeyHasher[K],
+	visit func(entry *Entry[K, V], step int)) (*Entry[K, V], int) {
+	step := 0
+	for node := c.head; node != nil; node = node.next {
+		if visit != nil {
+			visit(node.entry, step)
+		}
+		if keys.Equal(node.entry.Key, key) {
+			return node.entry, step
+		}
+		step++
+	}
+	return nil, -1
+}
+
+func (c *listChain[K, V]) add(hash int, entry *Entry[K, V]) {
+	node := &listNode[K, V]{entry: entry}
+	if c.tail == nil {
+		c.head = node
+	} else {
+		c.tail.next = node
+	}
+	c.tail = node
+	c.count++
+}
+
+func (c *listChain[K, V]) remove(hash int, entry *Entry[K, V]) {
+	var prev *listNode[K, V]
+	for node := c.head; node != nil; prev, node = node, node.next {
+		if node.entry != entry {
+			continue
+		}
+		if prev == nil {
+			c.head = node.next
+		} else {
+			prev.next = node.next
+		}
+		if c.tail == node {
+			c.tail = prev
+		}
+		c.count--
+		return
+	}
+}
+
+func (c *listChain[K, V]) each(fn func(entry *Entry[K, V]) bool) bool {
+	for node := c.head; node != nil; node = node.next {
+		if !fn(node.entry) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *listChain[K, V]) missLength() int {
+	return c.count
+}
+
+// sortedChain keeps its entries sorted by hash code, with their hash codes beside them.
+// Entries with the same hash code stay in the order they were added.
+type sortedChain[K any, V any] struct {
+	entries []*Entry[K, V]
+	hashes  []int
+}
+
+func (c *sortedChain[K, V]) len() int {
+	return len(c.entries)
+}
+
+// Return the index of the first entry whose hash code is at least hash,
+// and the number of entries examined to find it.
+func (c *sortedChain[K, V]) lowerBound(hash int, visit func(entry *Entry[K, V], step int)) (int, int) {
+	low, high := 0, len(c.hashes)
+	step := 0
+	for low < high {
+		mid := int(uint(low+high) >> 1)
+		if visit != nil {
+			visit(c.entries[mid], step)
+		}
+		step++
+		if c.hashes[mid] < hash {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	return low, step
+}
+
+func (c *sortedChain[K, V]) find(hash int, key K, keys KeyHasher[K],
+	visit func(entry *Entry[K, V], step int)) (*Entry[K, V], int) {
+	i, step := c.lowerBound(hash, visit)
+	for ; i < len(c.entries) && c.hashes[i] == hash; i++ {
+		if visit != nil {
+			visit(c.entries[i], step)
+		}
+		if keys.Equal(c.entries[i].Key, key) {
+			return c.entries[i], step
+		}
+		step++
+	}
+	return nil, -1
+}
+
+func (c *sortedChain[K, V]) add(hash int, entry *Entry[K, V]) {
+	// Insert after the entries that have the same hash code.
+	i := sort.Search(len(c.hashes), func(i int) bool { return c.hashes[i] > hash })
+	c.entries = append(c.entries, nil)
+	copy(c.entries[i+1:], c.entries[i:])
+	c.entries[i] = entry
+	c.hashes = append(c.hashes, 0)
+	copy(c.hashes[i+1:], c.hashes[i:])
+	c.hashes[i] = hash
+}
+
+func (c *sortedChain[K, V]) remove(hash int, entry *Entry[K, V]) {
+	i, _ := c.lowerBound(hash, nil)
+	for ; i < len(c.entries) && c.hashes[i] == hash; i++ {
+		if c.entries[i] == entry {
+			c.entries = append(c.entries[:i], c.entries[i+1:]...)
+			c.hashes = append(c.hashes[:i], c.hashes[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *sortedChain[K, V]) each(fn func(entry *Entry[K, V]) bool) bool {
+	for _, entry := range c.entries {
+		if !fn(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *sortedChain[K, V]) missLength() int {
+	return bits.Len(uint(len(c.entries)))
+}
+
+// treeChain is a slice of entries until it grows past TreeifyThreshold, then an AVL tree
+// of nodes ordered by hash code. Each node holds the entries whose hash codes are equal,
+// sorted by key if compare is not nil so they can be binary searched.
+type treeChain[K any, V any] struct {
+	entries []*Entry[K, V] // The entries while the chain is short, in the order they were added,
+	hashes  []int          // and their hash codes.
+	root    *treeNode[K, V]
+	count   int
+	compare func(a K, b K) int // The KeyHasher's order, or nil if it has none.
+}
+
+type treeNode[K any, V any] struct {
+	hash        int
+	entries     []*Entry[K, V]
+	left, right *treeNode[K, V]
+	height      int
+}
+
+func (c *treeChain[K, V]) len() int {
+	return c.count
+}
+
+func (c *treeChain[K, V]) find(hash int, key K, keys KeyHasher[K],
+	visit func(entry *Entry[K, V], step int)) (*Entry[K, V], int) {
+	if c.root == nil {
+		// Scan the short list in order, like a slice bucket.
+		for i, entry := range c.entries {
+			if visit != nil {
+				visit(entry, i)
+			}
+			if keys.Equal(entry.Key, key) {
+				return entry, i
+			}
+		}
+		return nil, -1
+	}
+
+	step := 0
+	node := c.root
+	for node != nil && node.hash != hash {
+		if visit != nil {
+			visit(node.entries[0], step)
+		}
+		step++
+		if hash < node.hash {
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+	if node == nil {
+		return nil, -1
+	}
+	if c.compare != nil {
+		// Binary search the keys that share the hash code.
+		low, high := 0, len(node.entries)
+		for low < high {
+			middle := (low + high) / 2
+			entry := node.entries[middle]
+			if visit != nil {
+				visit(entry, step)
+			}
+			switch order := c.compare(key, entry.Key); {
+			case order == 0:
+				return entry, step
+			case order < 0:
+				high = middle
+			default:
+				low = middle + 1
+			}
+			step++
+		}
+		return nil, -1
+	}
+	for _, entry := range node.entries {
+		if visit != nil {
+			visit(entry, step)
+		}
+		if keys.Equal(entry.Key, key) {
+			return entry, step
+		}
+		step++
+	}
+	return nil, -1
+}
+
+func (c *treeChain[K, V]) add(hash int, entry *Entry[K, V]) {
+	c.count++
+	if c.root != nil {
+		c.root = c.root.insert(hash, entry, c.compare)
+		return
+	}
+	c.entries = append(c.entries, entry)
+	c.hashes = append(c.hashes, hash)
+	if c.count > TreeifyThreshold {
+		for i, entry := range c.entries {
+			c.root = c.root.insert(c.hashes[i], entry, c.compare)
+		}
+		c.entries, c.hashes = nil, nil
+	}
+}
+
+func (c *treeChain[K, V]) remove(hash int, entry *Entry[K, V]) {
+	if c.root == nil {
+		for i, e := range c.entries {
+			if e == entry {
+				c.entries = append(c.entries[:i], c.entries[i+1:]...)
+				c.hashes = append(c.hashes[:i], c.hashes[i+1:]...)
+				c.count--
+				return
+			}
+		}
+		return
+	}
+
+	var removed bool
+	c.root, removed = c.root.delete(hash, entry, c.compare)
+	if !removed {
+		return
+	}
+	c.count--
+	if c.count <= UntreeifyThreshold {
+		c.root.walk(func(node *treeNode[K, V]) bool {
+			for _, entry := range node.entries {
+				c.entries = append(c.entries, entry)
+				c.hashes = append(c.hashes, node.hash)
+			}
+			return true
+		})
+		c.root = nil
+	}
+}
+
+func (c *treeChain[K, V]) each(fn func(entry *Entry[K, V]) bool) bool {
+	if c.root == nil {
+		for _, entry := range c.entries {
+			if !fn(entry) {
+				return false
+			}
+		}
+		return true
+	}
+	return c.root.walk(func(node *treeNode[K, V]) bool {
+		for _, entry := range node.entries {
+			if !fn(entry) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (c *treeChain[K, V]) missLength() int {
+	if c.root == nil {
+		return c.count
+	}
+	return c.root.height
+}
+
+// Return true if the chain has been turned into a tree.
+func (c *treeChain[K, V]) treeified() bool {
+	return c.root != nil
+}
+
+// Return the height of a subtree, which is 0 for an empty one.
+func (node *treeNode[K, V]) treeHeight() int {
+	if node == nil {
+		return 0
+	}
+	return node.height
+}
+
+// Call fn for each node in hash code order until fn returns false.
+func (node *treeNode[K, V]) walk(fn func(node *treeNode[K, V]) bool) bool {
+	if node == nil {
+		return true
+	}
+	return node.left.walk(fn) && fn(node) && node.right.walk(fn)
+}
+
+// Return the index of the first of the node's entries whose key does not sort before
+// this key. Without an order, return the number of entries.
+func (node *treeNode[K, V]) position(key K, compare func(a K, b K) int) int {
+	if compare == nil {
+		return len(node.entries)
+	}
+	return sort.Search(len(node.entries), func(i int) bool {
+		return compare(node.entries[i].Key, key) >= 0
+	})
+}
+
+// Add an entry to the subtree and return its new root.
+// The node's entries stay sorted by key if compare is not nil.
+func (node *treeNode[K, V]) insert(hash int, entry *Entry[K, V], compare func(a K, b K) int) *treeNode[K, V] {
+	switch {
+	case node == nil:
+		return &treeNode[K, V]{hash: hash, entries: []*Entry[K, V]{entry}, height: 1}
+	case hash < node.hash:
+		node.left = node.left.insert(hash, entry, compare)
+	case hash > node.hash:
+		node.right = node.right.insert(hash, entry, compare)
+	default:
+		i := node.position(entry.Key, compare)
+		node.entries = append(node.entries, nil)
+		copy(node.entries[i+1:], node.entries[i:])
+		node.entries[i] = entry
+		return node
+	}
+	return node.rebalance()
+}
+
+// Remove an entry from the subtree. Return its new root and true if the entry was there.
+func (node *treeNode[K, V]) delete(hash int, entry *Entry[K, V], compare func(a K, b K) int) (*treeNode[K, V], bool) {
+	if node == nil {
+		return nil, false
+	}
+	var removed bool
+	switch {
+	case hash < node.hash:
+		node.left, removed = node.left.delete(hash, entry, compare)
+	case hash > node.hash:
+		node.right, removed = node.right.delete(hash, entry, compare)
+	default:
+		// Without an order, scan from the start.
+		start := 0
+		if compare != nil {
+			start = node.position(entry.Key, compare)
+		}
+		for i := start; i < len(node.entries); i++ {
+			if node.entries[i] == entry {
+				node.entries = append(node.entries[:i], node.entries[i+1:]...)
+				removed = true
+				break
+			}
+		}
+		if len(node.entries) > 0 {
+			return node, removed
+		}
+
+		// The node is empty. Replace it with its in-order successor.
+		if node.left == nil {
+			return node.right, removed
+		}
+		if node.right == nil {
+			return node.left, removed
+		}
+		successor := node.right
+		for successor.left != nil {
+			successor = successor.left
+		}
+		node.hash, node.entries = successor.hash, successor.entries
+		node.right = node.right.deleteMin()
+	}
+	if !removed {
+		return node, false
+	}
+	return node.rebalance(), true
+}
+
+// Remove the leftmost node of the subtree and return its new root.
+func (node *treeNode[K, V]) deleteMin() *treeNode[K, V] {
+	if node.left == nil {
+		return node.right
+	}
+	node.left = node.left.deleteMin()
+	return node.rebalance()
+}
+
+// Update the node's height and rotate it if its subtrees' heights differ by more than one.
+func (node *treeNode[K, V]) rebalance() *treeNode[K, V] {
+	node.height = 1 + max(node.left.treeHeight(), node.right.treeHeight())
+	switch balance := node.left.treeHeight() - node.right.treeHeight(); {
+	case balance > 1:
+		if node.left.left.treeHeight() < node.left.right.treeHeight() {
+			node.left = node.left.rotateLeft()
+		}
+		return node.rotateRight()
+	case balance < -1:
+		if node.right.right.treeHeight() < node.right.left.treeHeight() {
+			node.right = node.right.rotateRight()
+		}
+		return node.rotateLeft()
+	}
+	return node
+}
+
+func (node *treeNode[K, V]) rotateLeft() *treeNode[K, V] {
+	right := node.right
+	node.right = right.left
+	right.left = node
+	node.height = 1 + max(node.left.treeHeight(), node.right.treeHeight())
+	right.height = 1 + max(right.left.treeHeight(), right.right.treeHeight())
+	return right
+}
+
+func (node *treeNode[K, V]) rotateRight() *treeNode[K, V] {
+	left := node.left
+	node.left = left.right
+	left.right = node
+	node.height = 1 + max(node.left.treeHeight(), node.right.treeHeight())
+	left.height = 1 + max(left.left.treeHeight(), left.right.treeHeight())
+	return left
+}
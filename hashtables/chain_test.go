@@ -0,0 +1,104 @@
+package hashtables_test
+
+import (
+	"io"
+	"math"
+	"testing"
+
+	"hashtables"
+)
+
+// Build a one-bucket chaining map of ints with tree buckets.
+func newTreeMap(t *testing.T, keys hashtables.KeyHasher[int], n int) *hashtables.ChainingMap[int, int] {
+	t.Helper()
+	m := hashtables.NewChainingMap[int, int](1, keys)
+	m.SetBuckets(hashtables.BucketTree)
+	for i := 0; i < n; i++ {
+		m.Set(i, i)
+	}
+	return m
+}
+
+// Return the most entries examined to find any key.
+func maxProbe(t *testing.T, m *hashtables.ChainingMap[int, int]) int {
+	t.Helper()
+	most := 0
+	m.Range(func(key int, value int) bool {
+		steps := m.Probe(io.Discard, key)
+		if steps < 0 {
+			t.Fatalf("Probe did not find %d", key)
+		}
+		most = max(most, steps)
+		return true
+	})
+	return most
+}
+
+// Return the most steps a balanced tree of n keys needs.
+func avlLimit(n int) int {
+	return int(1.45 * math.Log2(float64(n+2)))
+}
+
+func TestTreeBucketCollisions(t *testing.T) {
+	const n = 1000
+	constant := func(key int) int { return 42 }
+
+	// Ordered keys with the same full hash code are binary searched.
+	ordered := newTreeMap(t, hashtables.OrderedKeys(constant), n)
+	if steps := maxProbe(t, ordered); steps > avlLimit(n) {
+		t.Errorf("finding an ordered key took up to %d steps", steps)
+	}
+	for i := 0; i < n; i += 2 {
+		ordered.Delete(i)
+	}
+	if ordered.Len() != n/2 || ordered.Contains(10) || !ordered.Contains(11) {
+		t.Errorf("Len = %d after deleting the even keys", ordered.Len())
+	}
+	if _, ok := ordered.Lookup(n); ok {
+		t.Error("found a missing key")
+	}
+
+	// Keys without an order are scanned.
+	unordered := newTreeMap(t, hashtables.ComparableKeys(constant), n)
+	if steps := maxProbe(t, unordered); steps != n-1 {
+		t.Errorf("finding an unordered key took up to %d steps, want %d", steps, n-1)
+	}
+}
+
+func TestTreeBucketRebalance(t *testing.T) {
+	const n = 1000
+	identity := func(key int) int { return key }
+
+	// Adding keys in order would make an unbalanced tree a list.
+	m := newTreeMap(t, hashtables.OrderedKeys(identity), n)
+	if steps := maxProbe(t, m); steps > avlLimit(n) {
+		t.Errorf("after adding, finding a key took up to %d steps", steps)
+	}
+
+	// So would deleting every key from one side.
+	for i := 0; i < n*3/4; i++ {
+		m.Delete(i)
+	}
+	if steps := maxProbe(t, m); steps > avlLimit(n/4) {
+		t.Errorf("after deleting, finding a key took up to %d steps", steps)
+	}
+	if miss := m.Stats().Unsuccessful.Max; miss > avlLimit(n/4) {
+		t.Errorf("a miss takes %d steps", miss)
+	}
+
+	// A tree that shrinks turns back into a slice in key order.
+	for i := n * 3 / 4; i < n-hashtables.UntreeifyThreshold; i++ {
+		m.Delete(i)
+	}
+	if steps := maxProbe(t, m); steps != hashtables.UntreeifyThreshold-1 {
+		t.Errorf("the last %d keys took up to %d steps to find", hashtables.UntreeifyThreshold, steps)
+	}
+	next := n - hashtables.UntreeifyThreshold
+	m.Range(func(key int, value int) bool {
+		if key != next || value != key {
+			t.Errorf("Range visited %d: %d, want %d", key, value, next)
+		}
+		next++
+		return true
+	})
+}
@@ -0,0 +1,679 @@
+package hashtables
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ChainingMap keeps a chain of entries in each bucket. By default each chain is a slice.
+type ChainingMap[K any, V any] struct {
+	numBuckets int
+	buckets    []chain[K, V] // A nil chain is an empty bucket.
+	bucketKind BucketKind
+	count      int
+	keys       KeyHasher[K]
+	recorder   Recorder
+	clock      Clock
+	hasTTL     bool // True once any entry has been set with a time to live.
+
+	// During an incremental resize, old holds the previous buckets and moved counts
+	// how many of them have been migrated. migrateBuckets is how many old buckets each
+	// operation migrates, or 0 if Resize rebuilds the table at once.
+	old            *ChainingMap[K, V]
+	moved          int
+	migrateBuckets int
+
+	// Linear hashing splits bucket split whenever the load factor passes maxLoad.
+	// Each round doubles the baseBuckets << level buckets the table started it with.
+	// A maxLoad of 0 keeps the number of buckets fixed.
+	maxLoad     float64
+	baseBuckets int
+	level       int
+	split       int
+}
+
+// ChainingHashTable is the liveProject's chaining table of names and phone numbers.
+type ChainingHashTable = ChainingMap[string, string]
+
+// Initialize a ChainingMap and return a pointer to it.
+func NewChainingMap[K any, V any](numBuckets int, keys KeyHasher[K]) *ChainingMap[K, V] {
+	return &ChainingMap[K, V]{
+		numBuckets: numBuckets,
+		// Allocate the slice of buckets
+		buckets:     make([]chain[K, V], numBuckets),
+		bucketKind:  BucketSlice,
+		keys:        keys,
+		baseBuckets: numBuckets,
+	}
+}
+
+// Initialize a ChainingHashTable and return a pointer to it.
+func NewChainingHashTable(numBuckets int, hash HashFunc) *ChainingHashTable {
+	return NewChainingMap[string, string](numBuckets, StringKeys(hash))
+}
+
+// Return the number of live entries.
+func (hashTable *ChainingMap[K, V]) Len() int {
+	if hashTable.old != nil {
+		return hashTable.count + hashTable.old.count
+	}
+	return hashTable.count
+}
+
+// Return the number of buckets.
+func (hashTable *ChainingMap[K, V]) Capacity() int {
+	return hashTable.numBuckets
+}
+
+// Return the index of the bucket that holds this key.
+func (hashTable *ChainingMap[K, V]) bucketIndex(key K) int {
+	return hashTable.bucketFor(hashTable.keys.Hash(key))
+}
+
+// Return the index of the bucket for this hash code.
+// Buckets before the split pointer have already been split this round,
+// so their keys use the next round's number of buckets.
+func (hashTable *ChainingMap[K, V]) bucketFor(hash int) int {
+	roundBuckets := hashTable.baseBuckets << hashTable.level
+	index := reduce(hash, roundBuckets)
+	if index < hashTable.split {
+		index = reduce(hash, 2*roundBuckets)
+	}
+	return index
+}
+
+// Return the number of entries in a bucket.
+func (hashTable *ChainingMap[K, V]) bucketLen(bucketIndex int) int {
+	if bucket := hashTable.buckets[bucketIndex]; bucket != nil {
+		return bucket.len()
+	}
+	return 0
+}
+
+// Call fn for each entry in a bucket until fn returns false. Return false if fn did.
+func (hashTable *ChainingMap[K, V]) eachEntry(bucketIndex int, fn func(entry *Entry[K, V]) bool) bool {
+	if bucket := hashTable.buckets[bucketIndex]; bucket != nil {
+		return bucket.each(fn)
+	}
+	return true
+}
+
+// Add an entry to a bucket, making the bucket's chain if it is empty.
+func (hashTable *ChainingMap[K, V]) addEntry(bucketIndex int, hash int, entry *Entry[K, V]) {
+	if hashTable.buckets[bucketIndex] == nil {
+		hashTable.buckets[bucketIndex] = newChain[K, V](hashTable.bucketKind, hashTable.keys)
+	}
+	hashTable.buckets[bucketIndex].add(hash, entry)
+}
+
+// Find the bucket and Entry holding this key.
+// Return the bucket number and the number of entries examined before the key was found,
+// which is its position in a slice or list bucket.
+// If the key is not present, return the bucket number and -1.
+// During an incremental resize, Find only searches the new buckets, so inspecting
+// the table doesn't migrate anything.
+func (hashTable *ChainingMap[K, V]) Find(key K) (int, int) {
+	hash := hashTable.keys.Hash(key)
+	bucketIndex := hashTable.bucketFor(hash)
+	_, position := hashTable.searchBucket(bucketIndex, hash, key, nil)
+	return bucketIndex, position
+}
+
+// Return the key's entry or nil.
+func (hashTable *ChainingMap[K, V]) entry(key K) *Entry[K, V] {
+	_, entry, _ := hashTable.search(key, hashTable.keys.Hash(key), nil)
+	return entry
+}
+
+// Search the key's bucket like Find and also return the key's entry or nil.
+// If observe is not nil, call it for each entry examined.
+// During an incremental resize, a key that is still in the old buckets
+// is moved to its new bucket.
+func (hashTable *ChainingMap[K, V]) search(key K, hash int,
+	observe func(kind EventKind, index int, step int)) (int, *Entry[K, V], int) {
+	bucketIndex := hashTable.bucketFor(hash)
+	var visit func(entry *Entry[K, V], step int)
+	if observe != nil {
+		visit = func(entry *Entry[K, V], step int) { observe(EventVisit, bucketIndex, step) }
+	}
+	entry, position := hashTable.searchBucket(bucketIndex, hash, key, visit)
+	if hashTable.old == nil || entry != nil {
+		return bucketIndex, entry, position
+	}
+
+	oldBucketIndex := hashTable.old.bucketFor(hash)
+	entry, _ = hashTable.old.searchBucket(oldBucketIndex, hash, key, nil)
+	if entry == nil {
+		return bucketIndex, nil, -1
+	}
+	hashTable.moveFromOld(oldBucketIndex, hash, entry)
+	_, position = hashTable.buckets[bucketIndex].find(hash, key, hashTable.keys, nil)
+	return bucketIndex, entry, position
+}
+
+// Search one of this table's own buckets, dropping its expired entries first.
+func (hashTable *ChainingMap[K, V]) searchBucket(bucketIndex int, hash int, key K,
+	visit func(entry *Entry[K, V], step int)) (*Entry[K, V], int) {
+	hashTable.expireBucket(bucketIndex)
+	if hashTable.buckets[bucketIndex] == nil {
+		return nil, -1
+	}
+	return hashTable.buckets[bucketIndex].find(hash, key, hashTable.keys, visit)
+}
+
+// Record events with this recorder. A nil recorder stops recording.
+func (hashTable *ChainingMap[K, V]) SetRecorder(recorder Recorder) {
+	hashTable.recorder = recorder
+}
+
+// Record an event if there is a recorder.
+func (hashTable *ChainingMap[K, V]) record(event Event) {
+	if hashTable.recorder != nil {
+		hashTable.recorder(event)
+	}
+}
+
+// Record an event about an entry if there is a recorder.
+// The key and value are only formatted when something is recording.
+func (hashTable *ChainingMap[K, V]) recordEntry(kind EventKind, key K, value V, slot int, step int) {
+	if hashTable.recorder != nil {
+		hashTable.recorder(Event{Kind: kind, Key: format(key), Value: format(value), Slot: slot, Step: step})
+	}
+}
+
+// Start recording an operation on this key and return the observer
+// that records the entries it examines, or nil if nothing is recording.
+func (hashTable *ChainingMap[K, V]) trace(kind EventKind, key K, value string) func(EventKind, int, int) {
+	if hashTable.recorder == nil {
+		return nil
+	}
+	return hashTable.traceEvent(Event{Kind: kind, Key: format(key), Value: value})
+}
+
+// Record the event that starts an operation and return the observer that records its steps.
+func (hashTable *ChainingMap[K, V]) traceEvent(start Event) func(EventKind, int, int) {
+	start.Slot = -1
+	hashTable.record(start)
+	return func(kind EventKind, index int, step int) {
+		hashTable.record(Event{Kind: kind, Key: start.Key, Slot: index, Step: step})
+	}
+}
+
+// Start recording a set of this key and value, or return nil if nothing is recording.
+func (hashTable *ChainingMap[K, V]) traceSet(key K, value V, ttl time.Duration) func(EventKind, int, int) {
+	if hashTable.recorder == nil {
+		return nil
+	}
+	return hashTable.traceEvent(Event{Kind: EventSet, Key: format(key), Value: format(value), TTL: ttl})
+}
+
+// Set where the table gets the time for expiring entries. A nil clock means time.Now.
+func (hashTable *ChainingMap[K, V]) SetClock(clock Clock) {
+	hashTable.clock = clock
+}
+
+// Cut the entries whose time is up out of a bucket. Return how many there were.
+func (hashTable *ChainingMap[K, V]) expireBucket(bucketIndex int) int {
+	bucket := hashTable.buckets[bucketIndex]
+	if !hashTable.hasTTL || bucket == nil {
+		return 0
+	}
+	var expired []*Entry[K, V]
+	bucket.each(func(entry *Entry[K, V]) bool {
+		if entry.expires != 0 && hashTable.clock.expired(entry.expires) {
+			expired = append(expired, entry)
+		}
+		return true
+	})
+	for _, entry := range expired {
+		hashTable.record(Event{Kind: EventExpire, Key: format(entry.Key), Slot: bucketIndex})
+		bucket.remove(hashTable.keys.Hash(entry.Key), entry)
+	}
+	hashTable.count -= len(expired)
+	return len(expired)
+}
+
+// Remove every expired entry. Return how many there were.
+func (hashTable *ChainingMap[K, V]) RemoveExpired() int {
+	removed := 0
+	for bucketIndex := range hashTable.buckets {
+		removed += hashTable.expireBucket(bucketIndex)
+	}
+	if hashTable.old != nil {
+		removed += hashTable.old.RemoveExpired()
+	}
+	return removed
+}
+
+// Add an item to the hash table.
+func (hashTable *ChainingMap[K, V]) Set(key K, value V) {
+	hashTable.set(key, value, 0, 0)
+}
+
+// Add an item that expires once ttl has passed. A ttl of 0 or less means it never expires.
+func (hashTable *ChainingMap[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	hashTable.set(key, value, hashTable.clock.expiry(ttl), ttl)
+}
+
+// Add an item that expires at this time in Unix nanoseconds, or never if expires is 0.
+// The ttl it was set with is only recorded, so a replay can set it again.
+func (hashTable *ChainingMap[K, V]) set(key K, value V, expires int64, ttl time.Duration) {
+	hashTable.migrate(hashTable.migrateBuckets)
+	hash := hashTable.keys.Hash(key)
+	bucketIndex, entry, position := hashTable.search(key, hash, hashTable.traceSet(key, value, ttl))
+	if expires != 0 {
+		hashTable.hasTTL = true
+	}
+
+	// If the entry is found, update its value
+	if entry != nil {
+		entry.Value = value
+		entry.expires = expires
+		hashTable.recordEntry(EventUpdate, key, value, bucketIndex, position)
+		return
+	}
+
+	// If the entry is not found, add it to the bucket
+	hashTable.addEntry(bucketIndex, hash, &Entry[K, V]{Key: key, Value: value, expires: expires})
+	hashTable.count++
+	hashTable.recordEntry(EventClaim, key, value, bucketIndex, hashTable.bucketLen(bucketIndex)-1)
+	hashTable.grow()
+}
+
+// Make later sets grow the table by linear hashing: whenever the load factor passes
+// maxLoad, split the bucket at the split pointer into itself and a new bucket at the end.
+// The table grows one bucket at a time and never rehashes every bucket at once.
+// A maxLoad of 0 stops the growth.
+func (hashTable *ChainingMap[K, V]) SetLinearHashing(maxLoad float64) {
+	hashTable.record(Event{Kind: EventGrowth, Slot: -1, MaxLoad: maxLoad})
+	hashTable.maxLoad = max(0, maxLoad)
+	hashTable.grow()
+}
+
+// Split buckets until the load factor is at most maxLoad.
+func (hashTable *ChainingMap[K, V]) grow() {
+	for hashTable.maxLoad > 0 && float64(hashTable.count) > hashTable.maxLoad*float64(hashTable.numBuckets) {
+		hashTable.splitBucket()
+	}
+}
+
+// Split the bucket at the split pointer. Its keys either stay or move to a new bucket
+// at the end, which is the one the next round's number of buckets sends them to.
+func (hashTable *ChainingMap[K, V]) splitBucket() {
+	roundBuckets := hashTable.baseBuckets << hashTable.level
+	from := hashTable.split
+	hashTable.buckets = append(hashTable.buckets, nil)
+	hashTable.numBuckets++
+	hashTable.split++
+	if hashTable.split == roundBuckets {
+		hashTable.level++
+		hashTable.split = 0
+	}
+	hashTable.record(Event{Kind: EventSplit, Slot: from, Capacity: hashTable.numBuckets})
+
+	bucket := hashTable.buckets[from]
+	if bucket == nil {
+		return
+	}
+	hashTable.buckets[from] = nil
+	bucket.each(func(entry *Entry[K, V]) bool {
+		hash := hashTable.keys.Hash(entry.Key)
+		to := hashTable.bucketFor(hash)
+		hashTable.addEntry(to, hash, entry)
+		if to != from && hashTable.recorder != nil {
+			hashTable.record(Event{Kind: EventMove, Key: format(entry.Key), From: from, Slot: to})
+		}
+		return true
+	})
+}
+
+// Return the linear hashing state, or nil if the table does not grow.
+func (hashTable *ChainingMap[K, V]) linearHashing() *LinearHashing {
+	if hashTable.maxLoad == 0 && hashTable.level == 0 && hashTable.split == 0 {
+		return nil
+	}
+	return &LinearHashing{MaxLoad: hashTable.maxLoad, Level: hashTable.level, Split: hashTable.split,
+		RoundBuckets: hashTable.baseBuckets << hashTable.level}
+}
+
+// Store each bucket's entries in this kind of chain from now on.
+func (hashTable *ChainingMap[K, V]) SetBuckets(kind BucketKind) {
+	hashTable.record(Event{Kind: EventBuckets, Slot: -1, Value: string(kind)})
+	hashTable.rechain(kind)
+	if hashTable.old != nil {
+		hashTable.old.rechain(kind)
+	}
+}
+
+// Rebuild every bucket as this kind of chain.
+func (hashTable *ChainingMap[K, V]) rechain(kind BucketKind) {
+	hashTable.bucketKind = kind
+	for i, bucket := range hashTable.buckets {
+		if bucket == nil {
+			continue
+		}
+		rebuilt := newChain[K, V](kind, hashTable.keys)
+		bucket.each(func(entry *Entry[K, V]) bool {
+			rebuilt.add(hashTable.keys.Hash(entry.Key), entry)
+			return true
+		})
+		hashTable.buckets[i] = rebuilt
+	}
+}
+
+// Return an item from the hash table, or the zero value if it is not present.
+func (hashTable *ChainingMap[K, V]) Get(key K) V {
+	value, _ := hashTable.Lookup(key)
+	return value
+}
+
+// Return an item from the hash table and whether it was present.
+func (hashTable *ChainingMap[K, V]) Lookup(key K) (V, bool) {
+	hashTable.migrate(hashTable.migrateBuckets)
+	_, entry, _ := hashTable.search(key, hashTable.keys.Hash(key), hashTable.trace(EventLookup, key, ""))
+	if entry != nil {
+		return entry.Value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Return true if the key is in the hash table.
+func (hashTable *ChainingMap[K, V]) Contains(key K) bool {
+	hashTable.migrate(hashTable.migrateBuckets)
+	_, entry, _ := hashTable.search(key, hashTable.keys.Hash(key), hashTable.trace(EventLookup, key, ""))
+	return entry != nil
+}
+
+// Delete this key's entry.
+func (hashTable *ChainingMap[K, V]) Delete(key K) {
+	hashTable.migrate(hashTable.migrateBuckets)
+	hash := hashTable.keys.Hash(key)
+	bucketIndex, entry, position := hashTable.search(key, hash, hashTable.trace(EventDelete, key, ""))
+
+	// If the entry was found, cut it out of its bucket
+	if entry != nil {
+		hashTable.buckets[bucketIndex].remove(hash, entry)
+		hashTable.count--
+		if hashTable.recorder != nil {
+			hashTable.record(Event{Kind: EventRemove, Key: format(key), Slot: bucketIndex, Step: position})
+		}
+	}
+}
+
+// Call fn for each entry, bucket by bucket, until fn returns false.
+func (hashTable *ChainingMap[K, V]) Range(fn func(key K, value V) bool) {
+	visit := func(entry *Entry[K, V]) bool {
+		return hashTable.clock.expired(entry.expires) || fn(entry.Key, entry.Value)
+	}
+	for bucketIndex := range hashTable.buckets {
+		if !hashTable.eachEntry(bucketIndex, visit) {
+			return
+		}
+	}
+	if hashTable.old != nil {
+		hashTable.old.Range(fn)
+	}
+}
+
+// Rebuild the table with a new number of buckets, dropping expired entries.
+// In incremental mode, Resize finishes any migration in progress, then starts
+// a new one instead of moving the entries at once.
+func (hashTable *ChainingMap[K, V]) Resize(numBuckets int) {
+	hashTable.record(Event{Kind: EventResize, Slot: -1, Capacity: numBuckets, Migrate: hashTable.migrateBuckets})
+	hashTable.FinishResize()
+
+	resized := NewChainingMap[K, V](numBuckets, hashTable.keys)
+	resized.clock = hashTable.clock
+	resized.hasTTL = hashTable.hasTTL
+	resized.bucketKind = hashTable.bucketKind
+	resized.migrateBuckets = hashTable.migrateBuckets
+	resized.maxLoad = hashTable.maxLoad
+	if hashTable.migrateBuckets > 0 {
+		old := *hashTable
+		old.recorder = nil
+		resized.old = &old
+		resized.recorder = hashTable.recorder
+		*hashTable = *resized
+		return
+	}
+	for i := range hashTable.buckets {
+		hashTable.eachEntry(i, func(entry *Entry[K, V]) bool {
+			if hashTable.clock.expired(entry.expires) {
+				return true
+			}
+			resized.set(entry.Key, entry.Value, entry.expires, 0)
+			if hashTable.recorder != nil {
+				hashTable.record(Event{Kind: EventMove, Key: format(entry.Key), From: i,
+					Slot: resized.bucketIndex(entry.Key)})
+			}
+			return true
+		})
+	}
+	resized.recorder = hashTable.recorder
+	*hashTable = *resized
+}
+
+// Make later resizes incremental, migrating this many old buckets during each Set, Get,
+// Contains and Delete, the way Redis rehashes its dictionaries. Until the migration
+// is done, the table keeps both sets of buckets and searches them both.
+// A bucketsPerOp of 0 makes Resize rebuild the table at once again.
+func (hashTable *ChainingMap[K, V]) SetIncrementalResize(bucketsPerOp int) {
+	hashTable.migrateBuckets = max(0, bucketsPerOp)
+}
+
+// Migrate every old bucket that is left from an incremental resize.
+func (hashTable *ChainingMap[K, V]) FinishResize() {
+	if hashTable.old != nil {
+		hashTable.migrate(hashTable.old.numBuckets - hashTable.moved)
+	}
+}
+
+// Migrate up to n old buckets during an incremental resize.
+func (hashTable *ChainingMap[K, V]) migrate(n int) {
+	for ; n > 0 && hashTable.old != nil; n-- {
+		oldBucketIndex := hashTable.moved
+		hashTable.old.expireBucket(oldBucketIndex)
+		hashTable.old.eachEntry(oldBucketIndex, func(entry *Entry[K, V]) bool {
+			hashTable.addMoved(oldBucketIndex, hashTable.keys.Hash(entry.Key), entry)
+			return true
+		})
+		hashTable.old.count -= hashTable.old.bucketLen(oldBucketIndex)
+		hashTable.old.buckets[oldBucketIndex] = nil
+
+		hashTable.moved++
+		if hashTable.moved == hashTable.old.numBuckets {
+			hashTable.old = nil
+			hashTable.moved = 0
+		}
+	}
+}
+
+// Move one entry from an old bucket to its new bucket.
+func (hashTable *ChainingMap[K, V]) moveFromOld(oldBucketIndex int, hash int, entry *Entry[K, V]) {
+	hashTable.old.buckets[oldBucketIndex].remove(hash, entry)
+	hashTable.old.count--
+	hashTable.addMoved(oldBucketIndex, hash, entry)
+}
+
+// Add an entry taken from an old bucket to its new bucket.
+func (hashTable *ChainingMap[K, V]) addMoved(oldBucketIndex int, hash int, entry *Entry[K, V]) {
+	bucketIndex := hashTable.bucketFor(hash)
+	hashTable.addEntry(bucketIndex, hash, entry)
+	hashTable.count++
+	if hashTable.recorder != nil {
+		hashTable.record(Event{Kind: EventMove, Key: format(entry.Key), From: oldBucketIndex, Slot: bucketIndex})
+	}
+}
+
+// Return the progress of an incremental resize, or nil if there is none.
+func (hashTable *ChainingMap[K, V]) migration() *Migration {
+	if hashTable.old == nil {
+		return nil
+	}
+	return &Migration{OldCapacity: hashTable.old.numBuckets, Moved: hashTable.moved, Left: hashTable.old.count}
+}
+
+// Return true if the bucket's chain has turned into a tree.
+func (hashTable *ChainingMap[K, V]) treeified(bucketIndex int) bool {
+	tree, ok := hashTable.buckets[bucketIndex].(*treeChain[K, V])
+	return ok && tree.treeified()
+}
+
+// Describe each bucket for display.
+func (hashTable *ChainingMap[K, V]) Slots() []Slot {
+	slots := make([]Slot, hashTable.numBuckets)
+	for i := range hashTable.buckets {
+		slots[i] = Slot{State: SlotEmpty, Home: i, Entries: hashTable.bucketLen(i), Chained: true}
+		hashTable.eachEntry(i, func(entry *Entry[K, V]) bool {
+			slots[i].State = SlotLive
+			slots[i].Name = format(entry.Key)
+			return false
+		})
+	}
+	return slots
+}
+
+// Return the index of the bucket that Find examines for this key.
+func (hashTable *ChainingMap[K, V]) ProbePath(key K) []int {
+	return []int{hashTable.bucketIndex(key)}
+}
+
+// Display the hash table's contents.
+func (hashTable *ChainingMap[K, V]) Dump(w io.Writer) {
+	for i := range hashTable.buckets {
+		if hashTable.treeified(i) {
+			fmt.Fprintf(w, "Bucket %d (tree):\n", i)
+		} else {
+			fmt.Fprintf(w, "Bucket %d:\n", i)
+		}
+		hashTable.eachEntry(i, func(entry *Entry[K, V]) bool {
+			fmt.Fprintf(w, "\t%s: %s\n", format(entry.Key), format(entry.Value))
+			return true
+		})
+	}
+	if migration := hashTable.migration(); migration != nil {
+		migration.Dump(w)
+		hashTable.old.Dump(w)
+	}
+}
+
+// Make a display showing each bucket's chain length.
+// Empty buckets are shown as '.', chains longer than 9 as '+' and trees as 'T'.
+func (hashTable *ChainingMap[K, V]) DumpConcise(w io.Writer) {
+	for i := range hashTable.buckets {
+		hashTable.dumpBucketLength(w, i)
+		if i%50 == 49 {
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintln(w)
+
+	// Show the old buckets too, with the ones already migrated as '>'.
+	if migration := hashTable.migration(); migration != nil {
+		migration.Dump(w)
+		for i := range hashTable.old.buckets {
+			if i < hashTable.moved {
+				fmt.Fprint(w, ">")
+			} else {
+				hashTable.old.dumpBucketLength(w, i)
+			}
+			if i%50 == 49 {
+				fmt.Fprintln(w)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// Show one bucket's chain length as a single character.
+func (hashTable *ChainingMap[K, V]) dumpBucketLength(w io.Writer, bucketIndex int) {
+	length := hashTable.bucketLen(bucketIndex)
+	switch {
+	case length == 0:
+		fmt.Fprint(w, ".")
+	case hashTable.treeified(bucketIndex):
+		fmt.Fprint(w, "T")
+	case length > 9:
+		fmt.Fprint(w, "+")
+	default:
+		fmt.Fprint(w, length)
+	}
+}
+
+// Show the entries examined while looking for this key.
+// Return the number of entries examined before the key, or -1 if it is not present.
+func (hashTable *ChainingMap[K, V]) Probe(w io.Writer, key K) int {
+	hash := hashTable.keys.Hash(key)
+	bucketIndex := hashTable.bucketFor(hash)
+	fmt.Fprintf(w, "Probing %s (bucket %d)\n", format(key), bucketIndex)
+	_, position := hashTable.searchBucket(bucketIndex, hash, key, func(entry *Entry[K, V], step int) {
+		fmt.Fprintf(w, "    %d: %s\n", step, format(entry.Key))
+	})
+	if position < 0 {
+		fmt.Fprintf(w, "    Not found\n")
+	} else {
+		fmt.Fprintf(w, "    Returning found position %d\n", position)
+	}
+	return position
+}
+
+// Return the number of entries examined to find each entry in the table.
+func (hashTable *ChainingMap[K, V]) successfulLengths() []int {
+	var lengths []int
+	for i, bucket := range hashTable.buckets {
+		hashTable.eachEntry(i, func(entry *Entry[K, V]) bool {
+			_, position := bucket.find(hashTable.keys.Hash(entry.Key), entry.Key, hashTable.keys, nil)
+			lengths = append(lengths, position+1)
+			return true
+		})
+	}
+	return lengths
+}
+
+// Return the average number of entries examined to find the items in the table.
+func (hashTable *ChainingMap[K, V]) AveProbeSequenceLength() float32 {
+	totalLength := 0
+	for _, length := range hashTable.successfulLengths() {
+		totalLength += length
+	}
+
+	// An empty table has no probe sequences to average.
+	if hashTable.count == 0 {
+		return 0
+	}
+	return float32(totalLength) / float32(hashTable.count)
+}
+
+// Collect the stats for the table.
+func (hashTable *ChainingMap[K, V]) Stats() Stats {
+	stats := Stats{Capacity: hashTable.numBuckets, ChainLengths: []int{}}
+
+	var unsuccessful []int
+	for i, bucket := range hashTable.buckets {
+		length := hashTable.bucketLen(i)
+		stats.Live += length
+		if length == 0 {
+			stats.Empty++
+		}
+		stats.ChainLengths = increment(stats.ChainLengths, length)
+		if length > stats.MaxChain {
+			stats.MaxChain = length
+		}
+
+		// A miss examines the whole chain of a slice or list,
+		// but only one path through a sorted slice or tree.
+		missLength := 0
+		if bucket != nil {
+			missLength = bucket.missLength()
+		}
+		unsuccessful = append(unsuccessful, missLength)
+	}
+	stats.LoadFactor = float64(stats.Live) / float64(hashTable.numBuckets)
+	stats.Successful = newProbeStats(hashTable.successfulLengths())
+	stats.Unsuccessful = newProbeStats(unsuccessful)
+	stats.Migration = hashTable.migration()
+	stats.LinearHashing = hashTable.linearHashing()
+	return stats
+}
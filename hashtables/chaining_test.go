@@ -0,0 +1,68 @@
+package hashtables_test
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"hashtables"
+)
+
+func TestLinearHashing(t *testing.T) {
+	// With identity hash codes, keys land in predictable buckets.
+	m := hashtables.NewChainingMap[int, int](4, hashtables.OrderedKeys(func(key int) int { return key }))
+	m.SetLinearHashing(1)
+	for i := 0; i < 100; i++ {
+		m.Set(i, i)
+		if m.Capacity() < m.Len() {
+			t.Fatalf("%d keys in %d buckets", m.Len(), m.Capacity())
+		}
+	}
+
+	// 4 buckets double to 64 in four rounds, and 36 of those have split again.
+	want := hashtables.LinearHashing{MaxLoad: 1, Level: 4, Split: 36, RoundBuckets: 64}
+	if growth := m.Stats().LinearHashing; growth == nil || *growth != want {
+		t.Errorf("linear hashing %+v, want %+v", growth, want)
+	}
+	if m.Capacity() != 100 {
+		t.Errorf("%d buckets, want 100", m.Capacity())
+	}
+
+	// A key in a bucket that has split this round uses the next round's buckets.
+	for i := 0; i < 100; i++ {
+		bucket := i % 64
+		if bucket < 36 {
+			bucket = i % 128
+		}
+		if path := m.ProbePath(i); !reflect.DeepEqual(path, []int{bucket}) {
+			t.Errorf("key %d is in bucket %v, want %d", i, path, bucket)
+		}
+		if m.Probe(io.Discard, i) < 0 {
+			t.Errorf("key %d is not in its bucket", i)
+		}
+	}
+	if stats := m.Stats(); stats.MaxChain != 1 {
+		t.Errorf("the longest chain has %d keys", stats.MaxChain)
+	}
+
+	// Lowering the load factor splits more buckets at once.
+	m.SetLinearHashing(0.5)
+	want = hashtables.LinearHashing{MaxLoad: 0.5, Level: 5, Split: 72, RoundBuckets: 128}
+	if growth := m.Stats().LinearHashing; growth == nil || *growth != want {
+		t.Errorf("linear hashing %+v, want %+v", growth, want)
+	}
+	for i := 0; i < 100; i++ {
+		if value, ok := m.Lookup(i); !ok || value != i {
+			t.Errorf("Lookup(%d) = %d, %v", i, value, ok)
+		}
+	}
+
+	// Turning growth off keeps the buckets.
+	m.SetLinearHashing(0)
+	for i := 100; i < 300; i++ {
+		m.Set(i, i)
+	}
+	if m.Capacity() != 200 || m.Len() != 300 {
+		t.Errorf("%d keys in %d buckets after turning growth off", m.Len(), m.Capacity())
+	}
+}
@@ -0,0 +1,327 @@
+// Command compare builds each hash table strategy at several load factors
+// and reports timing, probe statistics and memory for each combination.
+//
+// Usage:
+//
+//	compare -strategies linear,double -capacity 1009 -loads 0.5,0.9 -hash djb2 -seed 12345 -generator pairs
+//	compare -keys names.txt -format csv
+//	compare -perfect
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"hashtables"
+	"hashtables/workload"
+)
+
+// result holds the measurements for one strategy at one load factor.
+type result struct {
+	Strategy   string  `json:"strategy"`
+	Hash       string  `json:"hash"`
+	Capacity   int     `json:"capacity"`
+	TargetLoad float64 `json:"target_load"`
+	Keys       int     `json:"keys"`
+	LoadFactor float64 `json:"load_factor"`
+
+	InsertNs float64 `json:"insert_ns_per_op"`
+	HitNs    float64 `json:"hit_ns_per_op"`
+	MissNs   float64 `json:"miss_ns_per_op"`
+
+	MeanHitProbe  float64 `json:"mean_hit_probe"`
+	P99HitProbe   int     `json:"p99_hit_probe"`
+	MaxHitProbe   int     `json:"max_hit_probe"`
+	MeanMissProbe float64 `json:"mean_miss_probe"`
+	P99MissProbe  int     `json:"p99_miss_probe"`
+	MaxCluster    int     `json:"max_cluster"`
+	MaxChain      int     `json:"max_chain"`
+
+	Bytes      uint64  `json:"bytes"`
+	BitsPerKey float64 `json:"bits_per_key,omitempty"` // Only for perfect hash tables.
+	Error      string  `json:"error,omitempty"`
+}
+
+func main() {
+	strategies := flag.String("strategies", strings.Join(hashtables.StrategyNames(), ","),
+		"comma-separated strategies to compare")
+	capacity := flag.Int("capacity", 1009, "slots or buckets in each table")
+	loads := flag.String("loads", "0.5,0.75,0.9", "comma-separated target load factors")
+	hashName := flag.String("hash", "djb2",
+		"hash function ("+strings.Join(hashtables.HasherNames(), ", ")+")")
+	seed := flag.Int64("seed", 12345, "seed for the key generator")
+	keyFile := flag.String("keys", "", "file with one key per line (overrides -generator)")
+	generatorName := flag.String("generator", "pairs",
+		"key generator ("+strings.Join(workload.GeneratorNames(), ", ")+")")
+	format := flag.String("format", "table", "output format (table, csv, json)")
+	perfect := flag.Bool("perfect", false, "also build a minimal perfect hash table of each key set")
+	flag.Parse()
+
+	results, err := run(*strategies, *capacity, *loads, *hashName, *seed, *keyFile, *generatorName, *perfect)
+	if err == nil {
+		err = write(os.Stdout, *format, results)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "compare:", err)
+		os.Exit(1)
+	}
+}
+
+// Parse the options, load or generate the keys and measure every combination.
+func run(strategyList string, capacity int, loadList string, hashName string,
+	seed int64, keyFile string, generatorName string, perfect bool) ([]result, error) {
+	hash, err := hashtables.LookupHasher(hashName)
+	if err != nil {
+		return nil, err
+	}
+	loads, err := parseLoads(loadList)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure every strategy exists before measuring any of them.
+	strategies := strings.Split(strategyList, ",")
+	for i, strategy := range strategies {
+		strategy = strings.TrimSpace(strategy)
+		strategies[i] = strategy
+		if _, err := hashtables.NewTable(strategy, capacity, hash); err != nil {
+			return nil, err
+		}
+	}
+
+	// Find the largest number of keys we need.
+	maxKeys := 0
+	for _, load := range loads {
+		maxKeys = max(maxKeys, int(float64(capacity)*load))
+	}
+
+	var keys []string
+	if keyFile != "" {
+		file, err := os.Open(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys, err = workload.ReadKeys(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		generator, err := workload.Lookup(generatorName)
+		if err != nil {
+			return nil, err
+		}
+		keys = generator(maxKeys, seed)
+	}
+	misses := workload.Misses(keys)
+
+	var results []result
+	for _, strategy := range strategies {
+		for _, load := range loads {
+			numKeys := min(int(float64(capacity)*load), len(keys))
+			r := measure(strategy, capacity, hash, keys[:numKeys], misses[:numKeys])
+			r.Hash = hashName
+			r.TargetLoad = load
+			results = append(results, r)
+		}
+	}
+	if perfect {
+		for _, load := range loads {
+			numKeys := min(int(float64(capacity)*load), len(keys))
+			r := measurePerfect(hash, keys[:numKeys], misses[:numKeys])
+			r.Hash = hashName
+			r.TargetLoad = load
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// Parse a comma-separated list of load factors.
+func parseLoads(list string) ([]float64, error) {
+	var loads []float64
+	for _, field := range strings.Split(list, ",") {
+		load, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || load <= 0 {
+			return nil, fmt.Errorf("bad load factor %q", field)
+		}
+		loads = append(loads, load)
+	}
+	return loads, nil
+}
+
+// Build one table and time its inserts, hits and misses.
+func measure(strategy string, capacity int, hash hashtables.HashFunc,
+	keys []string, misses []string) (r result) {
+	r = result{Strategy: strategy, Capacity: capacity, Keys: len(keys)}
+
+	// Open addressing tables panic when they run out of slots.
+	defer func() {
+		if p := recover(); p != nil {
+			r.Error = fmt.Sprint(p)
+		}
+	}()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	table, _ := hashtables.NewTable(strategy, capacity, hash)
+	start := time.Now()
+	for _, key := range keys {
+		table.Set(key, key)
+	}
+	r.InsertNs = nsPerOp(time.Since(start), len(keys))
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	if after.HeapAlloc > before.HeapAlloc {
+		r.Bytes = after.HeapAlloc - before.HeapAlloc
+	}
+
+	start = time.Now()
+	for _, key := range keys {
+		table.Get(key)
+	}
+	r.HitNs = nsPerOp(time.Since(start), len(keys))
+
+	start = time.Now()
+	for _, miss := range misses {
+		table.Get(miss)
+	}
+	r.MissNs = nsPerOp(time.Since(start), len(misses))
+
+	stats := table.Stats()
+	r.LoadFactor = stats.LoadFactor
+	r.MeanHitProbe = stats.Successful.Mean
+	r.P99HitProbe = stats.Successful.P99
+	r.MaxHitProbe = stats.Successful.Max
+	r.MeanMissProbe = stats.Unsuccessful.Mean
+	r.P99MissProbe = stats.Unsuccessful.P99
+	r.MaxCluster = stats.MaxCluster
+	r.MaxChain = stats.MaxChain
+	return r
+}
+
+// Build a minimal perfect hash table of the keys and time its build, hits and misses.
+// The build time per key goes in the insert column.
+func measurePerfect(hash hashtables.HashFunc, keys []string, misses []string) result {
+	r := result{Strategy: "perfect", Capacity: len(keys), Keys: len(keys)}
+	entries := make([]hashtables.Employee, len(keys))
+	for i, key := range keys {
+		entries[i] = hashtables.Employee{Key: key, Value: key}
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	table, build, err := hashtables.BuildPerfectMap(entries, hashtables.StringKeys(hash), 0)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.InsertNs = nsPerOp(build.BuildTime, len(keys))
+	r.BitsPerKey = build.BitsPerKey
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(entries)
+	if after.HeapAlloc > before.HeapAlloc {
+		r.Bytes = after.HeapAlloc - before.HeapAlloc
+	}
+
+	start := time.Now()
+	for _, key := range keys {
+		table.Get(key)
+	}
+	r.HitNs = nsPerOp(time.Since(start), len(keys))
+
+	start = time.Now()
+	for _, miss := range misses {
+		table.Get(miss)
+	}
+	r.MissNs = nsPerOp(time.Since(start), len(misses))
+
+	stats := table.Stats()
+	r.LoadFactor = stats.LoadFactor
+	r.MeanHitProbe = stats.Successful.Mean
+	r.P99HitProbe = stats.Successful.P99
+	r.MaxHitProbe = stats.Successful.Max
+	r.MeanMissProbe = stats.Unsuccessful.Mean
+	r.P99MissProbe = stats.Unsuccessful.P99
+	return r
+}
+
+// Return the average time per operation in nanoseconds.
+func nsPerOp(elapsed time.Duration, ops int) float64 {
+	if ops == 0 {
+		return 0
+	}
+	return float64(elapsed.Nanoseconds()) / float64(ops)
+}
+
+var header = []string{
+	"strategy", "hash", "capacity", "target", "keys", "load",
+	"insert ns/op", "hit ns/op", "miss ns/op",
+	"hit mean", "hit p99", "hit max", "miss mean", "miss p99",
+	"max cluster", "max chain", "bytes", "bits/key", "error",
+}
+
+// Return the result's fields in header order.
+func (r result) fields() []string {
+	return []string{
+		r.Strategy, r.Hash, strconv.Itoa(r.Capacity),
+		strconv.FormatFloat(r.TargetLoad, 'f', 2, 64),
+		strconv.Itoa(r.Keys),
+		strconv.FormatFloat(r.LoadFactor, 'f', 3, 64),
+		strconv.FormatFloat(r.InsertNs, 'f', 1, 64),
+		strconv.FormatFloat(r.HitNs, 'f', 1, 64),
+		strconv.FormatFloat(r.MissNs, 'f', 1, 64),
+		strconv.FormatFloat(r.MeanHitProbe, 'f', 3, 64),
+		strconv.Itoa(r.P99HitProbe),
+		strconv.Itoa(r.MaxHitProbe),
+		strconv.FormatFloat(r.MeanMissProbe, 'f', 3, 64),
+		strconv.Itoa(r.P99MissProbe),
+		strconv.Itoa(r.MaxCluster),
+		strconv.Itoa(r.MaxChain),
+		strconv.FormatUint(r.Bytes, 10),
+		strconv.FormatFloat(r.BitsPerKey, 'f', 2, 64),
+		r.Error,
+	}
+}
+
+// Write the results in the requested format.
+func write(w io.Writer, format string, results []result) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+		for _, r := range results {
+			fmt.Fprintln(tw, strings.Join(r.fields(), "\t")+"\t")
+		}
+		return tw.Flush()
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		for _, r := range results {
+			cw.Write(r.fields())
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+	return fmt.Errorf("unknown format %q (have table, csv, json)", format)
+}
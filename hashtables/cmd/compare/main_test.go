@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	results, err := run(" linear , chaining", 101, "0.5, 0.9", "djb2", 7, "", "uniform", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 6 {
+		t.Fatalf("%d results, want 2 strategies and the perfect table at 2 loads", len(results))
+	}
+	for _, r := range results {
+		if r.Error != "" || r.Keys != int(float64(101)*r.TargetLoad) || r.MeanHitProbe < 1 {
+			t.Errorf("result %+v", r)
+		}
+	}
+	if results[0].Strategy != "linear" || results[2].Strategy != "chaining" || results[4].Strategy != "perfect" {
+		t.Errorf("strategies %s, %s and %s", results[0].Strategy, results[2].Strategy, results[4].Strategy)
+	}
+
+	var out bytes.Buffer
+	if err := write(&out, "csv", results); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil || len(records) != 7 || len(records[0]) != len(header) {
+		t.Errorf("%d CSV records: %v", len(records), err)
+	}
+
+	for _, bad := range [][3]string{{"bogus", "0.5", "djb2"}, {"linear", "0", "djb2"}, {"linear", "0.5", "bogus"}} {
+		if _, err := run(bad[0], 101, bad[1], bad[2], 7, "", "uniform", false); err == nil {
+			t.Errorf("ran strategy %q at loads %q with hash %q", bad[0], bad[1], bad[2])
+		}
+	}
+}
+
+// An empty key file measures empty tables.
+func TestRunEmptyKeyFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	results, err := run("linear", 101, "0.5", "djb2", 7, file, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Keys != 0 || r.HitNs != 0 {
+			t.Errorf("result %+v for no keys", r)
+		}
+	}
+}
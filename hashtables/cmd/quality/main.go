@@ -0,0 +1,95 @@
+// Command quality compares the registered hash functions on a key set: avalanche,
+// chi-squared uniformity over a table's slots, full hash collisions, and how
+// independent each pair is when used as hash1 and hash2 for double hashing.
+//
+// Usage:
+//
+//	quality -capacity 1009 -n 10000 -generator pairs
+//	quality -hashes djb2,jenkins -keys names.txt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"hashtables"
+	"hashtables/quality"
+	"hashtables/workload"
+)
+
+func main() {
+	hashList := flag.String("hashes", strings.Join(hashtables.HasherNames(), ","),
+		"comma-separated hash functions to compare")
+	capacity := flag.Int("capacity", 1009, "slots for the uniformity and double hashing tests")
+	numKeys := flag.Int("n", 10000, "number of keys")
+	seed := flag.Int64("seed", 12345, "seed for the key generator")
+	keyFile := flag.String("keys", "", "file with one key per line (overrides -generator)")
+	generatorName := flag.String("generator", "pairs",
+		"key generator ("+strings.Join(workload.GeneratorNames(), ", ")+")")
+	flag.Parse()
+
+	if err := run(*hashList, *capacity, *numKeys, *seed, *keyFile, *generatorName); err != nil {
+		fmt.Fprintln(os.Stderr, "quality:", err)
+		os.Exit(1)
+	}
+}
+
+// Parse the options, load or generate the keys and write the report.
+func run(hashList string, capacity int, numKeys int, seed int64, keyFile string, generatorName string) error {
+	if capacity <= 0 {
+		return fmt.Errorf("capacity must be positive, got %d", capacity)
+	}
+
+	// Make sure every hash function exists before measuring any of them.
+	names := strings.Split(hashList, ",")
+	hashes := make([]hashtables.HashFunc, len(names))
+	for i, name := range names {
+		hash, err := hashtables.LookupHasher(name)
+		if err != nil {
+			return err
+		}
+		hashes[i] = hash
+	}
+
+	keys, err := loadKeys(keyFile, generatorName, numKeys, seed)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d keys, capacity %d\n\n", len(keys), capacity)
+
+	reports := make([]quality.Report, len(names))
+	for i, name := range names {
+		reports[i] = quality.Analyze(name, hashes[i], keys, capacity)
+	}
+	// Swapping hash1 and hash2 gives nearly the same results, so measure each pair once.
+	var pairs []quality.Pair
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			pairs = append(pairs, quality.MeasurePair(names[i], hashes[i], names[j], hashes[j], keys, capacity))
+		}
+	}
+	return quality.WriteReport(os.Stdout, reports, pairs)
+}
+
+func loadKeys(keyFile string, generatorName string, numKeys int, seed int64) ([]string, error) {
+	if keyFile == "" {
+		generator, err := workload.Lookup(generatorName)
+		if err != nil {
+			return nil, err
+		}
+		return generator(numKeys, seed), nil
+	}
+
+	file, err := os.Open(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	keys, err := workload.ReadKeys(file)
+	if err != nil {
+		return nil, err
+	}
+	return keys[:min(numKeys, len(keys))], nil
+}
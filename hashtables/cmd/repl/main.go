@@ -0,0 +1,90 @@
+// Command repl builds a hash table and reads commands to explore it.
+//
+// Usage:
+//
+//	repl -strategy double -capacity 10
+//	repl -strategy linear -capacity 1009 < session.txt
+//	repl -keys fold
+//
+// Type help at the prompt for the list of commands. Scripts piped on stdin
+// run without a prompt, so a saved session replays with the same output.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"hashtables"
+	"hashtables/repl"
+)
+
+func main() {
+	strategy := flag.String("strategy", "linear",
+		"table strategy ("+strings.Join(hashtables.StrategyNames(), ", ")+")")
+	capacity := flag.Int("capacity", 10, "slots or buckets in the table")
+	hashName := flag.String("hash", "djb2",
+		"hash function ("+strings.Join(hashtables.HasherNames(), ", ")+")")
+	keyMode := flag.String("keys", "exact",
+		"how names are compared ("+strings.Join(hashtables.KeyModeNames(), ", ")+")")
+	historyFile := flag.String("history", "", "file to load earlier commands from and save new ones to")
+	flag.Parse()
+
+	session, err := repl.NewSession(*strategy, *capacity, *hashName, *keyMode, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "repl:", err)
+		os.Exit(1)
+	}
+
+	// Only show a prompt when a person is typing.
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		session.Prompt = "> "
+	}
+
+	if *historyFile != "" {
+		session.History = readHistory(*historyFile)
+	}
+	numEarlier := len(session.History)
+
+	err = session.Run(os.Stdin)
+
+	if *historyFile != "" {
+		if err := appendHistory(*historyFile, session.History[numEarlier:]); err != nil {
+			fmt.Fprintln(os.Stderr, "repl:", err)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "repl:", err)
+		os.Exit(1)
+	}
+}
+
+// Read earlier commands from the history file. A missing file means no history.
+func readHistory(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var history []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		history = append(history, scanner.Text())
+	}
+	return history
+}
+
+// Add this session's commands to the end of the history file.
+func appendHistory(path string, lines []string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		fmt.Fprintln(file, line)
+	}
+	return file.Close()
+}
@@ -0,0 +1,190 @@
+// Command viz fills a hash table and draws its occupancy, clusters and a key's probe path.
+//
+// Usage:
+//
+//	viz -strategy linear -capacity 1009 -load 0.9 -mode clusters -probe 17-482913
+//	viz -strategy double -capacity 100003 -format html -o double.html
+//	viz -events session.jsonl -upto 25
+//
+// With -events, the table is rebuilt from a recorded event stream instead of generated keys,
+// and the probe path of the last replayed operation is shown unless -probe is given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"hashtables"
+	"hashtables/viz"
+	"hashtables/workload"
+)
+
+func main() {
+	strategy := flag.String("strategy", "linear",
+		"table strategy ("+strings.Join(hashtables.StrategyNames(), ", ")+")")
+	capacity := flag.Int("capacity", 1009, "slots or buckets in the table")
+	load := flag.Float64("load", 0.9, "target load factor")
+	hashName := flag.String("hash", "djb2",
+		"hash function ("+strings.Join(hashtables.HasherNames(), ", ")+")")
+	seed := flag.Int64("seed", 12345, "seed for the key generator")
+	keyFile := flag.String("keys", "", "file with one key per line (overrides -generator)")
+	generatorName := flag.String("generator", "pairs",
+		"key generator ("+strings.Join(workload.GeneratorNames(), ", ")+")")
+	probe := flag.String("probe", "", "key whose probe path to overlay")
+	modeName := flag.String("mode", "occupancy", "what colors show (occupancy, clusters)")
+	format := flag.String("format", "ansi", "output format (ansi, svg, html)")
+	output := flag.String("o", "", "output file (default stdout)")
+	width := flag.Int("width", 50, "cells per row")
+	rows := flag.Int("rows", 40, "rows before slots are grouped into cells")
+	eventFile := flag.String("events", "", "JSON lines event file to replay instead of generating keys")
+	upto := flag.Int("upto", -1, "replay only this many operations from -events")
+	flag.Parse()
+
+	var err error
+	if *eventFile != "" {
+		err = replay(*eventFile, *upto, *strategy, *capacity, *hashName,
+			*probe, *modeName, *format, *output, *width, *rows)
+	} else {
+		err = run(*strategy, *capacity, *load, *hashName, *seed, *keyFile, *generatorName,
+			*probe, *modeName, *format, *output, *width, *rows)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "viz:", err)
+		os.Exit(1)
+	}
+}
+
+func run(strategy string, capacity int, load float64, hashName string, seed int64,
+	keyFile string, generatorName string, probe string, modeName string,
+	format string, output string, width int, rows int) error {
+	hash, err := hashtables.LookupHasher(hashName)
+	if err != nil {
+		return err
+	}
+	table, err := hashtables.NewTable(strategy, capacity, hash)
+	if err != nil {
+		return err
+	}
+	mode, err := viz.ParseMode(modeName)
+	if err != nil {
+		return err
+	}
+
+	keys, err := loadKeys(keyFile, generatorName, int(float64(capacity)*load), seed)
+	if err != nil {
+		return err
+	}
+	numSet := fill(table, keys)
+	if numSet < len(keys) {
+		fmt.Fprintf(os.Stderr, "viz: table filled after %d of %d keys\n", numSet, len(keys))
+	}
+
+	title := fmt.Sprintf("%s table, %s hash, load %.2f", strategy, hashName, table.Stats().LoadFactor)
+	return draw(table, viz.Options{Mode: mode, Width: width, MaxRows: rows, Probe: probe}, format, output, title)
+}
+
+// Rebuild a table from recorded events and draw it.
+func replay(eventFile string, upto int, strategy string, capacity int, hashName string,
+	probe string, modeName string, format string, output string, width int, rows int) error {
+	file, err := os.Open(eventFile)
+	if err != nil {
+		return err
+	}
+	events, err := hashtables.ReadEvents(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	mode, err := viz.ParseMode(modeName)
+	if err != nil {
+		return err
+	}
+
+	// The recording says which table to build, if it starts with a new event.
+	keyMode := ""
+	if len(events) > 0 && events[0].Kind == hashtables.EventNew {
+		strategy, capacity, hashName, keyMode = events[0].Strategy, events[0].Capacity, events[0].Hash, events[0].Keys
+	}
+	hash, err := hashtables.LookupHasher(hashName)
+	if err != nil {
+		return err
+	}
+	table, err := hashtables.NewKeyedTable(strategy, capacity, hash, keyMode)
+	if err != nil {
+		return err
+	}
+
+	last := hashtables.Replay(table, events, upto)
+	if probe == "" {
+		probe = last.Key
+	}
+	title := fmt.Sprintf("%s table after %s %s", strategy, last.Kind, last.Key)
+	return draw(table, viz.Options{Mode: mode, Width: width, MaxRows: rows, Probe: probe}, format, output, title)
+}
+
+// Draw the table in the requested format.
+func draw(table hashtables.Table, options viz.Options, format string, output string, title string) error {
+	var w io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	grid := viz.Build(table, options)
+	switch format {
+	case "ansi":
+		grid.ANSI(w)
+	case "svg":
+		grid.SVG(w)
+	case "html":
+		grid.HTML(w, title)
+	default:
+		return fmt.Errorf("unknown format %q (have ansi, svg, html)", format)
+	}
+	return nil
+}
+
+// Read the keys from a file or make them with a generator.
+func loadKeys(keyFile string, generatorName string, numKeys int, seed int64) ([]string, error) {
+	if keyFile == "" {
+		generator, err := workload.Lookup(generatorName)
+		if err != nil {
+			return nil, err
+		}
+		return generator(numKeys, seed), nil
+	}
+
+	file, err := os.Open(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	keys, err := workload.ReadKeys(file)
+	if err != nil {
+		return nil, err
+	}
+	return keys[:min(numKeys, len(keys))], nil
+}
+
+// Set each key to itself until the table runs out of room.
+// Return the number of keys set.
+func fill(table hashtables.Table, keys []string) (numSet int) {
+	// Open addressing tables panic when they run out of slots. Anything else is a bug.
+	defer func() {
+		if p := recover(); p != nil && p != "Hash table is full" {
+			panic(p)
+		}
+	}()
+	for _, key := range keys {
+		table.Set(key, key)
+		numSet++
+	}
+	return numSet
+}
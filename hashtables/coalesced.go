@@ -0,0 +1,732 @@
+package hashtables
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// DefaultAddressFactor is the fraction of a coalesced table's slots that keys hash to.
+// Vitter found that about 86% minimizes the probes for hits and misses when the table is full.
+const DefaultAddressFactor = 0.86
+
+// CoalescedMap keeps its entries in a single slot array like the open addressing tables,
+// but chains the keys that collide through the slots like chaining does. Keys hash to
+// the address region at the front of the array. A key whose home slot is taken goes in
+// the highest empty slot, which is in the cellar at the back of the array until the
+// cellar fills up, and is linked to the end of its home slot's chain. Chains that run
+// into each other merge, which is where the name comes from.
+type CoalescedMap[K any, V any] struct {
+	capacity      int
+	addressSize   int     // Keys hash to the first addressSize slots. The rest are the cellar.
+	addressFactor float64 // addressSize as a fraction of capacity, kept by Resize.
+	entries       []*Entry[K, V]
+	next          []int // The next slot in each slot's chain, or -1.
+	free          int   // Every slot from free up holds an entry, live or deleted.
+	count         int
+	keys          KeyHasher[K]
+	recorder      Recorder
+	clock         Clock
+
+	// During an incremental resize, old holds the previous slots and moved counts
+	// how many of them have been migrated. migrateSlots is how many old slots each
+	// operation migrates, or 0 if Resize rebuilds the table at once.
+	old          *CoalescedMap[K, V]
+	moved        int
+	migrateSlots int
+}
+
+// CoalescedHashTable is a coalesced hashing table of names and phone numbers.
+type CoalescedHashTable = CoalescedMap[string, string]
+
+// Initialize a CoalescedMap and return a pointer to it.
+// An addressFactor of 0 means DefaultAddressFactor, and 1 means there is no cellar.
+func NewCoalescedMap[K any, V any](capacity int, keys KeyHasher[K], addressFactor float64) *CoalescedMap[K, V] {
+	if addressFactor <= 0 || addressFactor > 1 {
+		addressFactor = DefaultAddressFactor
+	}
+	next := make([]int, capacity)
+	for i := range next {
+		next[i] = -1
+	}
+	return &CoalescedMap[K, V]{
+		capacity:      capacity,
+		addressSize:   max(1, int(math.Round(addressFactor*float64(capacity)))),
+		addressFactor: addressFactor,
+		// Allocate the slice of entries
+		entries: make([]*Entry[K, V], capacity),
+		next:    next,
+		free:    capacity,
+		keys:    keys,
+	}
+}
+
+// Initialize a CoalescedHashTable with the default cellar and return a pointer to it.
+func NewCoalescedHashTable(capacity int, hash HashFunc) *CoalescedHashTable {
+	return NewCoalescedMap[string, string](capacity, StringKeys(hash), 0)
+}
+
+// Return the key's home slot in the address region.
+func (hashTable *CoalescedMap[K, V]) home(key K) int {
+	return reduce(hashTable.keys.Hash(key), hashTable.addressSize)
+}
+
+// Return the number of live entries.
+func (hashTable *CoalescedMap[K, V]) Len() int {
+	if hashTable.old != nil {
+		return hashTable.count + hashTable.old.count
+	}
+	return hashTable.count
+}
+
+// Return the number of slots, including the cellar.
+func (hashTable *CoalescedMap[K, V]) Capacity() int {
+	return hashTable.capacity
+}
+
+// Return the number of cellar slots.
+func (hashTable *CoalescedMap[K, V]) CellarSize() int {
+	return hashTable.capacity - hashTable.addressSize
+}
+
+// Return the key's index or where it would be if present and the number of slots
+// examined. If the key is not present and its chain has no empty or deleted slot,
+// return -1 for the index: a new key goes in a free slot linked to the chain's end.
+// During an incremental resize, Find only searches the new slots, so inspecting
+// the table doesn't migrate anything.
+func (hashTable *CoalescedMap[K, V]) Find(key K) (int, int) {
+	index, _, probeLength := hashTable.searchSlots(key, nil)
+	return index, probeLength
+}
+
+// Return the indices of the slots that Find visits for this key, in order.
+func (hashTable *CoalescedMap[K, V]) ProbePath(key K) []int {
+	var path []int
+	hashTable.searchSlots(key, func(kind EventKind, index int, step int) {
+		if kind == EventVisit {
+			path = append(path, index)
+		}
+	})
+	return path
+}
+
+// Follow the key's chain like Find, and also return the chain's last slot.
+// If observe is not nil, call it for each slot visited and for the first deleted
+// slot remembered. During an incremental resize, a key that is still in the old
+// slots is moved to the new ones.
+func (hashTable *CoalescedMap[K, V]) search(key K,
+	observe func(kind EventKind, index int, step int)) (int, int, int) {
+	index, last, probeLength := hashTable.searchSlots(key, observe)
+	if hashTable.old == nil || hashTable.live(index) != nil {
+		return index, last, probeLength
+	}
+	oldIndex, _, _ := hashTable.old.searchSlots(key, nil)
+	if hashTable.old.live(oldIndex) != nil {
+		index = hashTable.moveFromOld(oldIndex, index, last)
+	}
+	return index, last, probeLength
+}
+
+// Follow the key's chain through this table's own slots from its home slot.
+// Return the key's slot, or the empty home slot, or the first deleted slot
+// on the chain, or -1. Also return the chain's last slot and the number of
+// slots examined.
+func (hashTable *CoalescedMap[K, V]) searchSlots(key K,
+	observe func(kind EventKind, index int, step int)) (int, int, int) {
+	index := hashTable.home(key)
+
+	// This will be the index of the first deleted item we come across (if we find one).
+	deletedIndex := -1
+
+	// Follow the chain
+	for i := 0; ; i++ {
+		if observe != nil {
+			observe(EventVisit, index, i)
+		}
+
+		// Only a home slot can be empty, since every other slot on a chain was claimed.
+		entry := hashTable.entries[index]
+		if entry == nil {
+			return index, index, i + 1
+		}
+
+		// Lazily turn an entry whose time is up into a tombstone.
+		if entry.expires != 0 && !entry.deleted {
+			hashTable.expire(index)
+		}
+
+		// Remember the first deleted spot. Otherwise, if this spot contains the target, return its index.
+		if entry.deleted {
+			if deletedIndex < 0 {
+				deletedIndex = index
+				if observe != nil {
+					observe(EventTombstone, index, i)
+				}
+			}
+		} else if hashTable.keys.Equal(entry.Key, key) {
+			return index, index, i + 1
+		}
+
+		if hashTable.next[index] < 0 {
+			return deletedIndex, index, i + 1
+		}
+		index = hashTable.next[index]
+	}
+}
+
+// Return a slot for a new key, given what searchSlots found for it: an empty or deleted
+// slot on its chain, or else the highest empty slot, linked to the chain's last slot.
+// If deleted entries fill the only room left, rebuild the slots to reuse them first.
+// Return -1 if every slot is live.
+func (hashTable *CoalescedMap[K, V]) place(key K, index int, last int) int {
+	if index >= 0 {
+		return index
+	}
+	if index = hashTable.claimFree(last); index >= 0 {
+		return index
+	}
+
+	// Expired entries still count until something sweeps them.
+	hashTable.expireSlots()
+	if hashTable.count == hashTable.capacity {
+		return -1
+	}
+	hashTable.compact()
+	if index, last, _ = hashTable.searchSlots(key, nil); index >= 0 {
+		return index
+	}
+	return hashTable.claimFree(last)
+}
+
+// Claim the highest empty slot and link it to the end of the chain at last.
+// Return -1 if no slot is empty.
+func (hashTable *CoalescedMap[K, V]) claimFree(last int) int {
+	for hashTable.free > 0 {
+		hashTable.free--
+		if hashTable.entries[hashTable.free] == nil {
+			hashTable.next[last] = hashTable.free
+			return hashTable.free
+		}
+	}
+	return -1
+}
+
+// Rebuild the slots in place without the deleted and expired entries,
+// so the slots they held can be claimed again.
+func (hashTable *CoalescedMap[K, V]) compact() {
+	hashTable.record(Event{Kind: EventCompact, Slot: -1, Capacity: hashTable.capacity})
+	entries := hashTable.entries
+	hashTable.entries = make([]*Entry[K, V], hashTable.capacity)
+	for i := range hashTable.next {
+		hashTable.next[i] = -1
+	}
+	hashTable.free = hashTable.capacity
+	hashTable.count = 0
+	for i, entry := range entries {
+		if entry == nil || entry.deleted || hashTable.clock.expired(entry.expires) {
+			continue
+		}
+		index, last, _ := hashTable.searchSlots(entry.Key, nil)
+		if index < 0 {
+			index = hashTable.claimFree(last)
+		}
+		hashTable.entries[index] = entry
+		hashTable.count++
+		if hashTable.recorder != nil && index != i {
+			hashTable.record(Event{Kind: EventMove, Key: format(entry.Key), From: i, Slot: index})
+		}
+	}
+}
+
+// Record events with this recorder. A nil recorder stops recording.
+func (hashTable *CoalescedMap[K, V]) SetRecorder(recorder Recorder) {
+	hashTable.recorder = recorder
+}
+
+// Record an event if there is a recorder.
+func (hashTable *CoalescedMap[K, V]) record(event Event) {
+	if hashTable.recorder != nil {
+		hashTable.recorder(event)
+	}
+}
+
+// Record an event about an entry if there is a recorder.
+// The key and value are only formatted when something is recording.
+func (hashTable *CoalescedMap[K, V]) recordEntry(kind EventKind, key K, value V, slot int) {
+	if hashTable.recorder != nil {
+		hashTable.recorder(Event{Kind: kind, Key: format(key), Value: format(value), Slot: slot})
+	}
+}
+
+// Record an event about a key if there is a recorder.
+func (hashTable *CoalescedMap[K, V]) recordKey(kind EventKind, key K, slot int) {
+	if hashTable.recorder != nil {
+		hashTable.recorder(Event{Kind: kind, Key: format(key), Slot: slot})
+	}
+}
+
+// Start recording an operation on this key and return the observer
+// that records its chain, or nil if nothing is recording.
+func (hashTable *CoalescedMap[K, V]) trace(kind EventKind, key K, value string) func(EventKind, int, int) {
+	if hashTable.recorder == nil {
+		return nil
+	}
+	return hashTable.traceEvent(Event{Kind: kind, Key: format(key), Value: value})
+}
+
+// Record the event that starts an operation and return the observer that records its steps.
+func (hashTable *CoalescedMap[K, V]) traceEvent(start Event) func(EventKind, int, int) {
+	start.Slot = -1
+	hashTable.record(start)
+	return func(kind EventKind, index int, step int) {
+		hashTable.record(Event{Kind: kind, Key: start.Key, Slot: index, Step: step})
+	}
+}
+
+// Start recording a set of this key and value, or return nil if nothing is recording.
+func (hashTable *CoalescedMap[K, V]) traceSet(key K, value V, ttl time.Duration) func(EventKind, int, int) {
+	if hashTable.recorder == nil {
+		return nil
+	}
+	return hashTable.traceEvent(Event{Kind: EventSet, Key: format(key), Value: format(value), TTL: ttl})
+}
+
+// Set where the table gets the time for expiring entries. A nil clock means time.Now.
+func (hashTable *CoalescedMap[K, V]) SetClock(clock Clock) {
+	hashTable.clock = clock
+}
+
+// If the entry at this index has expired, mark it as deleted like Delete does.
+// Return true if it expired.
+func (hashTable *CoalescedMap[K, V]) expire(index int) bool {
+	entry := hashTable.entries[index]
+	if entry.deleted || !hashTable.clock.expired(entry.expires) {
+		return false
+	}
+	entry.deleted = true
+	hashTable.count--
+	hashTable.recordKey(EventExpire, entry.Key, index)
+	return true
+}
+
+// Turn every expired entry in this table's own slots into a tombstone.
+// Return how many there were.
+func (hashTable *CoalescedMap[K, V]) expireSlots() int {
+	removed := 0
+	for index, entry := range hashTable.entries {
+		if entry != nil && entry.expires != 0 && hashTable.expire(index) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// Turn every expired entry into a tombstone. Return how many there were.
+func (hashTable *CoalescedMap[K, V]) RemoveExpired() int {
+	removed := hashTable.expireSlots()
+	if hashTable.old != nil {
+		removed += hashTable.old.RemoveExpired()
+	}
+	return removed
+}
+
+// Add an item to the hash table.
+func (hashTable *CoalescedMap[K, V]) Set(key K, value V) {
+	hashTable.set(key, value, 0, 0)
+}
+
+// Add an item that expires once ttl has passed. A ttl of 0 or less means it never expires.
+func (hashTable *CoalescedMap[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	hashTable.set(key, value, hashTable.clock.expiry(ttl), ttl)
+}
+
+// Add an item that expires at this time in Unix nanoseconds, or never if expires is 0.
+// The ttl it was set with is only recorded, so a replay can set it again.
+func (hashTable *CoalescedMap[K, V]) set(key K, value V, expires int64, ttl time.Duration) {
+	hashTable.migrate(hashTable.migrateSlots)
+
+	// Call search to get the index of the key or of a slot on its chain that can take it
+	index, last, _ := hashTable.search(key, hashTable.traceSet(key, value, ttl))
+
+	// If the key is there, update its value.
+	if entry := hashTable.live(index); entry != nil {
+		entry.Value = value
+		entry.expires = expires
+		hashTable.recordEntry(EventUpdate, key, value, index)
+		return
+	}
+
+	// During an incremental resize, the new slots must also keep room for the old entries,
+	// not counting expired ones.
+	if hashTable.old != nil && hashTable.count+hashTable.old.count >= hashTable.capacity {
+		hashTable.RemoveExpired()
+	}
+	if hashTable.old != nil && hashTable.count+hashTable.old.count >= hashTable.capacity {
+		index = -1
+	} else {
+		index = hashTable.place(key, index, last)
+	}
+	if index < 0 {
+		hashTable.recordKey(EventFull, key, -1)
+		panic("Hash table is full")
+	}
+	hashTable.entries[index] = &Entry[K, V]{Key: key, Value: value, expires: expires}
+	hashTable.count++
+	hashTable.recordEntry(EventClaim, key, value, index)
+}
+
+// Return the live entry at this index or nil.
+func (hashTable *CoalescedMap[K, V]) live(index int) *Entry[K, V] {
+	if index < 0 || hashTable.entries[index] == nil || hashTable.entries[index].deleted {
+		return nil
+	}
+	return hashTable.entries[index]
+}
+
+// Return an item from the hash table, or the zero value if it is not present.
+func (hashTable *CoalescedMap[K, V]) Get(key K) V {
+	value, _ := hashTable.Lookup(key)
+	return value
+}
+
+// Return an item from the hash table and whether it was present.
+func (hashTable *CoalescedMap[K, V]) Lookup(key K) (V, bool) {
+	hashTable.migrate(hashTable.migrateSlots)
+	index, _, _ := hashTable.search(key, hashTable.trace(EventLookup, key, ""))
+	if entry := hashTable.live(index); entry != nil {
+		return entry.Value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Return true if the key is in the hash table.
+func (hashTable *CoalescedMap[K, V]) Contains(key K) bool {
+	hashTable.migrate(hashTable.migrateSlots)
+	index, _, _ := hashTable.search(key, hashTable.trace(EventLookup, key, ""))
+	return hashTable.live(index) != nil
+}
+
+// Delete an item from the hash table. Its slot stays on the chain as a tombstone
+// so the keys after it can still be found.
+func (hashTable *CoalescedMap[K, V]) Delete(key K) {
+	hashTable.migrate(hashTable.migrateSlots)
+	index, _, _ := hashTable.search(key, hashTable.trace(EventDelete, key, ""))
+
+	// If we found the Entry struct, mark it as deleted.
+	if entry := hashTable.live(index); entry != nil {
+		entry.deleted = true
+		hashTable.count--
+		hashTable.recordKey(EventRemove, key, index)
+	}
+}
+
+// Call fn for each live entry in slot order until fn returns false.
+func (hashTable *CoalescedMap[K, V]) Range(fn func(key K, value V) bool) {
+	for _, entry := range hashTable.entries {
+		if entry == nil || entry.deleted || hashTable.clock.expired(entry.expires) {
+			continue
+		}
+		if !fn(entry.Key, entry.Value) {
+			return
+		}
+	}
+	if hashTable.old != nil {
+		hashTable.old.Range(fn)
+	}
+}
+
+// Rebuild the table with a new capacity and the same fraction of cellar slots,
+// dropping deleted and expired entries.
+// If the entries do not fit, Resize panics and leaves the table unchanged.
+// In incremental mode, Resize finishes any migration in progress, then starts
+// a new one instead of moving the entries at once.
+func (hashTable *CoalescedMap[K, V]) Resize(capacity int) {
+	hashTable.record(Event{Kind: EventResize, Slot: -1, Capacity: capacity, Migrate: hashTable.migrateSlots})
+	hashTable.FinishResize()
+
+	resized := NewCoalescedMap[K, V](capacity, hashTable.keys, hashTable.addressFactor)
+	resized.clock = hashTable.clock
+	resized.migrateSlots = hashTable.migrateSlots
+	if hashTable.migrateSlots > 0 {
+		// Expired entries won't be migrated, so they don't need room.
+		hashTable.RemoveExpired()
+		if hashTable.count > capacity {
+			panic("Hash table is full")
+		}
+		old := *hashTable
+		old.recorder = nil
+		resized.old = &old
+		resized.recorder = hashTable.recorder
+		*hashTable = *resized
+		return
+	}
+
+	var moves []Event
+	for i, entry := range hashTable.entries {
+		if entry != nil && !entry.deleted && !hashTable.clock.expired(entry.expires) {
+			resized.set(entry.Key, entry.Value, entry.expires, 0)
+			if hashTable.recorder != nil {
+				index, _ := resized.Find(entry.Key)
+				moves = append(moves, Event{Kind: EventMove, Key: format(entry.Key), From: i, Slot: index})
+			}
+		}
+	}
+	resized.recorder = hashTable.recorder
+	*hashTable = *resized
+
+	// Only report the moves once every entry has fit.
+	for _, move := range moves {
+		hashTable.record(move)
+	}
+}
+
+// Make later resizes incremental, migrating this many old slots during each Set, Get,
+// Contains and Delete. Until the migration is done, the table keeps both arrays
+// and searches them both. A slotsPerOp of 0 makes Resize rebuild the table at once again.
+func (hashTable *CoalescedMap[K, V]) SetIncrementalResize(slotsPerOp int) {
+	hashTable.migrateSlots = max(0, slotsPerOp)
+}
+
+// Migrate every old slot that is left from an incremental resize.
+func (hashTable *CoalescedMap[K, V]) FinishResize() {
+	if hashTable.old != nil {
+		hashTable.migrate(hashTable.old.capacity - hashTable.moved)
+	}
+}
+
+// Migrate up to n old slots during an incremental resize.
+func (hashTable *CoalescedMap[K, V]) migrate(n int) {
+	for ; n > 0 && hashTable.old != nil; n-- {
+		oldIndex := hashTable.moved
+		if entry := hashTable.old.entries[oldIndex]; entry != nil && !entry.deleted && !hashTable.old.expire(oldIndex) {
+			index, last, _ := hashTable.searchSlots(entry.Key, nil)
+			hashTable.moveFromOld(oldIndex, index, last)
+		}
+		hashTable.moved++
+		if hashTable.moved == hashTable.old.capacity {
+			hashTable.old = nil
+			hashTable.moved = 0
+		}
+	}
+}
+
+// Move the live entry in an old slot to a slot for it on its new chain, given what
+// searchSlots found for it. The old slot becomes a tombstone so the old chains
+// through it still work. Return the new slot.
+func (hashTable *CoalescedMap[K, V]) moveFromOld(oldIndex int, index int, last int) int {
+	entry := hashTable.old.entries[oldIndex]
+	index = hashTable.place(entry.Key, index, last)
+	if index < 0 {
+		panic("Hash table is full")
+	}
+	hashTable.entries[index] = &Entry[K, V]{Key: entry.Key, Value: entry.Value, expires: entry.expires}
+	hashTable.count++
+	entry.deleted = true
+	hashTable.old.count--
+	if hashTable.recorder != nil {
+		hashTable.record(Event{Kind: EventMove, Key: format(entry.Key), From: oldIndex, Slot: index})
+	}
+	return index
+}
+
+// Return the progress of an incremental resize, or nil if there is none.
+func (hashTable *CoalescedMap[K, V]) migration() *Migration {
+	if hashTable.old == nil {
+		return nil
+	}
+	return &Migration{OldCapacity: hashTable.old.capacity, Moved: hashTable.moved, Left: hashTable.old.count}
+}
+
+// Describe each slot for display.
+func (hashTable *CoalescedMap[K, V]) Slots() []Slot {
+	slots := make([]Slot, hashTable.capacity)
+	for i, entry := range hashTable.entries {
+		switch {
+		case entry == nil:
+			slots[i] = Slot{State: SlotEmpty}
+		case entry.deleted:
+			slots[i] = Slot{State: SlotDeleted}
+		default:
+			slots[i] = Slot{State: SlotLive, Name: format(entry.Key), Home: hashTable.home(entry.Key), Entries: 1}
+		}
+	}
+	return slots
+}
+
+// Display the hash table's contents with the link from each slot to the next one on its chain.
+func (hashTable *CoalescedMap[K, V]) Dump(w io.Writer) {
+	for i, entry := range hashTable.entries {
+		if i == hashTable.addressSize {
+			fmt.Fprintln(w, "Cellar:")
+		}
+		if entry == nil {
+			fmt.Fprintf(w, "%d: ---\n", i)
+			continue
+		}
+		if entry.deleted {
+			fmt.Fprintf(w, "%d: xxx", i)
+		} else {
+			fmt.Fprintf(w, "%d: %s\t%s", i, format(entry.Key), format(entry.Value))
+		}
+		if hashTable.next[i] >= 0 {
+			fmt.Fprintf(w, "\t-> %d", hashTable.next[i])
+		}
+		fmt.Fprintln(w)
+	}
+	if migration := hashTable.migration(); migration != nil {
+		migration.Dump(w)
+		hashTable.old.Dump(w)
+	}
+}
+
+// Make a display showing whether each array entry is nil, with the cellar on its own rows.
+func (hashTable *CoalescedMap[K, V]) DumpConcise(w io.Writer) {
+	hashTable.dumpSlots(w, 0)
+
+	// Show the old slots too, with the ones already migrated as '>'.
+	if migration := hashTable.migration(); migration != nil {
+		migration.Dump(w)
+		hashTable.old.dumpSlots(w, hashTable.moved)
+	}
+}
+
+// Show each slot as a single character, with the first moved slots as '>'.
+func (hashTable *CoalescedMap[K, V]) dumpSlots(w io.Writer, moved int) {
+	row := 0
+	for i, entry := range hashTable.entries {
+		if i == hashTable.addressSize {
+			if row > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintln(w, "Cellar:")
+			row = 0
+		}
+		switch {
+		case i < moved:
+			fmt.Fprint(w, ">")
+		case entry == nil:
+			// This spot is empty.
+			fmt.Fprint(w, ".")
+		case entry.deleted:
+			// This spot is deleted.
+			fmt.Fprint(w, "x")
+		default:
+			// Display this entry.
+			fmt.Fprint(w, "O")
+		}
+		row++
+		if row == 50 {
+			fmt.Fprintln(w)
+			row = 0
+		}
+	}
+	if row > 0 {
+		fmt.Fprintln(w)
+	}
+}
+
+// Show this key's chain.
+func (hashTable *CoalescedMap[K, V]) Probe(w io.Writer, key K) int {
+	fmt.Fprintf(w, "Probing %s (%d)\n", format(key), hashTable.home(key))
+
+	// Show each slot that Find visits.
+	index, last, _ := hashTable.searchSlots(key, func(kind EventKind, index int, step int) {
+		if kind != EventVisit {
+			return
+		}
+		fmt.Fprintf(w, "    %d: ", index)
+		if hashTable.entries[index] == nil {
+			fmt.Fprintf(w, "---\n")
+		} else if hashTable.entries[index].deleted {
+			fmt.Fprintf(w, "xxx\n")
+		} else {
+			fmt.Fprintf(w, "%s\n", format(hashTable.entries[index].Key))
+		}
+	})
+
+	switch {
+	case index < 0:
+		// A new key would go in a free slot linked after the chain's end.
+		fmt.Fprintf(w, "    Not found, chain ends at %d\n", last)
+	case hashTable.entries[index] == nil:
+		fmt.Fprintf(w, "    Returning nil index %d\n", index)
+	case hashTable.entries[index].deleted:
+		fmt.Fprintf(w, "    Returning deleted index %d\n", index)
+	default:
+		fmt.Fprintf(w, "    Returning found index %d\n", index)
+	}
+	if hashTable.live(index) == nil && hashTable.old != nil {
+		if oldIndex, _, _ := hashTable.old.searchSlots(key, nil); hashTable.old.live(oldIndex) != nil {
+			fmt.Fprintf(w, "    Not migrated yet, old index %d\n", oldIndex)
+		}
+	}
+	return index
+}
+
+// Return the average probe sequence length for the items in the table.
+func (hashTable *CoalescedMap[K, V]) AveProbeSequenceLength() float32 {
+	totalLength := 0
+	numValues := 0
+	for _, entry := range hashTable.entries {
+		if entry != nil && !entry.deleted {
+			_, probeLength := hashTable.Find(entry.Key)
+			totalLength += probeLength
+			numValues++
+		}
+	}
+
+	// An empty table has no probe sequences to average.
+	if numValues == 0 {
+		return 0
+	}
+	return float32(totalLength) / float32(numValues)
+}
+
+// Collect the stats for the table. The chain lengths are the number of entries,
+// live or deleted, on the chain that starts at each address slot.
+func (hashTable *CoalescedMap[K, V]) Stats() Stats {
+	stats := Stats{Capacity: hashTable.capacity, ChainLengths: []int{}}
+
+	// Count the slots and measure each live key's chain position.
+	var successful []int
+	for _, entry := range hashTable.entries {
+		switch {
+		case entry == nil:
+			stats.Empty++
+		case entry.deleted:
+			stats.Deleted++
+		default:
+			stats.Live++
+			_, probeLength := hashTable.Find(entry.Key)
+			successful = append(successful, probeLength)
+		}
+	}
+	stats.LoadFactor = float64(stats.Live) / float64(hashTable.capacity)
+	stats.Successful = newProbeStats(successful)
+
+	// A miss follows its home slot's chain to the end, or stops at an empty home slot.
+	var unsuccessful []int
+	for home := 0; home < hashTable.addressSize; home++ {
+		length := 0
+		for index := home; index >= 0 && hashTable.entries[index] != nil; index = hashTable.next[index] {
+			length++
+		}
+		unsuccessful = append(unsuccessful, max(1, length))
+		stats.ChainLengths = increment(stats.ChainLengths, length)
+		if length > stats.MaxChain {
+			stats.MaxChain = length
+		}
+	}
+	stats.Unsuccessful = newProbeStats(unsuccessful)
+
+	stats.Cellar = &Cellar{Size: hashTable.CellarSize()}
+	for _, entry := range hashTable.entries[hashTable.addressSize:] {
+		if entry != nil {
+			stats.Cellar.Used++
+		}
+	}
+	stats.Migration = hashTable.migration()
+	return stats
+}
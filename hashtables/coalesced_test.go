@@ -0,0 +1,65 @@
+package hashtables_test
+
+import (
+	"testing"
+
+	"hashtables"
+)
+
+// Return the value Set panics with, or nil.
+func setPanic(m *hashtables.CoalescedMap[int, int], key int) (p any) {
+	defer func() { p = recover() }()
+	m.Set(key, key)
+	return nil
+}
+
+func TestCoalescedCellar(t *testing.T) {
+	// Keys hash to slots 0-9, and slots 10-19 are the cellar.
+	m := hashtables.NewCoalescedMap[int, int](20, hashtables.OrderedKeys(func(key int) int { return key }), 0.5)
+	for key := 0; key <= 100; key += 10 {
+		m.Set(key, key)
+	}
+	if cellar := m.Stats().Cellar; cellar == nil || *cellar != (hashtables.Cellar{Size: 10, Used: 10}) {
+		t.Fatalf("cellar %+v after 10 collisions", cellar)
+	}
+	path := m.ProbePath(100)
+	if len(path) != 11 || path[0] != 0 || path[10] != 10 {
+		t.Errorf("ProbePath(100) = %v, want slot 0 then the cellar from the top", path)
+	}
+
+	// Once the cellar is full, collisions take the highest empty slot in the address region,
+	// and keys that hash there later join the chain that took it.
+	m.Set(110, 110)
+	if path := m.ProbePath(110); path[len(path)-1] != 9 {
+		t.Errorf("ProbePath(110) = %v, want it to end in slot 9", path)
+	}
+	m.Set(9, 9)
+	if path := m.ProbePath(9); len(path) != 2 || path[0] != 9 || path[1] != 8 {
+		t.Errorf("ProbePath(9) = %v, want [9 8]", path)
+	}
+
+	for key := 1; m.Len() < 20; key++ {
+		m.Set(key, key)
+	}
+	if p := setPanic(m, 1000); p != "Hash table is full" {
+		t.Errorf("Set in a full table panicked with %v", p)
+	}
+	if m.Len() != 20 || m.Contains(1000) {
+		t.Errorf("Len = %d after a failed Set", m.Len())
+	}
+	m.Range(func(key int, value int) bool {
+		if found, ok := m.Lookup(key); !ok || found != key {
+			t.Errorf("Lookup(%d) = %d, %v", key, found, ok)
+		}
+		return true
+	})
+
+	// A deleted slot makes room again.
+	m.Delete(50)
+	if p := setPanic(m, 1000); p != nil {
+		t.Errorf("Set after a Delete panicked with %v", p)
+	}
+	if !m.Contains(1000) || m.Contains(50) || !m.Contains(60) || m.Len() != 20 {
+		t.Error("the table has the wrong keys after reusing a slot")
+	}
+}
@@ -0,0 +1,168 @@
+package hashtables
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// CountMinSketch estimates how often each key has been added in space that doesn't
+// grow with the number of keys, as in Cormode and Muthukrishnan's "An Improved Data
+// Stream Summary". Each row of counters has a different hash function, and a key's
+// estimate is its smallest counter. Collisions only add to a counter, so an estimate
+// is never low, and is too high by at most ε times the total count with probability
+// 1 - δ. Like BloomFilter, it finds row i's counter with hash1 + i*hash2.
+type CountMinSketch[K any] struct {
+	counters []uint64 // depth rows of width counters.
+	width    int
+	depth    int
+	total    uint64
+	keys     KeyHasher[K]
+	hash2    func(key K) int
+}
+
+// Build a count-min sketch whose estimates are within epsilon times the total count
+// with probability 1 - delta. The KeyHasher is hash1 and hash2 is the second hash
+// function. A nil hash2 remixes hash1, so it works with any KeyHasher.
+func NewCountMinSketch[K any](epsilon float64, delta float64, keys KeyHasher[K],
+	hash2 func(key K) int) (*CountMinSketch[K], error) {
+	if epsilon <= 0 || epsilon >= 1 {
+		return nil, fmt.Errorf("epsilon must be between 0 and 1, got %g", epsilon)
+	}
+	if delta <= 0 || delta >= 1 {
+		return nil, fmt.Errorf("delta must be between 0 and 1, got %g", delta)
+	}
+
+	// A row of e/ε counters is within ε N with probability 1 - 1/e, so ln 1/δ rows
+	// make all of them too high with probability δ.
+	width := int(math.Ceil(math.E / epsilon))
+	depth := max(1, int(math.Ceil(math.Log(1/delta))))
+	return newCountMinSketch(width, depth, keys, hash2), nil
+}
+
+func newCountMinSketch[K any](width int, depth int, keys KeyHasher[K], hash2 func(key K) int) *CountMinSketch[K] {
+	return &CountMinSketch[K]{
+		counters: make([]uint64, width*depth),
+		width:    width,
+		depth:    depth,
+		keys:     keys,
+		hash2:    secondHash(keys, hash2),
+	}
+}
+
+// Return the index of the key's counter in a row.
+func (sketch *CountMinSketch[K]) index(hash1 uint64, hash2 uint64, row int) int {
+	return row*sketch.width + int((hash1+uint64(row)*hash2)%uint64(sketch.width))
+}
+
+// Count the key count more times.
+func (sketch *CountMinSketch[K]) Add(key K, count uint64) {
+	hash1, hash2 := uint64(sketch.keys.Hash(key)), uint64(sketch.hash2(key))
+	for row := 0; row < sketch.depth; row++ {
+		sketch.counters[sketch.index(hash1, hash2, row)] += count
+	}
+	sketch.total += count
+}
+
+// Return an estimate of how many times the key was added. It is never too low.
+func (sketch *CountMinSketch[K]) Estimate(key K) uint64 {
+	hash1, hash2 := uint64(sketch.keys.Hash(key)), uint64(sketch.hash2(key))
+	estimate := uint64(math.MaxUint64)
+	for row := 0; row < sketch.depth; row++ {
+		estimate = min(estimate, sketch.counters[sketch.index(hash1, hash2, row)])
+	}
+	return estimate
+}
+
+// Return the sum of all counts added.
+func (sketch *CountMinSketch[K]) Total() uint64 {
+	return sketch.total
+}
+
+// Return the number of counters in each row.
+func (sketch *CountMinSketch[K]) Width() int {
+	return sketch.width
+}
+
+// Return the number of rows.
+func (sketch *CountMinSketch[K]) Depth() int {
+	return sketch.depth
+}
+
+// Return how far an estimate may be too high and the probability that it is within
+// that bound: ε N and 1 - δ for ε = e / width and δ = e^-depth.
+func (sketch *CountMinSketch[K]) ErrorBound() (float64, float64) {
+	epsilon := math.E / float64(sketch.width)
+	delta := math.Exp(-float64(sketch.depth))
+	return epsilon * float64(sketch.total), 1 - delta
+}
+
+// Add the counts in another sketch to this one, as if its keys had been added here.
+// Both sketches must have the same width, depth and KeyHasher.
+func (sketch *CountMinSketch[K]) Merge(other *CountMinSketch[K]) error {
+	if sketch.width != other.width || sketch.depth != other.depth {
+		return fmt.Errorf("can't merge a %dx%d count-min sketch with a %dx%d one",
+			sketch.depth, sketch.width, other.depth, other.width)
+	}
+	if !sameKeyHasher(sketch.keys, other.keys) {
+		return errors.New("can't merge count-min sketches with different KeyHashers")
+	}
+	for i, count := range other.counters {
+		sketch.counters[i] += count
+	}
+	sketch.total += other.total
+	return nil
+}
+
+// The serialized form starts with these bytes and a version.
+var countMinMagic = []byte("CMS\x01")
+
+// Encode the sketch: its width, depth, total and counters. Most counters are small,
+// so they are varints. The KeyHasher and hash2 are not included.
+func (sketch *CountMinSketch[K]) MarshalBinary() ([]byte, error) {
+	data := append([]byte(nil), countMinMagic...)
+	data = binary.AppendUvarint(data, uint64(sketch.width))
+	data = binary.AppendUvarint(data, uint64(sketch.depth))
+	data = binary.AppendUvarint(data, sketch.total)
+	for _, count := range sketch.counters {
+		data = binary.AppendUvarint(data, count)
+	}
+	return data, nil
+}
+
+// Decode a sketch written by MarshalBinary. Keys must be hashed with the same
+// KeyHasher and hash2 it was built with.
+func UnmarshalCountMinSketch[K any](data []byte, keys KeyHasher[K], hash2 func(key K) int) (*CountMinSketch[K], error) {
+	if len(data) < len(countMinMagic) || string(data[:len(countMinMagic)]) != string(countMinMagic) {
+		return nil, errors.New("not a serialized count-min sketch")
+	}
+	data = data[len(countMinMagic):]
+	var fields [3]uint64
+	for i := range fields {
+		value, read := binary.Uvarint(data)
+		if read <= 0 {
+			return nil, errors.New("bad count-min sketch header")
+		}
+		fields[i] = value
+		data = data[read:]
+	}
+	// Each counter takes at least a byte, which also bounds the allocation.
+	if fields[0] == 0 || fields[1] == 0 || fields[1] > uint64(len(data))/fields[0] {
+		return nil, errors.New("bad count-min sketch size")
+	}
+	sketch := newCountMinSketch(int(fields[0]), int(fields[1]), keys, hash2)
+	sketch.total = fields[2]
+	for i := range sketch.counters {
+		value, read := binary.Uvarint(data)
+		if read <= 0 {
+			return nil, errors.New("count-min sketch counters: truncated")
+		}
+		sketch.counters[i] = value
+		data = data[read:]
+	}
+	if len(data) != 0 {
+		return nil, errors.New("count-min sketch counters: trailing bytes")
+	}
+	return sketch, nil
+}
@@ -0,0 +1,106 @@
+package hashtables_test
+
+import (
+	"fmt"
+	"testing"
+
+	"hashtables"
+)
+
+// Add key i i times for i from 1 to n, and return the true counts.
+func addZipfish(sketch *hashtables.CountMinSketch[string], prefix string, n int) map[string]uint64 {
+	counts := make(map[string]uint64)
+	for i := 1; i <= n; i++ {
+		key := fmt.Sprint(prefix, i)
+		sketch.Add(key, uint64(i))
+		counts[key] = uint64(i)
+	}
+	return counts
+}
+
+// Check that every estimate is at least the true count, and that most are within
+// the sketch's error bound.
+func checkEstimates(t *testing.T, sketch *hashtables.CountMinSketch[string], counts map[string]uint64) {
+	t.Helper()
+	bound, probability := sketch.ErrorBound()
+	over := 0
+	for key, count := range counts {
+		estimate := sketch.Estimate(key)
+		if estimate < count {
+			t.Fatalf("Estimate(%q) = %d, below the true count %d", key, estimate, count)
+		}
+		if float64(estimate-count) > bound {
+			over++
+		}
+	}
+	if allowed := (1-probability)*float64(len(counts)) + 5; float64(over) > allowed {
+		t.Errorf("%d of %d estimates are more than %.0f too high, want at most %.0f",
+			over, len(counts), bound, allowed)
+	}
+}
+
+func TestCountMinSketch(t *testing.T) {
+	keys := hashtables.StringKeys(nil)
+	sketch, err := hashtables.NewCountMinSketch(0.001, 0.01, keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sketch.Width() != 2719 || sketch.Depth() != 5 {
+		t.Errorf("%d rows of %d counters", sketch.Depth(), sketch.Width())
+	}
+	counts := addZipfish(sketch, "a", 2000)
+	if sketch.Total() != 2000*2001/2 {
+		t.Errorf("Total = %d", sketch.Total())
+	}
+	checkEstimates(t, sketch, counts)
+	if sketch.Estimate("stranger") > uint64(sketch.Total()/1000) {
+		t.Errorf("Estimate(stranger) = %d", sketch.Estimate("stranger"))
+	}
+
+	// A merged sketch counts the keys of both.
+	other, _ := hashtables.NewCountMinSketch(0.001, 0.01, keys, nil)
+	for key, count := range addZipfish(other, "a", 100) {
+		counts[key] += count
+	}
+	for key, count := range addZipfish(other, "b", 500) {
+		counts[key] = count
+	}
+	if err := sketch.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	checkEstimates(t, sketch, counts)
+	smaller, _ := hashtables.NewCountMinSketch(0.01, 0.01, keys, nil)
+	if sketch.Merge(smaller) == nil {
+		t.Error("merged sketches of different sizes")
+	}
+	jenkins, _ := hashtables.NewCountMinSketch(0.001, 0.01, hashtables.StringKeys(hashtables.Jenkins), nil)
+	if sketch.Merge(jenkins) == nil {
+		t.Error("merged sketches with different KeyHashers")
+	}
+
+	// A loaded sketch gives the same estimates.
+	data, _ := sketch.MarshalBinary()
+	loaded, err := hashtables.UnmarshalCountMinSketch(data, keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Total() != sketch.Total() {
+		t.Errorf("loaded Total %d, want %d", loaded.Total(), sketch.Total())
+	}
+	for key := range counts {
+		if loaded.Estimate(key) != sketch.Estimate(key) {
+			t.Fatalf("the loaded sketch changed Estimate(%q)", key)
+		}
+	}
+	for _, bad := range [][]byte{nil, data[:6], data[:len(data)-1], append(data, 0)} {
+		if _, err := hashtables.UnmarshalCountMinSketch(bad, keys, nil); err == nil {
+			t.Errorf("unmarshaled %d bytes of %d", len(bad), len(data))
+		}
+	}
+
+	for _, bad := range [][2]float64{{0, 0.01}, {1, 0.01}, {0.01, 0}, {0.01, 1}} {
+		if _, err := hashtables.NewCountMinSketch(bad[0], bad[1], keys, nil); err == nil {
+			t.Errorf("built a sketch with epsilon %g and delta %g", bad[0], bad[1])
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package hashtables
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+const (
+	// cuckooBucketSize is the number of fingerprints in each bucket.
+	cuckooBucketSize = 4
+	// cuckooMaxKicks is how many fingerprints Add moves before giving up.
+	cuckooMaxKicks = 500
+	// cuckooMaxLoad is the fraction of fingerprint slots NewCuckooFilter expects to fill.
+	cuckooMaxLoad = 0.95
+)
+
+// CuckooFilter stores a short fingerprint of each key in one of two buckets, as in
+// Fan et al.'s "Cuckoo Filter: Practically Better Than Bloom". hash1 picks the first
+// bucket and hash2 the fingerprint, and the second bucket is the first one XORed with
+// a hash of the fingerprint, so a fingerprint can move between its buckets without its
+// key. Unlike a Bloom filter, it can delete keys, but Add fails once it is nearly full.
+type CuckooFilter[K any] struct {
+	fingerprints    packedInts // cuckooBucketSize slots per bucket. 0 is an empty slot.
+	numBuckets      int        // A power of two, so the XOR stays in range.
+	fingerprintBits uint
+	count           int
+	keys            KeyHasher[K]
+	hash2           func(key K) int
+	random          uint64 // Picks which fingerprint to kick out.
+
+	// A fingerprint that Add could not place. The filter is full while there is one.
+	victim       uint64
+	victimBucket int
+}
+
+// Build a cuckoo filter sized for this many keys at this false positive rate.
+// The KeyHasher is hash1 and hash2 is the second hash function.
+// A nil hash2 remixes hash1, so it works with any KeyHasher.
+func NewCuckooFilter[K any](expected int, falsePositiveRate float64, keys KeyHasher[K],
+	hash2 func(key K) int) (*CuckooFilter[K], error) {
+	if expected <= 0 {
+		return nil, fmt.Errorf("expected keys must be positive, got %d", expected)
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, fmt.Errorf("false positive rate must be between 0 and 1, got %g", falsePositiveRate)
+	}
+
+	// A lookup compares 2 buckets of fingerprints, so the rate is about 2b / 2^f.
+	fingerprintBits := max(2, int(math.Ceil(math.Log2(2*cuckooBucketSize/falsePositiveRate))))
+	if fingerprintBits > 32 {
+		return nil, fmt.Errorf("false positive rate %g needs %d-bit fingerprints, more than 32",
+			falsePositiveRate, fingerprintBits)
+	}
+	numBuckets := int(math.Ceil(float64(expected) / (cuckooBucketSize * cuckooMaxLoad)))
+	numBuckets = 1 << bits.Len(uint(max(1, numBuckets-1)))
+	return newCuckooFilter(numBuckets, uint(fingerprintBits), keys, hash2), nil
+}
+
+func newCuckooFilter[K any](numBuckets int, fingerprintBits uint, keys KeyHasher[K],
+	hash2 func(key K) int) *CuckooFilter[K] {
+	return &CuckooFilter[K]{
+		fingerprints:    newPackedInts(numBuckets*cuckooBucketSize, fingerprintBits),
+		numBuckets:      numBuckets,
+		fingerprintBits: fingerprintBits,
+		keys:            keys,
+		hash2:           secondHash(keys, hash2),
+		random:          0x2545f4914f6cdd1d,
+	}
+}
+
+// Return the key's first bucket and its fingerprint, which is never 0.
+func (filter *CuckooFilter[K]) locate(key K) (int, uint64) {
+	bucket := int(uint64(filter.keys.Hash(key)) & uint64(filter.numBuckets-1))
+	fingerprint := uint64(filter.hash2(key))%filter.fingerprints.mask() + 1
+	return bucket, fingerprint
+}
+
+// Return a fingerprint's other bucket. Applying it twice gives back the first bucket.
+func (filter *CuckooFilter[K]) alternate(bucket int, fingerprint uint64) int {
+	return bucket ^ int(uint64(remix(int(fingerprint)))&uint64(filter.numBuckets-1))
+}
+
+// Return the index of a bucket's slot holding the fingerprint, or -1.
+func (filter *CuckooFilter[K]) find(bucket int, fingerprint uint64) int {
+	for i := bucket * cuckooBucketSize; i < (bucket+1)*cuckooBucketSize; i++ {
+		if filter.fingerprints.get(i) == fingerprint {
+			return i
+		}
+	}
+	return -1
+}
+
+// Put the fingerprint in an empty slot of the bucket. Return false if it is full.
+func (filter *CuckooFilter[K]) insert(bucket int, fingerprint uint64) bool {
+	if i := filter.find(bucket, 0); i >= 0 {
+		filter.fingerprints.set(i, fingerprint)
+		return true
+	}
+	return false
+}
+
+// Add the key's fingerprint. Return false if the filter is too full to hold it.
+func (filter *CuckooFilter[K]) Add(key K) bool {
+	bucket, fingerprint := filter.locate(key)
+	return filter.add(bucket, fingerprint)
+}
+
+// Put a fingerprint in one of its buckets, kicking other fingerprints to their
+// other buckets to make room if need be.
+func (filter *CuckooFilter[K]) add(bucket int, fingerprint uint64) bool {
+	if filter.victim != 0 {
+		return false
+	}
+	filter.count++
+	alternate := filter.alternate(bucket, fingerprint)
+	if filter.insert(bucket, fingerprint) || filter.insert(alternate, fingerprint) {
+		return true
+	}
+
+	// Start from either bucket and swap the fingerprint for a random one there.
+	if filter.next()%2 == 0 {
+		bucket = alternate
+	}
+	for kick := 0; kick < cuckooMaxKicks; kick++ {
+		i := bucket*cuckooBucketSize + int(filter.next()%cuckooBucketSize)
+		kicked := filter.fingerprints.get(i)
+		filter.fingerprints.set(i, fingerprint)
+		fingerprint = kicked
+		bucket = filter.alternate(bucket, fingerprint)
+		if filter.insert(bucket, fingerprint) {
+			return true
+		}
+	}
+
+	// Keep the last fingerprint kicked out, so no key that was added is lost.
+	filter.victim, filter.victimBucket = fingerprint, bucket
+	return true
+}
+
+// Return the next number from a xorshift generator, so kicks are reproducible.
+func (filter *CuckooFilter[K]) next() uint64 {
+	filter.random ^= filter.random << 13
+	filter.random ^= filter.random >> 7
+	filter.random ^= filter.random << 17
+	return filter.random
+}
+
+// Return false if the key was definitely never added or has been deleted.
+func (filter *CuckooFilter[K]) MayContain(key K) bool {
+	bucket, fingerprint := filter.locate(key)
+	alternate := filter.alternate(bucket, fingerprint)
+	if filter.victim == fingerprint && (filter.victimBucket == bucket || filter.victimBucket == alternate) {
+		return true
+	}
+	return filter.find(bucket, fingerprint) >= 0 || filter.find(alternate, fingerprint) >= 0
+}
+
+// Remove one copy of the key's fingerprint. Only delete keys that were added,
+// or another key with the same fingerprint may be lost. Return false if the
+// fingerprint was not there.
+func (filter *CuckooFilter[K]) Delete(key K) bool {
+	bucket, fingerprint := filter.locate(key)
+	alternate := filter.alternate(bucket, fingerprint)
+	switch {
+	case filter.victim == fingerprint && (filter.victimBucket == bucket || filter.victimBucket == alternate):
+		filter.victim = 0
+	default:
+		i := filter.find(bucket, fingerprint)
+		if i < 0 {
+			i = filter.find(alternate, fingerprint)
+		}
+		if i < 0 {
+			return false
+		}
+		filter.fingerprints.set(i, 0)
+
+		// Now there is room for the fingerprint that couldn't be placed.
+		if filter.victim != 0 {
+			victim, victimBucket := filter.victim, filter.victimBucket
+			filter.victim = 0
+			filter.count--
+			filter.add(victimBucket, victim)
+		}
+	}
+	filter.count--
+	return true
+}
+
+// Return the number of fingerprints stored.
+func (filter *CuckooFilter[K]) Len() int {
+	return filter.count
+}
+
+// Return the fraction of fingerprint slots in use.
+func (filter *CuckooFilter[K]) LoadFactor() float64 {
+	return float64(filter.count) / float64(filter.numBuckets*cuckooBucketSize)
+}
+
+// Return the false positive rate expected at the current load: a lookup compares
+// the fingerprints in two buckets, each matching with chance 1 / (2^f - 1).
+func (filter *CuckooFilter[K]) FalsePositiveRate() float64 {
+	compared := 2 * cuckooBucketSize * filter.LoadFactor()
+	return 1 - math.Pow(1-1/float64(filter.fingerprints.mask()), compared)
+}
+
+// Add every fingerprint in another filter to this one. Both filters must have the same
+// number of buckets, fingerprint size and KeyHasher. Return an error if this filter
+// fills up, in which case some of the other filter's keys are missing.
+func (filter *CuckooFilter[K]) Union(other *CuckooFilter[K]) error {
+	if filter.numBuckets != other.numBuckets || filter.fingerprintBits != other.fingerprintBits {
+		return fmt.Errorf("can't union a %d-bucket, %d-bit cuckoo filter with a %d-bucket, %d-bit one",
+			filter.numBuckets, filter.fingerprintBits, other.numBuckets, other.fingerprintBits)
+	}
+	if !sameKeyHasher(filter.keys, other.keys) {
+		return errors.New("can't union cuckoo filters with different KeyHashers")
+	}
+	full := errors.New("cuckoo filter is full")
+	for i := 0; i < other.numBuckets*cuckooBucketSize; i++ {
+		if fingerprint := other.fingerprints.get(i); fingerprint != 0 {
+			if !filter.add(i/cuckooBucketSize, fingerprint) || filter.victim != 0 {
+				return full
+			}
+		}
+	}
+	if other.victim != 0 && (!filter.add(other.victimBucket, other.victim) || filter.victim != 0) {
+		return full
+	}
+	return nil
+}
+
+// The serialized form starts with these bytes and a version.
+var cuckooMagic = []byte("CKO\x01")
+
+// Encode the filter: its number of buckets, fingerprint size, key count,
+// unplaced fingerprint and packed fingerprints. The KeyHasher and hash2 are not included.
+func (filter *CuckooFilter[K]) MarshalBinary() ([]byte, error) {
+	data := append([]byte(nil), cuckooMagic...)
+	data = binary.AppendUvarint(data, uint64(filter.numBuckets))
+	data = append(data, byte(filter.fingerprintBits))
+	data = binary.AppendUvarint(data, uint64(filter.count))
+	data = binary.AppendUvarint(data, filter.victim)
+	data = binary.AppendUvarint(data, uint64(filter.victimBucket))
+	return filter.fingerprints.appendBytes(data, filter.numBuckets*cuckooBucketSize), nil
+}
+
+// Decode a filter written by MarshalBinary. Keys must be hashed with the same
+// KeyHasher and hash2 it was built with.
+func UnmarshalCuckooFilter[K any](data []byte, keys KeyHasher[K], hash2 func(key K) int) (*CuckooFilter[K], error) {
+	if len(data) < len(cuckooMagic) || string(data[:len(cuckooMagic)]) != string(cuckooMagic) {
+		return nil, errors.New("not a serialized cuckoo filter")
+	}
+	data = data[len(cuckooMagic):]
+	numBuckets, read := binary.Uvarint(data)
+	if read <= 0 || numBuckets == 0 || numBuckets&(numBuckets-1) != 0 || read >= len(data) {
+		return nil, errors.New("bad cuckoo filter bucket count")
+	}
+	fingerprintBits := uint(data[read])
+	if fingerprintBits < 2 || fingerprintBits > 32 {
+		return nil, errors.New("bad cuckoo filter fingerprint size")
+	}
+	data = data[read+1:]
+	var fields [3]uint64
+	for i := range fields {
+		value, read := binary.Uvarint(data)
+		if read <= 0 {
+			return nil, errors.New("bad cuckoo filter header")
+		}
+		fields[i] = value
+		data = data[read:]
+	}
+	if !packedByteLenIs(numBuckets, fingerprintBits*cuckooBucketSize, len(data)) {
+		return nil, fmt.Errorf("cuckoo filter fingerprints: got %d bytes for %d buckets of %d-bit fingerprints",
+			len(data), numBuckets, fingerprintBits)
+	}
+	filter := newCuckooFilter(int(numBuckets), fingerprintBits, keys, hash2)
+	filter.count = int(fields[0])
+	if fields[1] > filter.fingerprints.mask() {
+		return nil, errors.New("bad cuckoo filter victim")
+	}
+	if fields[2] >= numBuckets {
+		return nil, errors.New("bad cuckoo filter victim bucket")
+	}
+	filter.victim, filter.victimBucket = fields[1], int(fields[2])
+	if err := filter.fingerprints.readBytes(data, filter.numBuckets*cuckooBucketSize); err != nil {
+		return nil, fmt.Errorf("cuckoo filter fingerprints: %w", err)
+	}
+	return filter, nil
+}
@@ -0,0 +1,178 @@
+package hashtables
+
+import "reflect"
+
+// MapReader is what Diff needs from a map: lookups and a way to visit every entry.
+// Every table strategy and PersistentMap provide it.
+type MapReader[K any, V any] interface {
+	Lookup(key K) (V, bool)
+	Range(fn func(key K, value V) bool)
+}
+
+// MapDiff lists how one version of a map differs from another.
+type MapDiff[K any, V any] struct {
+	Added   []Entry[K, V]
+	Removed []Entry[K, V]
+	Changed []Change[K, V]
+}
+
+// Change is a key whose value differs between two versions of a map.
+type Change[K any, V any] struct {
+	Key K
+	Old V
+	New V
+}
+
+// Return true if the diff has no changes.
+func (diff MapDiff[K, V]) Empty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+}
+
+// Add a key that is missing from one map or whose values differ to the diff.
+func (diff *MapDiff[K, V]) record(old, new Entry[K, V], inOld, inNew bool) bool {
+	switch {
+	case !inNew:
+		diff.Removed = append(diff.Removed, Entry[K, V]{Key: old.Key, Value: old.Value})
+	case !inOld:
+		diff.Added = append(diff.Added, Entry[K, V]{Key: new.Key, Value: new.Value})
+	default:
+		diff.Changed = append(diff.Changed, Change[K, V]{Key: new.Key, Old: old.Value, New: new.Value})
+	}
+	return true
+}
+
+// Diff returns the keys added, removed and changed going from a to b, such as two loads
+// of the HR export. The maps may use different strategies, capacities and KeyHashers.
+// Each side's keys are looked up with the other side's KeyHasher. A nil valueEqual
+// compares values with reflect.DeepEqual. Two PersistentMaps that share a KeyHasher
+// only compare the parts of their tries that differ.
+func Diff[K any, V any](a, b MapReader[K, V], valueEqual func(a, b V) bool) MapDiff[K, V] {
+	var diff MapDiff[K, V]
+	if persistentA, ok := a.(*PersistentMap[K, V]); ok {
+		if persistentB, ok := b.(*PersistentMap[K, V]); ok {
+			return persistentA.Diff(persistentB, valueEqual)
+		}
+	}
+	diffByKey(a, b, defaultValueEqual(valueEqual), diff.record)
+	return diff
+}
+
+// Call report for each key of a that b lacks or holds with a different value,
+// then for each key of b that a lacks, until report returns false.
+func diffByKey[K any, V any](a, b MapReader[K, V], valueEqual func(a, b V) bool,
+	report func(old, new Entry[K, V], inOld, inNew bool) bool) {
+	stopped := false
+	a.Range(func(key K, value V) bool {
+		otherValue, ok := b.Lookup(key)
+		switch {
+		case !ok:
+			stopped = !report(Entry[K, V]{Key: key, Value: value}, Entry[K, V]{}, true, false)
+		case !valueEqual(value, otherValue):
+			stopped = !report(Entry[K, V]{Key: key, Value: value}, Entry[K, V]{Key: key, Value: otherValue}, true, true)
+		}
+		return !stopped
+	})
+	if stopped {
+		return
+	}
+	b.Range(func(key K, value V) bool {
+		if _, ok := a.Lookup(key); ok {
+			return true
+		}
+		return report(Entry[K, V]{}, Entry[K, V]{Key: key, Value: value}, false, true)
+	})
+}
+
+// Return valueEqual, or reflect.DeepEqual if it is nil.
+func defaultValueEqual[V any](valueEqual func(a, b V) bool) func(a, b V) bool {
+	if valueEqual != nil {
+		return valueEqual
+	}
+	return func(a, b V) bool { return reflect.DeepEqual(a, b) }
+}
+
+// Conflict is a change that Merge can't apply cleanly, because the target map no longer
+// holds what the diff says the key held before.
+type Conflict[K any, V any] struct {
+	Key K
+
+	Base    V // The value before the change, if HasBase.
+	HasBase bool
+
+	Current    V // The target map's value, if HasCurrent.
+	HasCurrent bool
+
+	Incoming    V // The value after the change, if HasIncoming. A removal has none.
+	HasIncoming bool
+}
+
+// Resolver decides a conflict. It returns the value the key should have, or false
+// if the key should be absent.
+type Resolver[K any, V any] func(conflict Conflict[K, V]) (value V, present bool)
+
+// PreferIncoming resolves every conflict in favor of the diff.
+func PreferIncoming[K any, V any](conflict Conflict[K, V]) (V, bool) {
+	return conflict.Incoming, conflict.HasIncoming
+}
+
+// PreferCurrent resolves every conflict by keeping what the target map holds.
+func PreferCurrent[K any, V any](conflict Conflict[K, V]) (V, bool) {
+	return conflict.Current, conflict.HasCurrent
+}
+
+// MergeResult counts what Merge did.
+type MergeResult struct {
+	Set       int // Keys added or changed.
+	Deleted   int
+	Unchanged int // Changes the target map already had.
+	Conflicts int
+}
+
+// Merge applies a diff to a map, touching only the keys the diff names, so a sync
+// doesn't have to rebuild the map. A change applies cleanly when the map still holds
+// the diff's old value for the key (or lacks the key, for an addition). A change the
+// map already has is skipped. Anything else is a conflict, which resolve settles.
+// A nil resolve is PreferIncoming and a nil valueEqual is reflect.DeepEqual.
+func Merge[K any, V any](dst Map[K, V], diff MapDiff[K, V], resolve Resolver[K, V],
+	valueEqual func(a, b V) bool) MergeResult {
+	if resolve == nil {
+		resolve = PreferIncoming[K, V]
+	}
+	valueEqual = defaultValueEqual(valueEqual)
+
+	var result MergeResult
+	apply := func(conflict Conflict[K, V]) {
+		current, hasCurrent := dst.Lookup(conflict.Key)
+		conflict.Current, conflict.HasCurrent = current, hasCurrent
+		target, present := conflict.Incoming, conflict.HasIncoming
+		switch {
+		case hasCurrent == present && (!present || valueEqual(current, target)):
+			result.Unchanged++
+			return
+		case hasCurrent != conflict.HasBase || (hasCurrent && !valueEqual(current, conflict.Base)):
+			result.Conflicts++
+			target, present = resolve(conflict)
+			if hasCurrent == present && (!present || valueEqual(current, target)) {
+				return
+			}
+		}
+		if present {
+			dst.Set(conflict.Key, target)
+			result.Set++
+		} else {
+			dst.Delete(conflict.Key)
+			result.Deleted++
+		}
+	}
+
+	for _, entry := range diff.Added {
+		apply(Conflict[K, V]{Key: entry.Key, Incoming: entry.Value, HasIncoming: true})
+	}
+	for _, change := range diff.Changed {
+		apply(Conflict[K, V]{Key: change.Key, Base: change.Old, HasBase: true, Incoming: change.New, HasIncoming: true})
+	}
+	for _, entry := range diff.Removed {
+		apply(Conflict[K, V]{Key: entry.Key, Base: entry.Value, HasBase: true})
+	}
+	return result
+}
@@ -0,0 +1,164 @@
+package hashtables_test
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"hashtables"
+)
+
+// Build a map from a strategy and entries.
+func newDiffMap(t *testing.T, strategy string, keys hashtables.KeyHasher[string],
+	entries map[string]string) hashtables.Map[string, string] {
+	t.Helper()
+	m, err := hashtables.NewMap[string, string](strategy, 16, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range entries {
+		m.Set(key, value)
+	}
+	return m
+}
+
+// Return the keys of a diff's entries, sorted.
+func entryKeys(entries []hashtables.Entry[string, string]) []string {
+	var keys []string
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestDiff(t *testing.T) {
+	before := map[string]string{"Ann": "555-1", "Bob": "555-2", "Cid": "555-3"}
+	after := map[string]string{"Ann": "555-1", "Bob": "555-9", "Dee": "555-4", "Eve": "555-5"}
+	strategies := hashtables.StrategyNames()
+	for i, strategy := range strategies {
+		// The two sides may use different strategies and hashers.
+		other := strategies[(i+1)%len(strategies)]
+		t.Run(strategy+"/"+other, func(t *testing.T) {
+			a := newDiffMap(t, strategy, hashtables.StringKeys(nil), before)
+			b := newDiffMap(t, other, hashtables.StringKeys(hashtables.Jenkins), after)
+			diff := hashtables.Diff[string, string](a, b, nil)
+			if got := entryKeys(diff.Added); !reflect.DeepEqual(got, []string{"Dee", "Eve"}) {
+				t.Errorf("Added %v", got)
+			}
+			if len(diff.Removed) != 1 || diff.Removed[0] != (hashtables.Entry[string, string]{Key: "Cid", Value: "555-3"}) {
+				t.Errorf("Removed %v", diff.Removed)
+			}
+			want := hashtables.Change[string, string]{Key: "Bob", Old: "555-2", New: "555-9"}
+			if len(diff.Changed) != 1 || diff.Changed[0] != want {
+				t.Errorf("Changed %v", diff.Changed)
+			}
+			if !hashtables.Diff[string, string](b, b, nil).Empty() || diff.Empty() {
+				t.Error("Empty is wrong")
+			}
+		})
+	}
+
+	// valueEqual decides which values count as changed.
+	a := newDiffMap(t, "chaining", hashtables.StringKeys(nil), before)
+	b := newDiffMap(t, "linear", hashtables.StringKeys(nil), map[string]string{"Ann": "555-1", "Bob": "555-2 ", "Cid": "555-3"})
+	trimmed := func(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) }
+	if diff := hashtables.Diff[string, string](a, b, trimmed); !diff.Empty() {
+		t.Errorf("diff %+v ignoring spaces", diff)
+	}
+}
+
+func TestPersistentDiff(t *testing.T) {
+	keys := hashtables.StringKeys(nil)
+	a := hashtables.NewPersistentMap[string, string](keys).Set("Ann", "555-1").Set("Bob", "555-2")
+	b := a.Set("Bob", "555-9").Delete("Ann").Set("Cid", "555-3")
+
+	// Diff gives the same answer for PersistentMaps as for tables.
+	diff := hashtables.Diff[string, string](a, b, nil)
+	table := newDiffMap(t, "double", keys, map[string]string{"Bob": "555-9", "Cid": "555-3"})
+	if tableDiff := hashtables.Diff[string, string](a, table, nil); !reflect.DeepEqual(diff, tableDiff) {
+		t.Errorf("diff %+v against a PersistentMap and %+v against a table", diff, tableDiff)
+	}
+	if entryKeys(diff.Added)[0] != "Cid" || entryKeys(diff.Removed)[0] != "Ann" || diff.Changed[0].Key != "Bob" {
+		t.Errorf("diff %+v", diff)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := map[string]string{"Ann": "555-1", "Bob": "555-2", "Cid": "555-3"}
+	diff := hashtables.Diff[string, string](
+		newDiffMap(t, "chaining", hashtables.StringKeys(nil), base),
+		newDiffMap(t, "chaining", hashtables.StringKeys(nil), map[string]string{"Ann": "555-1", "Bob": "555-9", "Dee": "555-4"}),
+		nil)
+
+	// Each case changes the target before the merge, then checks the result.
+	for _, test := range []struct {
+		name    string
+		edit    map[string]string // An empty value deletes the key.
+		resolve hashtables.Resolver[string, string]
+		want    map[string]string
+		result  hashtables.MergeResult
+	}{
+		{
+			name:   "clean",
+			want:   map[string]string{"Ann": "555-1", "Bob": "555-9", "Dee": "555-4"},
+			result: hashtables.MergeResult{Set: 2, Deleted: 1},
+		},
+		{
+			name:   "unchanged",
+			edit:   map[string]string{"Bob": "555-9", "Cid": ""},
+			want:   map[string]string{"Ann": "555-1", "Bob": "555-9", "Dee": "555-4"},
+			result: hashtables.MergeResult{Set: 1, Unchanged: 2},
+		},
+		{
+			name:   "prefer incoming",
+			edit:   map[string]string{"Bob": "555-7", "Cid": "555-8", "Dee": "555-6"},
+			want:   map[string]string{"Ann": "555-1", "Bob": "555-9", "Dee": "555-4"},
+			result: hashtables.MergeResult{Set: 2, Deleted: 1, Conflicts: 3},
+		},
+		{
+			name:    "prefer current",
+			edit:    map[string]string{"Bob": "555-7", "Cid": "555-8", "Dee": "555-6"},
+			resolve: hashtables.PreferCurrent[string, string],
+			want:    map[string]string{"Ann": "555-1", "Bob": "555-7", "Cid": "555-8", "Dee": "555-6"},
+			result:  hashtables.MergeResult{Conflicts: 3},
+		},
+		{
+			name: "custom",
+			edit: map[string]string{"Bob": "555-7", "Cid": "555-8"},
+			resolve: func(conflict hashtables.Conflict[string, string]) (string, bool) {
+				// Keep both phones for a change, and keep a key the diff removes.
+				if conflict.HasIncoming {
+					return conflict.Current + "," + conflict.Incoming, true
+				}
+				return conflict.Current, conflict.HasCurrent
+			},
+			want:   map[string]string{"Ann": "555-1", "Bob": "555-7,555-9", "Cid": "555-8", "Dee": "555-4"},
+			result: hashtables.MergeResult{Set: 2, Conflicts: 2},
+		},
+	} {
+		for _, strategy := range hashtables.StrategyNames() {
+			dst := newDiffMap(t, strategy, hashtables.StringKeys(nil), base)
+			for key, value := range test.edit {
+				if value == "" {
+					dst.Delete(key)
+				} else {
+					dst.Set(key, value)
+				}
+			}
+			result := hashtables.Merge(dst, diff, test.resolve, nil)
+			if result != test.result {
+				t.Errorf("%s/%s: result %+v, want %+v", test.name, strategy, result, test.result)
+			}
+			got := make(map[string]string)
+			dst.Range(func(key string, value string) bool {
+				got[key] = value
+				return true
+			})
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%s/%s: merged %v, want %v", test.name, strategy, got, test.want)
+			}
+		}
+	}
+}
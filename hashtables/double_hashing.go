@@ -0,0 +1,47 @@
+package hashtables
+
+// DoubleHashMap probes slots at multiples of a step size taken from a second hash function.
+type DoubleHashMap[K any, V any] struct {
+	openAddressing[K, V]
+}
+
+// DoubleHashTable is the liveProject's double hashing table of names and phone numbers.
+type DoubleHashTable = DoubleHashMap[string, string]
+
+// NewDoubleHashMap Initialize a DoubleHashMap and return a pointer to it.
+// keys picks the home slot and stepHash picks the step size. A nil stepHash
+// remixes the key's hash, so it works with any KeyHasher.
+func NewDoubleHashMap[K any, V any](capacity int, keys KeyHasher[K], stepHash func(key K) int) *DoubleHashMap[K, V] {
+	if stepHash == nil {
+		stepHash = func(key K) int { return remix(keys.Hash(key)) }
+	}
+	return &DoubleHashMap[K, V]{
+		openAddressing: newOpenAddressing[K, V](capacity, keys, stepHash, doubleIndex),
+	}
+}
+
+// NewDoubleHashTable Initialize a DoubleHashTable and return a pointer to it.
+// hash1 picks the home slot and hash2 picks the step size.
+func NewDoubleHashTable(capacity int, hash1 HashFunc, hash2 HashFunc) *DoubleHashTable {
+	if hash2 == nil {
+		hash2 = Jenkins
+	}
+	return NewDoubleHashMap[string, string](capacity, StringKeys(hash1), hash2)
+}
+
+// doubleIndex returns the index of the double hashing probe sequence.
+func doubleIndex(hash int, step int, i int, capacity int) int {
+	return (hash + i*step) % capacity
+}
+
+// remix scrambles a hash code with the splitmix64 finalizer so the result
+// looks independent of the original. The result is non-negative.
+func remix(hash int) int {
+	x := uint64(hash)
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return int(x >> 1)
+}
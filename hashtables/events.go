@@ -0,0 +1,139 @@
+package hashtables
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// EventKind names a step that a table takes while it works.
+type EventKind string
+
+const (
+	// Operations. Each one starts a new group of events.
+	EventNew     EventKind = "new"     // A table was created. Written by tools, not tables.
+	EventSet     EventKind = "set"     // Set started.
+	EventLookup  EventKind = "lookup"  // Get or Contains started.
+	EventDelete  EventKind = "delete"  // Delete started.
+	EventResize  EventKind = "resize"  // Resize began.
+	EventGrowth  EventKind = "growth"  // Linear hashing growth was turned on, changed or off.
+	EventBuckets EventKind = "buckets" // A chaining table's buckets changed to the kind in Value.
+
+	// Steps within an operation.
+	EventVisit     EventKind = "visit"     // A slot or bucket entry was examined.
+	EventTombstone EventKind = "tombstone" // The first deleted slot was remembered for reuse.
+	EventClaim     EventKind = "claim"     // A new entry was stored in a slot or bucket.
+	EventUpdate    EventKind = "update"    // An existing entry's value was changed.
+	EventRemove    EventKind = "remove"    // An entry was deleted.
+	EventMove      EventKind = "move"      // Resize moved an entry to its new slot or bucket.
+	EventFull      EventKind = "full"      // Set found no room for a new entry.
+	EventExpire    EventKind = "expire"    // An entry's time to live ran out and it was removed.
+	EventSplit     EventKind = "split"     // Linear hashing split a bucket and added one at the end.
+	EventCompact   EventKind = "compact"   // A coalesced table rebuilt its slots in place to reuse deleted ones.
+)
+
+// Event is one step in a table's work. Slot is -1 when the event has no slot.
+type Event struct {
+	Seq      int           `json:"seq"`
+	Kind     EventKind     `json:"kind"`
+	Key      string        `json:"key,omitempty"`
+	Value    string        `json:"value,omitempty"`
+	Slot     int           `json:"slot"`
+	Step     int           `json:"step,omitempty"`     // The position in the probe sequence or chain.
+	From     int           `json:"from,omitempty"`     // The old slot of a moved entry.
+	Capacity int           `json:"capacity,omitempty"` // The new capacity for resize and new.
+	Strategy string        `json:"strategy,omitempty"` // The strategy for new.
+	Hash     string        `json:"hash,omitempty"`     // The hash function for new.
+	Keys     string        `json:"keys,omitempty"`     // The key mode for new, if not exact.
+	Migrate  int           `json:"migrate,omitempty"`  // Slots or buckets per operation for an incremental resize.
+	MaxLoad  float64       `json:"max_load,omitempty"` // The load factor that splits a bucket, for growth.
+	TTL      time.Duration `json:"ttl,omitempty"`      // The time to live for set in nanoseconds, if the entry expires.
+}
+
+// IsOperation returns true for the events that start an operation.
+func (event Event) IsOperation() bool {
+	switch event.Kind {
+	case EventNew, EventSet, EventLookup, EventDelete, EventResize, EventGrowth, EventBuckets:
+		return true
+	}
+	return false
+}
+
+// Recorder receives a table's events as they happen.
+type Recorder func(event Event)
+
+// NewJSONRecorder returns a Recorder that numbers each event
+// and writes it to w as one line of JSON, and a function that returns
+// the first error writing an event. The recorder stops writing after an error.
+func NewJSONRecorder(w io.Writer) (Recorder, func() error) {
+	encoder := json.NewEncoder(w)
+	seq := 0
+	var err error
+	recorder := func(event Event) {
+		if err != nil {
+			return
+		}
+		seq++
+		event.Seq = seq
+		err = encoder.Encode(event)
+	}
+	return recorder, func() error { return err }
+}
+
+// Read events written by a JSON recorder.
+func ReadEvents(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("event line %d: %w", line, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// Replay applies the first numOps operations in the events to the table.
+// A negative numOps replays every operation. Steps within operations are ignored,
+// since the table takes them again itself. Return the last operation replayed.
+func Replay(table Table, events []Event, numOps int) Event {
+	var last Event
+	for _, event := range events {
+		if !event.IsOperation() || event.Kind == EventNew {
+			continue
+		}
+		if numOps == 0 {
+			break
+		}
+		numOps--
+
+		switch event.Kind {
+		case EventSet:
+			table.SetWithTTL(event.Key, event.Value, event.TTL)
+		case EventLookup:
+			table.Contains(event.Key)
+		case EventDelete:
+			table.Delete(event.Key)
+		case EventResize:
+			table.SetIncrementalResize(event.Migrate)
+			table.Resize(event.Capacity)
+		case EventGrowth:
+			if growing, ok := table.(interface{ SetLinearHashing(maxLoad float64) }); ok {
+				growing.SetLinearHashing(event.MaxLoad)
+			}
+		case EventBuckets:
+			if chained, ok := table.(interface{ SetBuckets(kind BucketKind) }); ok {
+				chained.SetBuckets(BucketKind(event.Value))
+			}
+		}
+		last = event
+	}
+	return last
+}
@@ -0,0 +1,71 @@
+package hashtables_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"hashtables"
+)
+
+func TestReplayTTL(t *testing.T) {
+	for _, strategy := range hashtables.StrategyNames() {
+		t.Run(strategy, func(t *testing.T) {
+			var buffer bytes.Buffer
+			recorder, recordErr := hashtables.NewJSONRecorder(&buffer)
+			tables, _ := newClockedTables(t, 8)
+			table := tables[strategy]
+			table.SetRecorder(recorder)
+			table.SetWithTTL("Ann", "1", time.Second)
+			table.Set("Bob", "2")
+			if err := recordErr(); err != nil {
+				t.Fatal(err)
+			}
+
+			events, err := hashtables.ReadEvents(&buffer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tables, clock := newClockedTables(t, 8)
+			replayed := tables[strategy]
+			hashtables.Replay(replayed, events, -1)
+			if !replayed.Contains("Ann") || !replayed.Contains("Bob") {
+				t.Fatal("the replayed table is missing keys")
+			}
+			clock.Advance(time.Second)
+			if replayed.Contains("Ann") || !replayed.Contains("Bob") {
+				t.Error("the replayed table lost the time to live")
+			}
+		})
+	}
+}
+
+// failingWriter fails every write after the first n.
+type failingWriter struct{ n int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errors.New("disk full")
+	}
+	w.n--
+	return len(p), nil
+}
+
+func TestJSONRecorderError(t *testing.T) {
+	writer := &failingWriter{n: 1}
+	recorder, recordErr := hashtables.NewJSONRecorder(writer)
+	recorder(hashtables.Event{Kind: hashtables.EventSet, Key: "Ann"})
+	if err := recordErr(); err != nil {
+		t.Fatalf("error after a good write: %v", err)
+	}
+	recorder(hashtables.Event{Kind: hashtables.EventSet, Key: "Bob"})
+	writer.n = 10
+	recorder(hashtables.Event{Kind: hashtables.EventSet, Key: "Cid"})
+	if err := recordErr(); err == nil || err.Error() != "disk full" {
+		t.Errorf("recordErr() = %v, want the first write error", err)
+	}
+	if writer.n != 10 {
+		t.Error("the recorder kept writing after an error")
+	}
+}
@@ -0,0 +1,128 @@
+package hashtables
+
+import "time"
+
+// Filter is a compact, approximate set of keys. MayContain never returns false for a
+// key that was added, but may return true for one that wasn't, so a false answer lets
+// a lookup skip the table.
+type Filter[K any] interface {
+	// Add adds a key. It returns false if the filter is too full to hold it.
+	Add(key K) bool
+	MayContain(key K) bool
+}
+
+// Return the second hash function for a filter or sketch: hash2, or a remix of the
+// KeyHasher's hash if hash2 is nil, just as double hashing picks its step size.
+func secondHash[K any](keys KeyHasher[K], hash2 func(key K) int) func(key K) int {
+	if hash2 != nil {
+		return hash2
+	}
+	return func(key K) int { return remix(keys.Hash(key)) }
+}
+
+// FilterStats counts how a FilteredMap's filter did.
+type FilterStats struct {
+	Skipped        int // Lookups the filter answered without probing the table.
+	Probed         int // Lookups the filter passed to the table.
+	FalsePositives int // Probed lookups that missed anyway.
+}
+
+// FilteredMap puts a filter in front of a table, so that Get, Lookup and Contains for
+// most missing keys skip probing the table, which helps most when probing is expensive,
+// as with a large or disk-backed table. Deleting a key removes it from the filter
+// only if the filter supports deletes, like CuckooFilter. If the filter fills up,
+// the map stops using it.
+type FilteredMap[K any, V any] struct {
+	Map[K, V]
+	filter   Filter[K]
+	overflow bool // The filter refused a key, so it can't rule any key out.
+	stats    FilterStats
+}
+
+// Put a filter in front of a map, adding the keys the map already holds to it.
+func NewFilteredMap[K any, V any](m Map[K, V], filter Filter[K]) *FilteredMap[K, V] {
+	filtered := &FilteredMap[K, V]{Map: m, filter: filter}
+	m.Range(func(key K, value V) bool {
+		filtered.addKey(key)
+		return true
+	})
+	return filtered
+}
+
+// Add a key to the filter, or stop using the filter if it is full.
+func (filtered *FilteredMap[K, V]) addKey(key K) {
+	if !filtered.overflow && !filtered.filter.Add(key) {
+		filtered.overflow = true
+	}
+}
+
+// Return true if the key may be present, so the table must be probed.
+func (filtered *FilteredMap[K, V]) mayContain(key K) bool {
+	if !filtered.overflow && !filtered.filter.MayContain(key) {
+		filtered.stats.Skipped++
+		return false
+	}
+	filtered.stats.Probed++
+	return true
+}
+
+// Return the filter's counts of skipped and probed lookups.
+func (filtered *FilteredMap[K, V]) FilterStats() FilterStats {
+	return filtered.stats
+}
+
+// Add an item to the map and its key to the filter.
+func (filtered *FilteredMap[K, V]) Set(key K, value V) {
+	if !filtered.Map.Contains(key) {
+		filtered.addKey(key)
+	}
+	filtered.Map.Set(key, value)
+}
+
+// Add an item that expires once ttl has passed. The filter keeps the key after it expires.
+func (filtered *FilteredMap[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	if !filtered.Map.Contains(key) {
+		filtered.addKey(key)
+	}
+	filtered.Map.SetWithTTL(key, value, ttl)
+}
+
+// Return an item from the map, or the zero value if it is not present.
+func (filtered *FilteredMap[K, V]) Get(key K) V {
+	value, _ := filtered.Lookup(key)
+	return value
+}
+
+// Return an item from the map and whether it was present.
+func (filtered *FilteredMap[K, V]) Lookup(key K) (V, bool) {
+	if !filtered.mayContain(key) {
+		var zero V
+		return zero, false
+	}
+	value, ok := filtered.Map.Lookup(key)
+	if !ok {
+		filtered.stats.FalsePositives++
+	}
+	return value, ok
+}
+
+// Return true if the key is in the map.
+func (filtered *FilteredMap[K, V]) Contains(key K) bool {
+	if !filtered.mayContain(key) {
+		return false
+	}
+	ok := filtered.Map.Contains(key)
+	if !ok {
+		filtered.stats.FalsePositives++
+	}
+	return ok
+}
+
+// Delete this key's entry, and its key from the filter if the filter supports deletes.
+func (filtered *FilteredMap[K, V]) Delete(key K) {
+	deleter, ok := filtered.filter.(interface{ Delete(key K) bool })
+	if ok && !filtered.overflow && filtered.Map.Contains(key) {
+		deleter.Delete(key)
+	}
+	filtered.Map.Delete(key)
+}
@@ -0,0 +1,297 @@
+package hashtables_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"hashtables"
+)
+
+// Return n distinct keys with a prefix.
+func filterKeys(prefix string, n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprint(prefix, i)
+	}
+	return keys
+}
+
+// Return the fraction of keys the filter may contain.
+func mayContainRate(filter hashtables.Filter[string], keys []string) float64 {
+	found := 0
+	for _, key := range keys {
+		if filter.MayContain(key) {
+			found++
+		}
+	}
+	return float64(found) / float64(len(keys))
+}
+
+// Return serialized filter data: the magic, header fields and zeroed contents.
+func filterData(magic string, fields []uint64, bytes int) []byte {
+	data := []byte(magic)
+	for _, field := range fields {
+		data = binary.AppendUvarint(data, field)
+	}
+	return append(data, make([]byte, bytes)...)
+}
+
+func TestBloomFilter(t *testing.T) {
+	keys := hashtables.StringKeys(nil)
+	filter, err := hashtables.NewBloomFilter(1000, 0.01, keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, strangers := filterKeys("added", 1000), filterKeys("stranger", 10000)
+	for _, key := range added {
+		filter.Add(key)
+	}
+	if rate := mayContainRate(filter, added); rate != 1 {
+		t.Errorf("found %.3f of the keys added", rate)
+	}
+	if rate := mayContainRate(filter, strangers); rate > 0.03 {
+		t.Errorf("false positive rate %.3f, want about 0.01", rate)
+	}
+	if rate := filter.FalsePositiveRate(); rate < 0.005 || rate > 0.02 {
+		t.Errorf("estimated false positive rate %.3f, want about 0.01", rate)
+	}
+
+	// A loaded filter answers the same way.
+	data, _ := filter.MarshalBinary()
+	loaded, err := hashtables.UnmarshalBloomFilter(data, keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != filter.Len() || loaded.Bits() != filter.Bits() || loaded.Hashes() != filter.Hashes() {
+		t.Errorf("loaded %d keys, %d bits and %d hashes", loaded.Len(), loaded.Bits(), loaded.Hashes())
+	}
+	for _, key := range append(added, strangers...) {
+		if loaded.MayContain(key) != filter.MayContain(key) {
+			t.Fatalf("the loaded filter changed its answer for %q", key)
+		}
+	}
+	for _, bad := range [][]byte{nil, data[:6], data[:len(data)-1]} {
+		if _, err := hashtables.UnmarshalBloomFilter(bad, keys, nil); err == nil {
+			t.Errorf("unmarshaled %d bytes of %d", len(bad), len(data))
+		}
+	}
+
+	// A union holds the keys of both filters.
+	other, _ := hashtables.NewBloomFilter(1000, 0.01, keys, nil)
+	for _, key := range strangers[:500] {
+		other.Add(key)
+	}
+	if err := filter.Union(other); err != nil {
+		t.Fatal(err)
+	}
+	if rate := mayContainRate(filter, append(added, strangers[:500]...)); rate != 1 || filter.Len() != 1500 {
+		t.Errorf("the union found %.3f of the keys and has Len %d", rate, filter.Len())
+	}
+	smaller, _ := hashtables.NewBloomFilter(10, 0.01, keys, nil)
+	if filter.Union(smaller) == nil {
+		t.Error("united filters of different sizes")
+	}
+	jenkins, _ := hashtables.NewBloomFilter(1000, 0.01, hashtables.StringKeys(hashtables.Jenkins), nil)
+	if filter.Union(jenkins) == nil {
+		t.Error("united filters with different KeyHashers")
+	}
+}
+
+// Corrupt sizes are rejected before the filter is allocated.
+func TestUnmarshalFilterSizes(t *testing.T) {
+	keys := hashtables.StringKeys(nil)
+	if bloom, err := hashtables.UnmarshalBloomFilter(filterData("BLM\x01", []uint64{16, 2, 0}, 2), keys, nil); err != nil ||
+		bloom.MayContain("Ann") {
+		t.Errorf("an empty 16-bit Bloom filter gives an error %v", err)
+	}
+	for name, bad := range map[string][]byte{
+		"huge":        filterData("BLM\x01", []uint64{1 << 62, 3, 0}, 0),
+		"large":       filterData("BLM\x01", []uint64{1 << 40, 3, 0}, 1),
+		"short":       filterData("BLM\x01", []uint64{16, 2, 0}, 1),
+		"long":        filterData("BLM\x01", []uint64{16, 2, 0}, 3),
+		"many hashes": filterData("BLM\x01", []uint64{16, 17, 0}, 2),
+	} {
+		if _, err := hashtables.UnmarshalBloomFilter(bad, keys, nil); err == nil {
+			t.Errorf("unmarshaled a %s Bloom filter", name)
+		}
+	}
+
+	// A cuckoo header is the bucket count, the fingerprint size byte, the count,
+	// the pending fingerprint and its bucket.
+	cuckoo := func(numBuckets uint64, fingerprintBits byte, victim, victimBucket uint64, bytes int) []byte {
+		data := filterData("CKO\x01", []uint64{numBuckets}, 0)
+		return filterData(string(append(data, fingerprintBits)), []uint64{0, victim, victimBucket}, bytes)
+	}
+	if filter, err := hashtables.UnmarshalCuckooFilter(cuckoo(2, 8, 0, 0, 8), keys, nil); err != nil ||
+		filter.MayContain("Ann") || !filter.Add("Ann") || !filter.MayContain("Ann") {
+		t.Errorf("an empty 2-bucket cuckoo filter gives an error %v", err)
+	}
+	for name, bad := range map[string][]byte{
+		"huge":       cuckoo(1<<61, 8, 0, 0, 0),
+		"large":      cuckoo(1<<40, 8, 0, 0, 1),
+		"short":      cuckoo(2, 8, 0, 0, 7),
+		"long":       cuckoo(2, 8, 0, 0, 9),
+		"bad victim": cuckoo(2, 8, 256, 0, 8),
+		"misplaced":  cuckoo(2, 8, 1, 2, 8),
+	} {
+		if _, err := hashtables.UnmarshalCuckooFilter(bad, keys, nil); err == nil {
+			t.Errorf("unmarshaled a %s cuckoo filter", name)
+		}
+	}
+}
+
+func TestCuckooFilter(t *testing.T) {
+	keys := hashtables.StringKeys(nil)
+	filter, err := hashtables.NewCuckooFilter(1000, 0.01, keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, strangers := filterKeys("added", 1000), filterKeys("stranger", 10000)
+	for _, key := range added {
+		if !filter.Add(key) {
+			t.Fatalf("the filter is full after %d keys", filter.Len())
+		}
+	}
+	if rate := mayContainRate(filter, added); rate != 1 {
+		t.Errorf("found %.3f of the keys added", rate)
+	}
+	if rate := mayContainRate(filter, strangers); rate > 0.03 {
+		t.Errorf("false positive rate %.3f, want about 0.01", rate)
+	}
+
+	// Deleting keys leaves the others.
+	for _, key := range added[:500] {
+		if !filter.Delete(key) {
+			t.Fatalf("Delete(%q) found nothing", key)
+		}
+	}
+	if rate := mayContainRate(filter, added[500:]); rate != 1 || filter.Len() != 500 {
+		t.Errorf("found %.3f of the keys left and Len %d", rate, filter.Len())
+	}
+	if rate := mayContainRate(filter, added[:500]); rate > 0.03 {
+		t.Errorf("%.3f of the deleted keys are still there", rate)
+	}
+
+	// A loaded filter answers the same way.
+	data, _ := filter.MarshalBinary()
+	loaded, err := hashtables.UnmarshalCuckooFilter(data, keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range append(added, strangers...) {
+		if loaded.MayContain(key) != filter.MayContain(key) {
+			t.Fatalf("the loaded filter changed its answer for %q", key)
+		}
+	}
+	if loaded.Len() != filter.Len() || loaded.LoadFactor() != filter.LoadFactor() {
+		t.Errorf("loaded Len %d and load factor %v", loaded.Len(), loaded.LoadFactor())
+	}
+	for _, bad := range [][]byte{nil, data[:5], data[:len(data)-1]} {
+		if _, err := hashtables.UnmarshalCuckooFilter(bad, keys, nil); err == nil {
+			t.Errorf("unmarshaled %d bytes of %d", len(bad), len(data))
+		}
+	}
+
+	// A union holds the keys of both filters.
+	other, _ := hashtables.NewCuckooFilter(1000, 0.01, keys, nil)
+	for _, key := range strangers[:300] {
+		other.Add(key)
+	}
+	if err := filter.Union(other); err != nil {
+		t.Fatal(err)
+	}
+	if rate := mayContainRate(filter, append(added[500:], strangers[:300]...)); rate != 1 || filter.Len() != 800 {
+		t.Errorf("the union found %.3f of the keys and has Len %d", rate, filter.Len())
+	}
+	smaller, _ := hashtables.NewCuckooFilter(10, 0.01, keys, nil)
+	if filter.Union(smaller) == nil {
+		t.Error("united filters of different sizes")
+	}
+}
+
+// A full cuckoo filter holds the fingerprint it couldn't place until a Delete makes room.
+func TestCuckooFilterFull(t *testing.T) {
+	keys := hashtables.StringKeys(nil)
+	filter, _ := hashtables.NewCuckooFilter(4, 0.01, keys, nil)
+	var added []string
+	for _, key := range filterKeys("added", 100) {
+		if !filter.Add(key) {
+			break
+		}
+		added = append(added, key)
+	}
+	if len(added) == 100 {
+		t.Fatal("a filter for 4 keys never filled up")
+	}
+	if rate := mayContainRate(filter, added); rate != 1 || filter.Len() != len(added) {
+		t.Errorf("a full filter found %.3f of %d keys and has Len %d", rate, len(added), filter.Len())
+	}
+
+	// The pending fingerprint survives a round trip.
+	data, _ := filter.MarshalBinary()
+	loaded, err := hashtables.UnmarshalCuckooFilter(data, keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate := mayContainRate(loaded, added); rate != 1 || loaded.Add("late") {
+		t.Errorf("the loaded filter found %.3f of the keys or took another", rate)
+	}
+
+	// Deleting a placed key makes room for the pending one, so nothing is lost.
+	if !filter.Delete(added[0]) {
+		t.Fatalf("Delete(%q) found nothing", added[0])
+	}
+	if rate := mayContainRate(filter, added[1:]); rate != 1 || filter.Len() != len(added)-1 {
+		t.Errorf("found %.3f of the keys left and Len %d", rate, filter.Len())
+	}
+	if !filter.Add(added[0]) || !filter.MayContain(added[0]) {
+		t.Error("no room after a Delete")
+	}
+}
+
+func TestFilteredMap(t *testing.T) {
+	keys := hashtables.StringKeys(nil)
+	bloom, _ := hashtables.NewBloomFilter(100, 0.01, keys, nil)
+	cuckoo, _ := hashtables.NewCuckooFilter(100, 0.01, keys, nil)
+	tiny, _ := hashtables.NewCuckooFilter(4, 0.01, keys, nil)
+	for name, filter := range map[string]hashtables.Filter[string]{"bloom": bloom, "cuckoo": cuckoo, "tiny": tiny} {
+		m := hashtables.NewLinearProbingMap[string, int](128, keys)
+		m.Set("before", 0)
+		filtered := hashtables.NewFilteredMap[string, int](m, filter)
+		added := filterKeys("added", 50)
+		for i, key := range added {
+			filtered.Set(key, i)
+		}
+		for i, key := range append(added, "before") {
+			if value, ok := filtered.Lookup(key); !ok || (key != "before" && value != i) {
+				t.Errorf("%s: Lookup(%q) = %d, %v", name, key, value, ok)
+			}
+		}
+		for _, key := range filterKeys("stranger", 1000) {
+			if filtered.Contains(key) {
+				t.Errorf("%s: found %q", name, key)
+			}
+		}
+		stats := filtered.FilterStats()
+		if stats.Probed+stats.Skipped != 1051 || stats.FalsePositives > stats.Probed-51 {
+			t.Errorf("%s: stats %+v", name, stats)
+		}
+		if name != "tiny" && stats.Skipped < 950 {
+			t.Errorf("%s: the filter skipped only %d of 1000 misses", name, stats.Skipped)
+		}
+		if name == "tiny" && stats.Skipped != 0 {
+			t.Errorf("a full filter skipped %d lookups", stats.Skipped)
+		}
+	}
+
+	// Deleting a key removes it from a filter that supports deletes.
+	filtered := hashtables.NewFilteredMap[string, int](hashtables.NewChainingMap[string, int](16, keys), cuckoo)
+	filtered.Set("Ann", 1)
+	before := cuckoo.Len()
+	filtered.Delete("Ann")
+	filtered.Delete("Ann")
+	if cuckoo.Len() != before-1 || filtered.Contains("Ann") {
+		t.Errorf("the filter has Len %d after deleting Ann, want %d", cuckoo.Len(), before-1)
+	}
+}
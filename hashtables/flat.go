@@ -0,0 +1,585 @@
+package hashtables
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// FlatChainingMap is a chaining table that keeps every entry in one flat array.
+// Each bucket holds the int32 index of its first entry, and each entry holds the
+// index of the next entry in its chain, so the table makes no allocation per entry
+// and the chains stay close together in memory. Deleted entries go on a free list
+// that later sets reuse.
+type FlatChainingMap[K any, V any] struct {
+	numBuckets int
+	heads      []int32 // The index of each bucket's first entry, or noEntry.
+	entries    []flatEntry[K, V]
+	free       int32 // The first entry on the free list, or noEntry.
+	count      int
+	keys       KeyHasher[K]
+	recorder   Recorder
+	clock      Clock
+	hasTTL     bool // True once any entry has been set with a time to live.
+
+	// During an incremental resize, old holds the previous buckets and moved counts
+	// how many of them have been migrated. migrateBuckets is how many old buckets each
+	// operation migrates, or 0 if Resize rebuilds the table at once.
+	old            *FlatChainingMap[K, V]
+	moved          int
+	migrateBuckets int
+}
+
+// flatEntry is an entry stored in the flat array with its hash code and the
+// index of the next entry in its chain or on the free list.
+type flatEntry[K any, V any] struct {
+	Entry[K, V]
+	hash int
+	next int32
+}
+
+// noEntry ends a chain or the free list.
+const noEntry int32 = -1
+
+// FlatChainingHashTable is a flat chaining table of names and phone numbers.
+type FlatChainingHashTable = FlatChainingMap[string, string]
+
+// Initialize a FlatChainingMap and return a pointer to it.
+func NewFlatChainingMap[K any, V any](numBuckets int, keys KeyHasher[K]) *FlatChainingMap[K, V] {
+	heads := make([]int32, numBuckets)
+	for i := range heads {
+		heads[i] = noEntry
+	}
+	return &FlatChainingMap[K, V]{
+		numBuckets: numBuckets,
+		heads:      heads,
+		free:       noEntry,
+		keys:       keys,
+	}
+}
+
+// Initialize a FlatChainingHashTable and return a pointer to it.
+func NewFlatChainingHashTable(numBuckets int, hash HashFunc) *FlatChainingHashTable {
+	return NewFlatChainingMap[string, string](numBuckets, StringKeys(hash))
+}
+
+// Return the number of live entries.
+func (hashTable *FlatChainingMap[K, V]) Len() int {
+	if hashTable.old != nil {
+		return hashTable.count + hashTable.old.count
+	}
+	return hashTable.count
+}
+
+// Return the number of buckets.
+func (hashTable *FlatChainingMap[K, V]) Capacity() int {
+	return hashTable.numBuckets
+}
+
+// Return the index of the bucket that holds this key.
+func (hashTable *FlatChainingMap[K, V]) bucketIndex(key K) int {
+	return reduce(hashTable.keys.Hash(key), hashTable.numBuckets)
+}
+
+// Return the number of entries in a bucket.
+func (hashTable *FlatChainingMap[K, V]) bucketLen(bucketIndex int) int {
+	length := 0
+	for i := hashTable.heads[bucketIndex]; i != noEntry; i = hashTable.entries[i].next {
+		length++
+	}
+	return length
+}
+
+// Call fn for each entry in a bucket until fn returns false. Return false if fn did.
+func (hashTable *FlatChainingMap[K, V]) eachEntry(bucketIndex int, fn func(entry *flatEntry[K, V]) bool) bool {
+	for i := hashTable.heads[bucketIndex]; i != noEntry; i = hashTable.entries[i].next {
+		if !fn(&hashTable.entries[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Find the bucket and entry holding this key.
+// Return the bucket number and the key's position in its chain.
+// If the key is not present, return the bucket number and -1.
+// During an incremental resize, Find only searches the new buckets, so inspecting
+// the table doesn't migrate anything.
+func (hashTable *FlatChainingMap[K, V]) Find(key K) (int, int) {
+	hash := hashTable.keys.Hash(key)
+	bucketIndex := reduce(hash, hashTable.numBuckets)
+	_, position := hashTable.searchBucket(bucketIndex, hash, key, nil)
+	return bucketIndex, position
+}
+
+// Search the key's bucket like Find and also return the index of the key's entry
+// in the flat array, or noEntry. If observe is not nil, call it for each entry examined.
+// During an incremental resize, a key that is still in the old buckets
+// is moved to its new bucket.
+func (hashTable *FlatChainingMap[K, V]) search(key K, hash int,
+	observe func(kind EventKind, index int, step int)) (int, int32, int) {
+	bucketIndex := reduce(hash, hashTable.numBuckets)
+	index, position := hashTable.searchBucket(bucketIndex, hash, key, observe)
+	if hashTable.old == nil || index != noEntry {
+		return bucketIndex, index, position
+	}
+
+	oldBucketIndex := reduce(hash, hashTable.old.numBuckets)
+	oldIndex, _ := hashTable.old.searchBucket(oldBucketIndex, hash, key, nil)
+	if oldIndex == noEntry {
+		return bucketIndex, noEntry, -1
+	}
+	index = hashTable.moveFromOld(oldBucketIndex, oldIndex)
+	return bucketIndex, index, 0
+}
+
+// Search one of this table's own buckets, dropping its expired entries first.
+// Return the entry's index in the flat array and its position in the chain,
+// or noEntry and -1.
+func (hashTable *FlatChainingMap[K, V]) searchBucket(bucketIndex int, hash int, key K,
+	observe func(kind EventKind, index int, step int)) (int32, int) {
+	hashTable.expireBucket(bucketIndex)
+	position := 0
+	for i := hashTable.heads[bucketIndex]; i != noEntry; i = hashTable.entries[i].next {
+		if observe != nil {
+			observe(EventVisit, bucketIndex, position)
+		}
+		entry := &hashTable.entries[i]
+		if entry.hash == hash && hashTable.keys.Equal(entry.Key, key) {
+			return i, position
+		}
+		position++
+	}
+	return noEntry, -1
+}
+
+// Put an entry at the front of a bucket's chain, reusing a free entry if there is one.
+// Return its index in the flat array.
+func (hashTable *FlatChainingMap[K, V]) addEntry(bucketIndex int, hash int, entry Entry[K, V]) int32 {
+	flat := flatEntry[K, V]{Entry: entry, hash: hash, next: hashTable.heads[bucketIndex]}
+	index := hashTable.free
+	if index == noEntry {
+		index = int32(len(hashTable.entries))
+		hashTable.entries = append(hashTable.entries, flat)
+	} else {
+		hashTable.free = hashTable.entries[index].next
+		hashTable.entries[index] = flat
+	}
+	hashTable.heads[bucketIndex] = index
+	hashTable.count++
+	return index
+}
+
+// Unlink an entry from its bucket's chain and put it on the free list.
+func (hashTable *FlatChainingMap[K, V]) removeEntry(bucketIndex int, index int32) {
+	link := &hashTable.heads[bucketIndex]
+	for *link != index {
+		link = &hashTable.entries[*link].next
+	}
+	*link = hashTable.entries[index].next
+
+	// Clear the entry so the free list doesn't keep its key and value alive.
+	hashTable.entries[index] = flatEntry[K, V]{Entry: Entry[K, V]{deleted: true}, next: hashTable.free}
+	hashTable.free = index
+	hashTable.count--
+}
+
+// Record events with this recorder. A nil recorder stops recording.
+func (hashTable *FlatChainingMap[K, V]) SetRecorder(recorder Recorder) {
+	hashTable.recorder = recorder
+}
+
+// Record an event if there is a recorder.
+func (hashTable *FlatChainingMap[K, V]) record(event Event) {
+	if hashTable.recorder != nil {
+		hashTable.recorder(event)
+	}
+}
+
+// Record an event about an entry if there is a recorder.
+// The key and value are only formatted when something is recording.
+func (hashTable *FlatChainingMap[K, V]) recordEntry(kind EventKind, key K, value V, slot int, step int) {
+	if hashTable.recorder != nil {
+		hashTable.recorder(Event{Kind: kind, Key: format(key), Value: format(value), Slot: slot, Step: step})
+	}
+}
+
+// Start recording an operation on this key and return the observer
+// that records the entries it examines, or nil if nothing is recording.
+func (hashTable *FlatChainingMap[K, V]) trace(kind EventKind, key K, value string) func(EventKind, int, int) {
+	if hashTable.recorder == nil {
+		return nil
+	}
+	return hashTable.traceEvent(Event{Kind: kind, Key: format(key), Value: value})
+}
+
+// Record the event that starts an operation and return the observer that records its steps.
+func (hashTable *FlatChainingMap[K, V]) traceEvent(start Event) func(EventKind, int, int) {
+	start.Slot = -1
+	hashTable.record(start)
+	return func(kind EventKind, index int, step int) {
+		hashTable.record(Event{Kind: kind, Key: start.Key, Slot: index, Step: step})
+	}
+}
+
+// Set where the table gets the time for expiring entries. A nil clock means time.Now.
+func (hashTable *FlatChainingMap[K, V]) SetClock(clock Clock) {
+	hashTable.clock = clock
+}
+
+// Cut the entries whose time is up out of a bucket. Return how many there were.
+func (hashTable *FlatChainingMap[K, V]) expireBucket(bucketIndex int) int {
+	if !hashTable.hasTTL {
+		return 0
+	}
+	removed := 0
+	for i := hashTable.heads[bucketIndex]; i != noEntry; {
+		entry := &hashTable.entries[i]
+		next := entry.next
+		if entry.expires != 0 && hashTable.clock.expired(entry.expires) {
+			hashTable.record(Event{Kind: EventExpire, Key: format(entry.Key), Slot: bucketIndex})
+			hashTable.removeEntry(bucketIndex, i)
+			removed++
+		}
+		i = next
+	}
+	return removed
+}
+
+// Remove every expired entry. Return how many there were.
+func (hashTable *FlatChainingMap[K, V]) RemoveExpired() int {
+	removed := 0
+	for bucketIndex := range hashTable.heads {
+		removed += hashTable.expireBucket(bucketIndex)
+	}
+	if hashTable.old != nil {
+		removed += hashTable.old.RemoveExpired()
+	}
+	return removed
+}
+
+// Add an item to the hash table.
+func (hashTable *FlatChainingMap[K, V]) Set(key K, value V) {
+	hashTable.set(key, value, 0, 0)
+}
+
+// Add an item that expires once ttl has passed. A ttl of 0 or less means it never expires.
+func (hashTable *FlatChainingMap[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	hashTable.set(key, value, hashTable.clock.expiry(ttl), ttl)
+}
+
+// Add an item that expires at this time in Unix nanoseconds, or never if expires is 0.
+// The ttl it was set with is only recorded, so a replay can set it again.
+func (hashTable *FlatChainingMap[K, V]) set(key K, value V, expires int64, ttl time.Duration) {
+	hashTable.migrate(hashTable.migrateBuckets)
+	hash := hashTable.keys.Hash(key)
+	var observe func(EventKind, int, int)
+	if hashTable.recorder != nil {
+		observe = hashTable.traceEvent(Event{Kind: EventSet, Key: format(key), Value: format(value), TTL: ttl})
+	}
+	bucketIndex, index, position := hashTable.search(key, hash, observe)
+	if expires != 0 {
+		hashTable.hasTTL = true
+	}
+
+	// If the entry is found, update its value
+	if index != noEntry {
+		entry := &hashTable.entries[index]
+		entry.Value = value
+		entry.expires = expires
+		hashTable.recordEntry(EventUpdate, key, value, bucketIndex, position)
+		return
+	}
+
+	// If the entry is not found, put it at the front of the bucket
+	hashTable.addEntry(bucketIndex, hash, Entry[K, V]{Key: key, Value: value, expires: expires})
+	hashTable.recordEntry(EventClaim, key, value, bucketIndex, 0)
+}
+
+// Return an item from the hash table, or the zero value if it is not present.
+func (hashTable *FlatChainingMap[K, V]) Get(key K) V {
+	value, _ := hashTable.Lookup(key)
+	return value
+}
+
+// Return an item from the hash table and whether it was present.
+func (hashTable *FlatChainingMap[K, V]) Lookup(key K) (V, bool) {
+	hashTable.migrate(hashTable.migrateBuckets)
+	_, index, _ := hashTable.search(key, hashTable.keys.Hash(key), hashTable.trace(EventLookup, key, ""))
+	if index != noEntry {
+		return hashTable.entries[index].Value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Return true if the key is in the hash table.
+func (hashTable *FlatChainingMap[K, V]) Contains(key K) bool {
+	hashTable.migrate(hashTable.migrateBuckets)
+	_, index, _ := hashTable.search(key, hashTable.keys.Hash(key), hashTable.trace(EventLookup, key, ""))
+	return index != noEntry
+}
+
+// Delete this key's entry.
+func (hashTable *FlatChainingMap[K, V]) Delete(key K) {
+	hashTable.migrate(hashTable.migrateBuckets)
+	bucketIndex, index, position := hashTable.search(key, hashTable.keys.Hash(key),
+		hashTable.trace(EventDelete, key, ""))
+
+	// If the entry was found, unlink it and free it
+	if index != noEntry {
+		hashTable.removeEntry(bucketIndex, index)
+		if hashTable.recorder != nil {
+			hashTable.record(Event{Kind: EventRemove, Key: format(key), Slot: bucketIndex, Step: position})
+		}
+	}
+}
+
+// Call fn for each entry, bucket by bucket, until fn returns false.
+func (hashTable *FlatChainingMap[K, V]) Range(fn func(key K, value V) bool) {
+	visit := func(entry *flatEntry[K, V]) bool {
+		return hashTable.clock.expired(entry.expires) || fn(entry.Key, entry.Value)
+	}
+	for bucketIndex := range hashTable.heads {
+		if !hashTable.eachEntry(bucketIndex, visit) {
+			return
+		}
+	}
+	if hashTable.old != nil {
+		hashTable.old.Range(fn)
+	}
+}
+
+// Rebuild the table with a new number of buckets, dropping expired entries
+// and the free list. The entries keep their hash codes, so no key is hashed again.
+// In incremental mode, Resize finishes any migration in progress, then starts
+// a new one instead of moving the entries at once.
+func (hashTable *FlatChainingMap[K, V]) Resize(numBuckets int) {
+	hashTable.record(Event{Kind: EventResize, Slot: -1, Capacity: numBuckets, Migrate: hashTable.migrateBuckets})
+	hashTable.FinishResize()
+
+	resized := NewFlatChainingMap[K, V](numBuckets, hashTable.keys)
+	resized.clock = hashTable.clock
+	resized.hasTTL = hashTable.hasTTL
+	resized.migrateBuckets = hashTable.migrateBuckets
+	if hashTable.migrateBuckets > 0 {
+		old := *hashTable
+		old.recorder = nil
+		resized.old = &old
+		resized.recorder = hashTable.recorder
+		*hashTable = *resized
+		return
+	}
+	resized.entries = make([]flatEntry[K, V], 0, hashTable.count)
+	for i := range hashTable.heads {
+		hashTable.eachEntry(i, func(entry *flatEntry[K, V]) bool {
+			if hashTable.clock.expired(entry.expires) {
+				return true
+			}
+			to := reduce(entry.hash, numBuckets)
+			resized.addEntry(to, entry.hash, entry.Entry)
+			if hashTable.recorder != nil {
+				hashTable.record(Event{Kind: EventMove, Key: format(entry.Key), From: i, Slot: to})
+			}
+			return true
+		})
+	}
+	resized.recorder = hashTable.recorder
+	*hashTable = *resized
+}
+
+// Make later resizes incremental, migrating this many old buckets during each Set, Get,
+// Contains and Delete. Until the migration is done, the table keeps both sets of
+// buckets and searches them both. A bucketsPerOp of 0 makes Resize rebuild the table
+// at once again.
+func (hashTable *FlatChainingMap[K, V]) SetIncrementalResize(bucketsPerOp int) {
+	hashTable.migrateBuckets = max(0, bucketsPerOp)
+}
+
+// Migrate every old bucket that is left from an incremental resize.
+func (hashTable *FlatChainingMap[K, V]) FinishResize() {
+	if hashTable.old != nil {
+		hashTable.migrate(hashTable.old.numBuckets - hashTable.moved)
+	}
+}
+
+// Migrate up to n old buckets during an incremental resize.
+func (hashTable *FlatChainingMap[K, V]) migrate(n int) {
+	for ; n > 0 && hashTable.old != nil; n-- {
+		oldBucketIndex := hashTable.moved
+		hashTable.old.expireBucket(oldBucketIndex)
+		for hashTable.old.heads[oldBucketIndex] != noEntry {
+			hashTable.moveFromOld(oldBucketIndex, hashTable.old.heads[oldBucketIndex])
+		}
+
+		hashTable.moved++
+		if hashTable.moved == hashTable.old.numBuckets {
+			hashTable.old = nil
+			hashTable.moved = 0
+		}
+	}
+}
+
+// Move one entry from an old bucket to the front of its new bucket.
+// Return its index in the new flat array.
+func (hashTable *FlatChainingMap[K, V]) moveFromOld(oldBucketIndex int, oldIndex int32) int32 {
+	flat := hashTable.old.entries[oldIndex]
+	hashTable.old.removeEntry(oldBucketIndex, oldIndex)
+	bucketIndex := reduce(flat.hash, hashTable.numBuckets)
+	index := hashTable.addEntry(bucketIndex, flat.hash, flat.Entry)
+	if hashTable.recorder != nil {
+		hashTable.record(Event{Kind: EventMove, Key: format(flat.Key), From: oldBucketIndex, Slot: bucketIndex})
+	}
+	return index
+}
+
+// Return the progress of an incremental resize, or nil if there is none.
+func (hashTable *FlatChainingMap[K, V]) migration() *Migration {
+	if hashTable.old == nil {
+		return nil
+	}
+	return &Migration{OldCapacity: hashTable.old.numBuckets, Moved: hashTable.moved, Left: hashTable.old.count}
+}
+
+// Describe each bucket for display.
+func (hashTable *FlatChainingMap[K, V]) Slots() []Slot {
+	slots := make([]Slot, hashTable.numBuckets)
+	for i, head := range hashTable.heads {
+		slots[i] = Slot{State: SlotEmpty, Home: i, Entries: hashTable.bucketLen(i), Chained: true}
+		if head != noEntry {
+			slots[i].State = SlotLive
+			slots[i].Name = format(hashTable.entries[head].Key)
+		}
+	}
+	return slots
+}
+
+// Return the index of the bucket that Find examines for this key.
+func (hashTable *FlatChainingMap[K, V]) ProbePath(key K) []int {
+	return []int{hashTable.bucketIndex(key)}
+}
+
+// Display the hash table's contents with each entry's index in the flat array.
+func (hashTable *FlatChainingMap[K, V]) Dump(w io.Writer) {
+	for i := range hashTable.heads {
+		fmt.Fprintf(w, "Bucket %d:\n", i)
+		for j := hashTable.heads[i]; j != noEntry; j = hashTable.entries[j].next {
+			entry := &hashTable.entries[j]
+			fmt.Fprintf(w, "\t[%d] %s: %s\n", j, format(entry.Key), format(entry.Value))
+		}
+	}
+	fmt.Fprintf(w, "Entries: %d used, %d free\n", hashTable.count, len(hashTable.entries)-hashTable.count)
+	if migration := hashTable.migration(); migration != nil {
+		migration.Dump(w)
+		hashTable.old.Dump(w)
+	}
+}
+
+// Make a display showing each bucket's chain length.
+// Empty buckets are shown as '.' and chains longer than 9 as '+'.
+func (hashTable *FlatChainingMap[K, V]) DumpConcise(w io.Writer) {
+	for i := range hashTable.heads {
+		hashTable.dumpBucketLength(w, i)
+		if i%50 == 49 {
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintln(w)
+
+	// Show the old buckets too, with the ones already migrated as '>'.
+	if migration := hashTable.migration(); migration != nil {
+		migration.Dump(w)
+		for i := range hashTable.old.heads {
+			if i < hashTable.moved {
+				fmt.Fprint(w, ">")
+			} else {
+				hashTable.old.dumpBucketLength(w, i)
+			}
+			if i%50 == 49 {
+				fmt.Fprintln(w)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// Show one bucket's chain length as a single character.
+func (hashTable *FlatChainingMap[K, V]) dumpBucketLength(w io.Writer, bucketIndex int) {
+	switch length := hashTable.bucketLen(bucketIndex); {
+	case length == 0:
+		fmt.Fprint(w, ".")
+	case length > 9:
+		fmt.Fprint(w, "+")
+	default:
+		fmt.Fprint(w, length)
+	}
+}
+
+// Show the entries examined while looking for this key.
+// Return the key's position in its chain, or -1 if it is not present.
+func (hashTable *FlatChainingMap[K, V]) Probe(w io.Writer, key K) int {
+	hash := hashTable.keys.Hash(key)
+	bucketIndex := reduce(hash, hashTable.numBuckets)
+	fmt.Fprintf(w, "Probing %s (bucket %d)\n", format(key), bucketIndex)
+	_, position := hashTable.searchBucket(bucketIndex, hash, key, func(kind EventKind, index int, step int) {
+		fmt.Fprintf(w, "    %d: %s\n", step, format(hashTable.entryAt(bucketIndex, step).Key))
+	})
+	if position < 0 {
+		fmt.Fprintf(w, "    Not found\n")
+	} else {
+		fmt.Fprintf(w, "    Returning found position %d\n", position)
+	}
+	return position
+}
+
+// Return the entry at this position in a bucket's chain.
+func (hashTable *FlatChainingMap[K, V]) entryAt(bucketIndex int, position int) *flatEntry[K, V] {
+	i := hashTable.heads[bucketIndex]
+	for ; position > 0; position-- {
+		i = hashTable.entries[i].next
+	}
+	return &hashTable.entries[i]
+}
+
+// Return the average number of entries examined to find the items in the table.
+func (hashTable *FlatChainingMap[K, V]) AveProbeSequenceLength() float32 {
+	// An empty table has no probe sequences to average.
+	if hashTable.count == 0 {
+		return 0
+	}
+
+	// The kth entry in a chain takes k probes to find.
+	totalLength := 0
+	for i := range hashTable.heads {
+		length := hashTable.bucketLen(i)
+		totalLength += length * (length + 1) / 2
+	}
+	return float32(totalLength) / float32(hashTable.count)
+}
+
+// Collect the stats for the table.
+func (hashTable *FlatChainingMap[K, V]) Stats() Stats {
+	stats := Stats{Capacity: hashTable.numBuckets, ChainLengths: []int{}}
+
+	var successful, unsuccessful []int
+	for i := range hashTable.heads {
+		length := hashTable.bucketLen(i)
+		stats.Live += length
+		if length == 0 {
+			stats.Empty++
+		}
+		stats.ChainLengths = increment(stats.ChainLengths, length)
+		if length > stats.MaxChain {
+			stats.MaxChain = length
+		}
+		for k := 1; k <= length; k++ {
+			successful = append(successful, k)
+		}
+		unsuccessful = append(unsuccessful, length)
+	}
+	stats.LoadFactor = float64(stats.Live) / float64(hashTable.numBuckets)
+	stats.Successful = newProbeStats(successful)
+	stats.Unsuccessful = newProbeStats(unsuccessful)
+	stats.Migration = hashTable.migration()
+	return stats
+}
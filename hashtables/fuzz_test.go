@@ -0,0 +1,188 @@
+package hashtables_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"hashtables"
+)
+
+// The fuzz input is a capacity byte followed by two bytes per operation:
+// one picks the operation and the other picks the key. A small key space
+// and small capacities make collisions, tombstones and full tables common.
+//
+// Run it with
+//
+//	go test -run '^$' -fuzz FuzzTables -fuzztime 30s
+const (
+	fuzzMaxCapacity = 16
+	fuzzNumKeys     = 24
+)
+
+type fuzzOpKind int
+
+const (
+	opSet fuzzOpKind = iota
+	opGet
+	opContains
+	opDelete
+	numFuzzOpKinds
+)
+
+// fuzzOp is one decoded operation.
+type fuzzOp struct {
+	kind  fuzzOpKind
+	key   string
+	value string
+}
+
+// Write the operation as a REPL command.
+func (op fuzzOp) String() string {
+	switch op.kind {
+	case opSet:
+		return fmt.Sprintf("set %s %s", op.key, op.value)
+	case opGet:
+		return "get " + op.key
+	case opContains:
+		return "contains " + op.key
+	default:
+		return "del " + op.key
+	}
+}
+
+// Decode fuzz input into a capacity and a list of operations.
+func decodeOps(data []byte) (int, []fuzzOp) {
+	if len(data) == 0 {
+		return 1, nil
+	}
+	capacity := 1 + int(data[0])%fuzzMaxCapacity
+	var ops []fuzzOp
+	for i := 1; i+1 < len(data); i += 2 {
+		ops = append(ops, fuzzOp{
+			kind:  fuzzOpKind(data[i]) % numFuzzOpKinds,
+			key:   fmt.Sprintf("k%d", int(data[i+1])%fuzzNumKeys),
+			value: fmt.Sprintf("v%d", len(ops)),
+		})
+	}
+	return capacity, ops
+}
+
+// Run the operations against a new table and a Go map, and describe the first
+// place the table disagrees with the map. Return "" if they always agree.
+func divergence(strategy hashtables.Strategy, capacity int, ops []fuzzOp) (problem string) {
+	table := strategy.New(capacity, hashtables.DJB2)
+	model := make(map[string]string)
+
+	var op fuzzOp
+	defer func() {
+		if p := recover(); p != nil {
+			problem = fmt.Sprintf("%s panicked with %d of %d slots live: %v", op, table.Len(), table.Capacity(), p)
+		}
+	}()
+
+	for _, op = range ops {
+		switch op.kind {
+		case opSet:
+			if !setOrFull(table, op.key, op.value) {
+				// Open addressing tables refuse new keys only when every slot is live.
+				if _, present := model[op.key]; present || table.Len() < table.Capacity() {
+					return fmt.Sprintf("%s: table is full with %d of %d slots live", op, table.Len(), table.Capacity())
+				}
+				continue
+			}
+			model[op.key] = op.value
+		case opGet:
+			if got, want := table.Get(op.key), model[op.key]; got != want {
+				return fmt.Sprintf("%s = %q, want %q", op, got, want)
+			}
+		case opContains:
+			_, want := model[op.key]
+			if got := table.Contains(op.key); got != want {
+				return fmt.Sprintf("%s = %v, want %v", op, got, want)
+			}
+		case opDelete:
+			table.Delete(op.key)
+			delete(model, op.key)
+		}
+
+		if table.Len() != len(model) {
+			return fmt.Sprintf("after %s: Len = %d, want %d", op, table.Len(), len(model))
+		}
+	}
+
+	// Check every key at the end too, since the operations may not have looked.
+	for i := 0; i < fuzzNumKeys; i++ {
+		key := fmt.Sprintf("k%d", i)
+		want, present := model[key]
+		if got := table.Get(key); got != want {
+			return fmt.Sprintf("at the end: get %s = %q, want %q", key, got, want)
+		}
+		if got := table.Contains(key); got != present {
+			return fmt.Sprintf("at the end: contains %s = %v, want %v", key, got, present)
+		}
+	}
+	return ""
+}
+
+// Set a key, returning false if the table panicked because it is full.
+func setOrFull(table hashtables.Table, key string, value string) (ok bool) {
+	defer func() {
+		if p := recover(); p != nil {
+			if p != "Hash table is full" {
+				panic(p)
+			}
+			ok = false
+		}
+	}()
+	table.Set(key, value)
+	return true
+}
+
+// Shrink a failing operation list by dropping operations, and then chunks of
+// operations, for as long as the table still diverges from the map.
+func minimizeOps(strategy hashtables.Strategy, capacity int, ops []fuzzOp) []fuzzOp {
+	for chunk := len(ops) / 2; chunk >= 1; chunk /= 2 {
+		for start := 0; start+chunk <= len(ops); {
+			shorter := append(append([]fuzzOp(nil), ops[:start]...), ops[start+chunk:]...)
+			if divergence(strategy, capacity, shorter) != "" {
+				ops = shorter
+			} else {
+				start++
+			}
+		}
+	}
+	return ops
+}
+
+// Describe a failure as a script that can be pasted into the REPL.
+func opScript(strategy hashtables.Strategy, capacity int, ops []fuzzOp) string {
+	var script strings.Builder
+	fmt.Fprintf(&script, "    new %s %d djb2\n", strategy.Name, capacity)
+	for _, op := range ops {
+		fmt.Fprintf(&script, "    %s\n", op)
+	}
+	return script.String()
+}
+
+func FuzzTables(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{9, 0, 1, 0, 2, 1, 1, 2, 2, 3, 1, 1, 1, 2, 2})
+	// Fill a small table, delete from it and look the deleted keys up again.
+	f.Add([]byte{4, 0, 0, 0, 4, 0, 8, 0, 12, 3, 4, 2, 4, 1, 4, 0, 16, 2, 4})
+	// Fill a table whose capacity is not prime, where quadratic and double hashing
+	// probe sequences cannot reach every slot.
+	f.Add([]byte{7, 0, 0, 0, 1, 0, 2, 0, 3, 0, 4, 0, 5, 0, 6, 0, 7, 0, 8, 0, 9})
+	f.Add([]byte{15, 0, 0, 0, 16, 0, 32, 0, 48, 0, 64, 0, 80, 0, 96, 0, 112, 3, 16, 0, 128, 2, 16})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		capacity, ops := decodeOps(data)
+		for _, strategy := range hashtables.Strategies {
+			if problem := divergence(strategy, capacity, ops); problem != "" {
+				ops = minimizeOps(strategy, capacity, ops)
+				t.Fatalf("%s table disagrees with a map: %s\nReplay it in the REPL with\n%s",
+					strategy.Name, divergence(strategy, capacity, ops), opScript(strategy, capacity, ops))
+			}
+		}
+	})
+}
@@ -0,0 +1,5 @@
+module hashtables
+
+go 1.21.3
+
+require golang.org/x/text v0.14.0
@@ -0,0 +1,102 @@
+package hashtables
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// HashFunc maps a key to a non-negative int.
+type HashFunc func(value string) int
+
+// DJB2 is the djb2 hash function. See http://www.cse.yorku.ca/~oz/hash.html.
+func DJB2(value string) int {
+	hash := 5381
+	for _, ch := range value {
+		hash = ((hash << 5) + hash) + int(ch)
+	}
+
+	// Make sure the result is non-negative. Negating the smallest int
+	// overflows back to itself, so clear its sign bit instead.
+	if hash < 0 {
+		hash = -hash
+	}
+	if hash < 0 {
+		hash &= math.MaxInt
+	}
+	return hash
+}
+
+// Jenkins is the one_at_a_time hash function.
+// See https://en.wikipedia.org/wiki/Jenkins_hash_function
+func Jenkins(value string) int {
+	hash := 0
+	for _, ch := range value {
+		hash += int(ch)
+		hash += hash << 10
+		hash ^= hash >> 6
+	}
+
+	// Make sure the result is non-negative. Negating the smallest int
+	// overflows back to itself, so clear its sign bit instead.
+	if hash < 0 {
+		hash = -hash
+	}
+	if hash < 0 {
+		hash &= math.MaxInt
+	}
+
+	// Make sure the result is not 0.
+	if hash == 0 {
+		hash = 1
+	}
+	return hash
+}
+
+// FNV1a is the 64-bit FNV-1a hash function.
+// See https://en.wikipedia.org/wiki/Fowler%E2%80%93Noll%E2%80%93Vo_hash_function
+func FNV1a(value string) int {
+	return Seeded(0)(value)
+}
+
+// Seeded returns an FNV-1a hash function whose offset basis is mixed with the seed,
+// so different seeds give independent-looking hash functions.
+func Seeded(seed uint64) HashFunc {
+	basis := uint64(14695981039346656037) ^ (seed * 0x9e3779b97f4a7c15)
+	return func(value string) int {
+		hash := basis
+		for i := 0; i < len(value); i++ {
+			hash ^= uint64(value[i])
+			hash *= 1099511628211
+		}
+
+		// Drop the sign bit so the result is non-negative.
+		return int(hash >> 1)
+	}
+}
+
+// Hashers holds the named hash functions that tools can choose from.
+var Hashers = map[string]HashFunc{
+	"djb2":    DJB2,
+	"jenkins": Jenkins,
+	"fnv1a":   FNV1a,
+}
+
+// Return the names of the registered hash functions in sorted order.
+func HasherNames() []string {
+	names := make([]string, 0, len(Hashers))
+	for name := range Hashers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Return the registered hash function with this name.
+func LookupHasher(name string) (HashFunc, error) {
+	hash, ok := Hashers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash function %q (have %v)", name, HasherNames())
+	}
+	return hash, nil
+}
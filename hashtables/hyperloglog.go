@@ -0,0 +1,163 @@
+package hashtables
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+const (
+	// MinLogLogPrecision and MaxLogLogPrecision bound a HyperLogLog's precision,
+	// which gives it 2^precision registers.
+	MinLogLogPrecision = 4
+	MaxLogLogPrecision = 18
+	// logLogHashBits is the number of bits remix returns.
+	logLogHashBits = 63
+)
+
+// HyperLogLog estimates the number of distinct keys added in 2^precision small
+// registers, as in Flajolet et al.'s "HyperLogLog: the analysis of a near-optimal
+// cardinality estimation algorithm". The first bits of a key's hash pick a register,
+// which keeps the longest run of leading zeros seen in the rest. Adding a key twice
+// changes nothing, so the estimate counts distinct keys. Its standard error is
+// 1.04 / sqrt(registers).
+type HyperLogLog[K any] struct {
+	registers []uint8
+	precision uint
+	keys      KeyHasher[K]
+}
+
+// Build a HyperLogLog with 2^precision registers, remixing the KeyHasher's hash so
+// weak hash functions like DJB2 still spread keys over them.
+func NewHyperLogLog[K any](precision int, keys KeyHasher[K]) (*HyperLogLog[K], error) {
+	if precision < MinLogLogPrecision || precision > MaxLogLogPrecision {
+		return nil, fmt.Errorf("precision must be between %d and %d, got %d",
+			MinLogLogPrecision, MaxLogLogPrecision, precision)
+	}
+	return &HyperLogLog[K]{registers: make([]uint8, 1<<precision), precision: uint(precision), keys: keys}, nil
+}
+
+// Return the precision needed for this standard error, for NewHyperLogLog.
+func LogLogPrecision(standardError float64) int {
+	registers := math.Pow(1.04/standardError, 2)
+	return min(MaxLogLogPrecision, max(MinLogLogPrecision, int(math.Ceil(math.Log2(registers)))))
+}
+
+// Record the key.
+func (counter *HyperLogLog[K]) Add(key K) {
+	hash := uint64(remix(counter.keys.Hash(key)))
+	register := hash >> (logLogHashBits - counter.precision)
+	// Move the rest of the bits to the top. The rank is one more than the number of
+	// leading zeros, counting only those bits.
+	rest := hash << (64 - logLogHashBits + counter.precision)
+	restBits := logLogHashBits - counter.precision
+	rank := uint8(min(uint(bits.LeadingZeros64(rest)), restBits) + 1)
+	counter.registers[register] = max(counter.registers[register], rank)
+}
+
+// Return the estimated number of distinct keys added.
+func (counter *HyperLogLog[K]) Count() uint64 {
+	m := float64(len(counter.registers))
+	sum, zeros := 0.0, 0
+	for _, rank := range counter.registers {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+	estimate := logLogAlpha(len(counter.registers)) * m * m / sum
+
+	// The raw estimate is biased when few registers are set, so count empty
+	// registers instead, as linear counting does. 63-bit hashes rarely collide,
+	// so large counts need no correction.
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(math.Round(estimate))
+}
+
+// Return the bias correction constant for this many registers.
+func logLogAlpha(registers int) float64 {
+	switch registers {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	}
+	return 0.7213 / (1 + 1.079/float64(registers))
+}
+
+// Return the precision, the log of the number of registers.
+func (counter *HyperLogLog[K]) Precision() int {
+	return int(counter.precision)
+}
+
+// Return the estimate's standard error as a fraction of the count: 1.04 / sqrt(registers).
+// About two thirds of estimates are within one standard error and 95% within two.
+func (counter *HyperLogLog[K]) StandardError() float64 {
+	return 1.04 / math.Sqrt(float64(len(counter.registers)))
+}
+
+// Add the keys counted by another HyperLogLog to this one, so Count estimates the
+// distinct keys in either. Both must have the same precision and KeyHasher.
+func (counter *HyperLogLog[K]) Merge(other *HyperLogLog[K]) error {
+	if counter.precision != other.precision {
+		return fmt.Errorf("can't merge a precision %d HyperLogLog with a precision %d one",
+			counter.precision, other.precision)
+	}
+	if !sameKeyHasher(counter.keys, other.keys) {
+		return errors.New("can't merge HyperLogLogs with different KeyHashers")
+	}
+	for i, rank := range other.registers {
+		counter.registers[i] = max(counter.registers[i], rank)
+	}
+	return nil
+}
+
+// logLogRegisterBits is the width of a serialized register, which holds ranks up to 63.
+const logLogRegisterBits = 6
+
+// The serialized form starts with these bytes and a version.
+var logLogMagic = []byte("HLL\x01")
+
+// Encode the HyperLogLog: its precision and 6-bit registers. The KeyHasher is not included.
+func (counter *HyperLogLog[K]) MarshalBinary() ([]byte, error) {
+	data := append([]byte(nil), logLogMagic...)
+	data = append(data, byte(counter.precision))
+	packed := newPackedInts(len(counter.registers), logLogRegisterBits)
+	for i, rank := range counter.registers {
+		packed.set(i, uint64(rank))
+	}
+	return packed.appendBytes(data, len(counter.registers)), nil
+}
+
+// Decode a HyperLogLog written by MarshalBinary. Keys must be hashed with the same
+// KeyHasher it was built with.
+func UnmarshalHyperLogLog[K any](data []byte, keys KeyHasher[K]) (*HyperLogLog[K], error) {
+	if len(data) < len(logLogMagic) || string(data[:len(logLogMagic)]) != string(logLogMagic) {
+		return nil, errors.New("not a serialized HyperLogLog")
+	}
+	data = data[len(logLogMagic):]
+	if len(data) == 0 {
+		return nil, errors.New("bad HyperLogLog header")
+	}
+	counter, err := NewHyperLogLog(int(data[0]), keys)
+	if err != nil {
+		return nil, err
+	}
+	packed := newPackedInts(len(counter.registers), logLogRegisterBits)
+	if err := packed.readBytes(data[1:], len(counter.registers)); err != nil {
+		return nil, fmt.Errorf("HyperLogLog registers: %w", err)
+	}
+	for i := range counter.registers {
+		rank := packed.get(i)
+		if rank > uint64(logLogHashBits-counter.precision+1) {
+			return nil, fmt.Errorf("bad HyperLogLog register %d: %d", i, rank)
+		}
+		counter.registers[i] = uint8(rank)
+	}
+	return counter, nil
+}
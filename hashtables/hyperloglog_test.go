@@ -0,0 +1,94 @@
+package hashtables_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"hashtables"
+)
+
+// Return how far the estimate is from the true count, in standard errors.
+func logLogErrors(counter *hashtables.HyperLogLog[string], count int) float64 {
+	return math.Abs(float64(counter.Count())-float64(count)) / (counter.StandardError() * float64(count))
+}
+
+func TestHyperLogLog(t *testing.T) {
+	keys := hashtables.StringKeys(hashtables.DJB2)
+	for _, precision := range []int{hashtables.MinLogLogPrecision, 10, 14} {
+		for _, count := range []int{10, 1000, 100000} {
+			counter, err := hashtables.NewHyperLogLog(precision, keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Adding a key again doesn't change the count.
+			for i := 0; i < count; i++ {
+				counter.Add(fmt.Sprint("key", i))
+				counter.Add(fmt.Sprint("key", i/2))
+			}
+			if off := logLogErrors(counter, count); off > 4 {
+				t.Errorf("precision %d: Count() = %d for %d keys, %.1f standard errors off",
+					precision, counter.Count(), count, off)
+			}
+		}
+	}
+
+	empty, _ := hashtables.NewHyperLogLog(10, keys)
+	if empty.Count() != 0 {
+		t.Errorf("an empty HyperLogLog counts %d", empty.Count())
+	}
+	for _, precision := range []int{hashtables.MinLogLogPrecision - 1, hashtables.MaxLogLogPrecision + 1} {
+		if _, err := hashtables.NewHyperLogLog(precision, keys); err == nil {
+			t.Errorf("built a HyperLogLog with precision %d", precision)
+		}
+	}
+	if precision := hashtables.LogLogPrecision(0.01); precision != 14 {
+		t.Errorf("LogLogPrecision(0.01) = %d, want 14", precision)
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	keys := hashtables.StringKeys(nil)
+	a, _ := hashtables.NewHyperLogLog(12, keys)
+	b, _ := hashtables.NewHyperLogLog(12, keys)
+	for i := 0; i < 30000; i++ {
+		a.Add(fmt.Sprint("key", i))
+		b.Add(fmt.Sprint("key", i+20000)) // 10000 keys overlap.
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if off := logLogErrors(a, 50000); off > 4 {
+		t.Errorf("the merge counts %d of 50000 keys, %.1f standard errors off", a.Count(), off)
+	}
+	coarse, _ := hashtables.NewHyperLogLog(10, keys)
+	if a.Merge(coarse) == nil {
+		t.Error("merged HyperLogLogs of different precisions")
+	}
+	jenkins, _ := hashtables.NewHyperLogLog(12, hashtables.StringKeys(hashtables.Jenkins))
+	if a.Merge(jenkins) == nil {
+		t.Error("merged HyperLogLogs with different KeyHashers")
+	}
+
+	// A loaded HyperLogLog gives the same count and keeps counting the same way.
+	data, _ := a.MarshalBinary()
+	loaded, err := hashtables.UnmarshalHyperLogLog(data, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Count() != a.Count() || loaded.Precision() != 12 {
+		t.Errorf("loaded count %d at precision %d, want %d at 12", loaded.Count(), loaded.Precision(), a.Count())
+	}
+	for i := 0; i < 1000; i++ {
+		loaded.Add(fmt.Sprint("new", i))
+		a.Add(fmt.Sprint("new", i))
+	}
+	if loaded.Count() != a.Count() {
+		t.Errorf("the loaded HyperLogLog counts %d, want %d", loaded.Count(), a.Count())
+	}
+	for _, bad := range [][]byte{nil, data[:4], data[:len(data)-1], {'H', 'L', 'L', 1, 30}} {
+		if _, err := hashtables.UnmarshalHyperLogLog(bad, keys); err == nil {
+			t.Errorf("unmarshaled %v", bad[:min(len(bad), 5)])
+		}
+	}
+}
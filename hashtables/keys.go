@@ -0,0 +1,202 @@
+package hashtables
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// KeyHasher hashes and compares a table's keys. Keys that are Equal must have the same Hash.
+// Hash may return any int. The tables reduce it to a slot themselves.
+// The constructors here return pointers, so sets can tell when they share a KeyHasher.
+type KeyHasher[K any] interface {
+	Hash(key K) int
+	Equal(a K, b K) bool
+}
+
+// keyHasher is a KeyHasher made from a pair of functions.
+type keyHasher[K any] struct {
+	hash  func(key K) int
+	equal func(a K, b K) bool
+}
+
+func (keys *keyHasher[K]) Hash(key K) int {
+	return keys.hash(key)
+}
+
+func (keys *keyHasher[K]) Equal(a K, b K) bool {
+	return keys.equal(a, b)
+}
+
+// KeyOrderer is a KeyHasher that can also put keys in order. Compare returns a negative
+// number, zero or a positive number as a sorts before, equal to or after b, and must return
+// zero exactly when Equal is true. Tree buckets use the order to find keys whose full hash
+// codes collide in logarithmic time instead of scanning them.
+type KeyOrderer[K any] interface {
+	KeyHasher[K]
+	Compare(a K, b K) int
+}
+
+// orderedKeyHasher is a keyHasher with an order.
+type orderedKeyHasher[K any] struct {
+	keyHasher[K]
+	compare func(a K, b K) int
+}
+
+func (keys *orderedKeyHasher[K]) Compare(a K, b K) int {
+	return keys.compare(a, b)
+}
+
+// Return the KeyHasher's order, or nil if it doesn't have one.
+func keyOrder[K any](keys KeyHasher[K]) func(a K, b K) int {
+	if orderer, ok := keys.(KeyOrderer[K]); ok {
+		return orderer.Compare
+	}
+	return nil
+}
+
+// NewKeyHasher pairs a hash function with an equality function, for keys such as
+// structs with fields that should be ignored or compared loosely.
+func NewKeyHasher[K any](hash func(key K) int, equal func(a K, b K) bool) KeyHasher[K] {
+	return &keyHasher[K]{hash: hash, equal: equal}
+}
+
+// ComparableKeys hashes comparable keys, such as composite struct keys, and compares them with ==.
+// The keys have no order, so tree buckets scan keys whose full hash codes collide.
+func ComparableKeys[K comparable](hash func(key K) int) KeyHasher[K] {
+	return &keyHasher[K]{hash: hash, equal: func(a K, b K) bool { return a == b }}
+}
+
+// OrderedKeys hashes keys such as numbers and strings, and compares and orders them with
+// == and <.
+func OrderedKeys[K cmp.Ordered](hash func(key K) int) KeyHasher[K] {
+	return &orderedKeyHasher[K]{
+		keyHasher: keyHasher[K]{hash: hash, equal: func(a K, b K) bool { return a == b }},
+		compare:   cmp.Compare[K],
+	}
+}
+
+// StringKeys compares strings exactly, like the liveProject tables. A nil hash means DJB2.
+func StringKeys(hash HashFunc) KeyHasher[string] {
+	if hash == nil {
+		hash = DJB2
+	}
+	return OrderedKeys(hash)
+}
+
+// BytesKeys compares byte slices by content. A nil hash means DJB2.
+func BytesKeys(hash HashFunc) KeyHasher[[]byte] {
+	if hash == nil {
+		hash = DJB2
+	}
+	return &orderedKeyHasher[[]byte]{
+		keyHasher: keyHasher[[]byte]{
+			hash:  func(key []byte) int { return hash(string(key)) },
+			equal: bytes.Equal,
+		},
+		compare: bytes.Compare,
+	}
+}
+
+// NormalizedKeys treats strings as equal when they normalize to the same string.
+// The table stores each key as it was first set. A nil hash means DJB2.
+func NormalizedKeys(hash HashFunc, normalize func(key string) string) KeyHasher[string] {
+	if hash == nil {
+		hash = DJB2
+	}
+	return &orderedKeyHasher[string]{
+		keyHasher: keyHasher[string]{
+			hash: func(key string) int { return hash(normalize(key)) },
+			equal: func(a string, b string) bool {
+				return a == b || normalize(a) == normalize(b)
+			},
+		},
+		compare: func(a string, b string) int {
+			if a == b {
+				return 0
+			}
+			return strings.Compare(normalize(a), normalize(b))
+		},
+	}
+}
+
+// CaseInsensitiveKeys treats strings that differ only in case, like "ann archer"
+// and "Ann Archer", as the same key.
+func CaseInsensitiveKeys(hash HashFunc) KeyHasher[string] {
+	return NormalizedKeys(hash, FoldCase)
+}
+
+// NFCKeys treats strings with the same Unicode NFC form as the same key, so a name typed
+// with a precomposed "é" matches one typed as "e" plus a combining accent.
+func NFCKeys(hash HashFunc) KeyHasher[string] {
+	return NormalizedKeys(hash, norm.NFC.String)
+}
+
+// FoldCase maps each rune to the smallest rune it is case-folded with, so strings
+// that strings.EqualFold considers equal fold to the same string.
+func FoldCase(value string) string {
+	return strings.Map(func(r rune) rune {
+		folded := r
+		for other := unicode.SimpleFold(r); other != r; other = unicode.SimpleFold(other) {
+			if other < folded {
+				folded = other
+			}
+		}
+		return folded
+	}, value)
+}
+
+// KeyModes holds the named ways of comparing names that tools can choose from.
+var KeyModes = map[string]func(hash HashFunc) KeyHasher[string]{
+	"exact": StringKeys,
+	"fold":  CaseInsensitiveKeys,
+	"nfc":   NFCKeys,
+	"nfc-fold": func(hash HashFunc) KeyHasher[string] {
+		return NormalizedKeys(hash, func(key string) string { return FoldCase(norm.NFC.String(key)) })
+	},
+}
+
+// Return the names of the registered key modes in sorted order.
+func KeyModeNames() []string {
+	names := make([]string, 0, len(KeyModes))
+	for name := range KeyModes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Return the registered key mode with this name.
+func LookupKeyMode(name string) (func(hash HashFunc) KeyHasher[string], error) {
+	mode, ok := KeyModes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown key mode %q (have %v)", name, KeyModeNames())
+	}
+	return mode, nil
+}
+
+// Return true if a and b are the same KeyHasher, so their hash codes agree.
+func sameKeyHasher[K any](a, b KeyHasher[K]) bool {
+	typeA, typeB := reflect.TypeOf(a), reflect.TypeOf(b)
+	if typeA != typeB || typeA == nil || !typeA.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// Format a key or value for dumps and events.
+func format[T any](value T) string {
+	switch value := any(value).(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,113 @@
+package hashtables_test
+
+import (
+	"strings"
+	"testing"
+
+	"hashtables"
+)
+
+func TestFoldCase(t *testing.T) {
+	words := []string{"ann archer", "ANN ARCHER", "Ann Archer", "Kelvin", "\u212aelvin", "kelvin",
+		"straße", "STRASSE", "ǅemal", "ǆemal", "Σίσυφος", "ΣΊΣΥΦΟΣ", "σίσυφοσ", "Bob"}
+	for _, a := range words {
+		for _, b := range words {
+			if folded := hashtables.FoldCase(a) == hashtables.FoldCase(b); folded != strings.EqualFold(a, b) {
+				t.Errorf("FoldCase says %q and %q are equal: %v, but EqualFold says %v",
+					a, b, folded, !folded)
+			}
+		}
+	}
+}
+
+func TestKeyModes(t *testing.T) {
+	const (
+		precomposed = "Ren\u00e9e"  // é as one rune.
+		decomposed  = "Rene\u0301e" // e and a combining accent.
+	)
+	for _, test := range []struct {
+		mode    string
+		set     string
+		lookups map[string]bool
+	}{
+		{"exact", "Ann Archer", map[string]bool{"Ann Archer": true, "ann archer": false}},
+		{"fold", "Ann Archer", map[string]bool{"ANN ARCHER": true, "ann archer": true, "Ann Archers": false}},
+		{"nfc", precomposed, map[string]bool{decomposed: true, "RENÉE": false, "Renee": false}},
+		{"nfc-fold", precomposed, map[string]bool{decomposed: true, strings.ToUpper(decomposed): true, "Renee": false}},
+	} {
+		mode, err := hashtables.LookupKeyMode(test.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, strategy := range hashtables.StrategyNames() {
+			table, err := hashtables.NewTableWithKeys(strategy, 8, mode(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			table.Set(test.set, "1")
+			for name, want := range test.lookups {
+				if table.Contains(name) != want {
+					t.Errorf("%s/%s: Contains(%q) = %v", test.mode, strategy, name, !want)
+				}
+			}
+
+			// Setting an equal key changes the value but keeps the key as first set.
+			for name, equal := range test.lookups {
+				if equal {
+					table.Set(name, "2")
+				}
+			}
+			if keys := rangeNames(table); len(keys) != 1 || !keys[test.set] || table.Get(test.set) != "2" {
+				t.Errorf("%s/%s: keys %v after setting equal keys", test.mode, strategy, keys)
+			}
+		}
+	}
+	if _, err := hashtables.LookupKeyMode("bogus"); err == nil {
+		t.Error("found a bogus key mode")
+	}
+}
+
+func TestKeyHashers(t *testing.T) {
+	type point struct{ x, y int }
+	points := hashtables.NewLinearProbingMap[point, string](8,
+		hashtables.ComparableKeys(func(p point) int { return p.x*31 + p.y }))
+	points.Set(point{1, 2}, "a")
+	points.Set(point{2, 1}, "b")
+	if points.Get(point{1, 2}) != "a" || points.Get(point{2, 1}) != "b" || points.Contains(point{1, 1}) {
+		t.Error("struct keys are mixed up")
+	}
+
+	// Byte slices are compared by content, not identity.
+	blobs := hashtables.NewChainingMap[[]byte, int](8, hashtables.BytesKeys(nil))
+	blobs.Set([]byte("Ann"), 1)
+	if value, ok := blobs.Lookup([]byte("Ann")); !ok || value != 1 {
+		t.Errorf("Lookup(Ann) = %d, %v", value, ok)
+	}
+
+	// A custom hasher can ignore part of the key.
+	prefix := hashtables.NewKeyHasher(func(key string) int { return hashtables.DJB2(key[:3]) },
+		func(a string, b string) bool { return a[:3] == b[:3] })
+	short := hashtables.NewDoubleHashMap[string, int](8, prefix, nil)
+	short.Set("Annabel", 1)
+	short.Set("Annette", 2)
+	if short.Len() != 1 || short.Get("Ann") != 2 {
+		t.Errorf("Len = %d and Get(Ann) = %d with a prefix hasher", short.Len(), short.Get("Ann"))
+	}
+
+	// An order agrees with equality.
+	for _, keys := range []hashtables.KeyHasher[string]{
+		hashtables.StringKeys(nil), hashtables.CaseInsensitiveKeys(nil), hashtables.NFCKeys(nil),
+	} {
+		orderer, ok := keys.(hashtables.KeyOrderer[string])
+		if !ok {
+			t.Fatalf("%T is not a KeyOrderer", keys)
+		}
+		for _, pair := range [][2]string{{"Ann", "Ann"}, {"Ann", "ANN"}, {"Ann", "Bob"}, {"Bob", "Ann"}} {
+			a, b := pair[0], pair[1]
+			if (orderer.Compare(a, b) == 0) != keys.Equal(a, b) ||
+				orderer.Compare(a, b) != -orderer.Compare(b, a) {
+				t.Errorf("%T orders %q and %q inconsistently", keys, a, b)
+			}
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package hashtables
+
+// LinearProbingMap probes consecutive slots after the key's home slot.
+type LinearProbingMap[K any, V any] struct {
+	openAddressing[K, V]
+}
+
+// LinearProbingHashTable is the liveProject's linear probing table of names and phone numbers.
+type LinearProbingHashTable = LinearProbingMap[string, string]
+
+// NewLinearProbingMap Initialize a LinearProbingMap and return a pointer to it.
+func NewLinearProbingMap[K any, V any](capacity int, keys KeyHasher[K]) *LinearProbingMap[K, V] {
+	table := &LinearProbingMap[K, V]{
+		openAddressing: newOpenAddressing[K, V](capacity, keys, nil, linearIndex),
+	}
+	table.exhaustive = true
+	return table
+}
+
+// NewLinearProbingHashTable Initialize a LinearProbingHashTable and return a pointer to it.
+func NewLinearProbingHashTable(capacity int, hash HashFunc) *LinearProbingHashTable {
+	return NewLinearProbingMap[string, string](capacity, StringKeys(hash))
+}
+
+// linearIndex returns the index of the linear probe sequence.
+func linearIndex(hash int, step int, i int, capacity int) int {
+	return (hash + i) % capacity
+}
@@ -0,0 +1,113 @@
+package hashtables_test
+
+import (
+	"fmt"
+	"testing"
+
+	"hashtables"
+)
+
+// Fill a table of each strategy with n names and start migrating it to a larger
+// capacity, slotsPerOp old slots or buckets per operation.
+func startMigration(t *testing.T, strategy string, n int, slotsPerOp int) hashtables.Table {
+	t.Helper()
+	table, err := hashtables.NewTable(strategy, 16, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		table.Set(fmt.Sprintf("name%d", i), fmt.Sprint(i))
+	}
+	table.SetIncrementalResize(slotsPerOp)
+	table.Resize(37)
+	return table
+}
+
+func TestMigrationProgress(t *testing.T) {
+	for _, strategy := range hashtables.StrategyNames() {
+		t.Run(strategy, func(t *testing.T) {
+			table := startMigration(t, strategy, 12, 3)
+			migration := table.Stats().Migration
+			if migration == nil || migration.OldCapacity != 16 || migration.Moved != 0 || migration.Left != 12 {
+				t.Fatalf("migration %+v after Resize", migration)
+			}
+			if table.Capacity() != 37 || table.Len() != 12 {
+				t.Errorf("capacity %d and Len %d after Resize", table.Capacity(), table.Len())
+			}
+
+			// Each operation moves the next 3 old slots, until none are left.
+			for ops := 1; ; ops++ {
+				table.Get("name0")
+				migration := table.Stats().Migration
+				if migration == nil {
+					if ops != 16/3+1 {
+						t.Errorf("the migration finished after %d operations", ops)
+					}
+					break
+				}
+				if migration.Moved != 3*ops || migration.Left > 12 {
+					t.Fatalf("migration %+v after %d operations", migration, ops)
+				}
+			}
+			if table.Len() != 12 {
+				t.Errorf("Len = %d after the migration", table.Len())
+			}
+		})
+	}
+}
+
+// Until the migration finishes, every operation has to look in both the old and the
+// new slots.
+func TestMigrationConsultsBoth(t *testing.T) {
+	for _, strategy := range hashtables.StrategyNames() {
+		t.Run(strategy, func(t *testing.T) {
+			table := startMigration(t, strategy, 12, 1)
+			check := func(name string, want string) {
+				t.Helper()
+				if phone, ok := table.Lookup(name); want == "" && ok {
+					t.Errorf("found %s = %q", name, phone)
+				} else if want != "" && phone != want {
+					t.Errorf("Lookup(%s) = %q, %v, want %q", name, phone, ok, want)
+				}
+			}
+
+			// Change, delete and add keys while most are still in the old slots.
+			table.Set("name3", "changed")
+			table.Delete("name7")
+			table.Set("new", "added")
+			if migration := table.Stats().Migration; migration == nil || migration.Left == 0 {
+				t.Fatalf("migration %+v, want entries left in the old slots", migration)
+			}
+			check("name3", "changed")
+			check("name7", "")
+			check("new", "added")
+			for i := 0; i < 12; i++ {
+				if i != 3 && i != 7 {
+					check(fmt.Sprintf("name%d", i), fmt.Sprint(i))
+				}
+			}
+			if names := rangeNames(table); len(names) != 12 || names["name7"] {
+				t.Errorf("Range visited %d names", len(names))
+			}
+			if table.Len() != 12 {
+				t.Errorf("Len = %d during the migration", table.Len())
+			}
+
+			// Resizing again finishes the migration first.
+			table.Resize(53)
+			if migration := table.Stats().Migration; migration == nil || migration.OldCapacity != 37 ||
+				migration.Left != 12 {
+				t.Errorf("migration %+v after resizing again", migration)
+			}
+			for table.Stats().Migration != nil {
+				table.Get("name0")
+			}
+			check("name3", "changed")
+			check("name7", "")
+			check("new", "added")
+			if table.Capacity() != 53 || table.Len() != 12 {
+				t.Errorf("capacity %d and Len %d after the migration", table.Capacity(), table.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package hashtables
+
+import (
+	"fmt"
+	"io"
+)
+
+// ChainingMultiMap maps each key to a list of values, like an employee with several
+// phone numbers. It keeps each key's values together in one entry of a chaining table's bucket.
+type ChainingMultiMap[K any, V comparable] struct {
+	table     *ChainingMap[K, []V]
+	numValues int
+}
+
+// PhoneBook maps names to all of their phone numbers.
+type PhoneBook = ChainingMultiMap[string, string]
+
+// Initialize a ChainingMultiMap and return a pointer to it.
+func NewChainingMultiMap[K any, V comparable](numBuckets int, keys KeyHasher[K]) *ChainingMultiMap[K, V] {
+	return &ChainingMultiMap[K, V]{table: NewChainingMap[K, []V](numBuckets, keys)}
+}
+
+// Initialize a PhoneBook and return a pointer to it.
+func NewPhoneBook(numBuckets int, hash HashFunc) *PhoneBook {
+	return NewChainingMultiMap[string, string](numBuckets, StringKeys(hash))
+}
+
+// Return the number of distinct keys.
+func (multiMap *ChainingMultiMap[K, V]) NumKeys() int {
+	return multiMap.table.Len()
+}
+
+// Return the number of values across all keys.
+func (multiMap *ChainingMultiMap[K, V]) NumValues() int {
+	return multiMap.numValues
+}
+
+// Return the number of buckets.
+func (multiMap *ChainingMultiMap[K, V]) Capacity() int {
+	return multiMap.table.Capacity()
+}
+
+// Return the key's entry or nil.
+func (multiMap *ChainingMultiMap[K, V]) entry(key K) *Entry[K, []V] {
+	return multiMap.table.entry(key)
+}
+
+// Add a value to the key's list. A key can hold the same value more than once.
+func (multiMap *ChainingMultiMap[K, V]) Add(key K, value V) {
+	if entry := multiMap.entry(key); entry != nil {
+		entry.Value = append(entry.Value, value)
+	} else {
+		multiMap.table.Set(key, []V{value})
+	}
+	multiMap.numValues++
+}
+
+// Return a copy of the key's values in the order they were added, or nil if the key is not present.
+func (multiMap *ChainingMultiMap[K, V]) GetAll(key K) []V {
+	entry := multiMap.entry(key)
+	if entry == nil {
+		return nil
+	}
+	return append([]V(nil), entry.Value...)
+}
+
+// Return true if the key has at least one value.
+func (multiMap *ChainingMultiMap[K, V]) Contains(key K) bool {
+	return multiMap.entry(key) != nil
+}
+
+// Return true if the key holds this value.
+func (multiMap *ChainingMultiMap[K, V]) ContainsValue(key K, value V) bool {
+	entry := multiMap.entry(key)
+	if entry == nil {
+		return false
+	}
+	for _, v := range entry.Value {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove the first copy of this value from the key's list.
+// When the last value goes, the key goes too. Return true if the value was present.
+func (multiMap *ChainingMultiMap[K, V]) RemoveValue(key K, value V) bool {
+	entry := multiMap.entry(key)
+	if entry == nil {
+		return false
+	}
+	for i, v := range entry.Value {
+		if v == value {
+			if len(entry.Value) == 1 {
+				multiMap.table.Delete(key)
+			} else {
+				entry.Value = append(entry.Value[:i], entry.Value[i+1:]...)
+			}
+			multiMap.numValues--
+			return true
+		}
+	}
+	return false
+}
+
+// Remove the key and all of its values. Return the number of values removed.
+func (multiMap *ChainingMultiMap[K, V]) RemoveAll(key K) int {
+	entry := multiMap.entry(key)
+	if entry == nil {
+		return 0
+	}
+	removed := len(entry.Value)
+	multiMap.table.Delete(key)
+	multiMap.numValues -= removed
+	return removed
+}
+
+// Call fn for each key with all of its values, bucket by bucket, until fn returns false.
+// The values slice belongs to the multimap, so fn must not keep or change it.
+func (multiMap *ChainingMultiMap[K, V]) Range(fn func(key K, values []V) bool) {
+	multiMap.table.Range(fn)
+}
+
+// Rebuild the multimap with a new number of buckets.
+func (multiMap *ChainingMultiMap[K, V]) Resize(numBuckets int) {
+	multiMap.table.Resize(numBuckets)
+}
+
+// Display each bucket's keys with their values.
+func (multiMap *ChainingMultiMap[K, V]) Dump(w io.Writer) {
+	for i := range multiMap.table.buckets {
+		fmt.Fprintf(w, "Bucket %d:\n", i)
+		multiMap.table.eachEntry(i, func(entry *Entry[K, []V]) bool {
+			fmt.Fprintf(w, "\t%s:", format(entry.Key))
+			for _, value := range entry.Value {
+				fmt.Fprintf(w, " %s", format(value))
+			}
+			fmt.Fprintln(w)
+			return true
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package hashtables_test
+
+import (
+	"reflect"
+	"testing"
+
+	"hashtables"
+)
+
+func TestMultiMapOrder(t *testing.T) {
+	book := hashtables.NewPhoneBook(4, nil)
+	book.Add("Ann", "555-1")
+	book.Add("Bob", "555-2")
+	book.Add("Ann", "555-3")
+	book.Add("Ann", "555-1") // A value can repeat.
+	book.Add("Ann", "555-4")
+
+	if got, want := book.GetAll("Ann"), []string{"555-1", "555-3", "555-1", "555-4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAll(Ann) = %v, want %v", got, want)
+	}
+	if book.NumKeys() != 2 || book.NumValues() != 5 {
+		t.Errorf("%d keys and %d values", book.NumKeys(), book.NumValues())
+	}
+
+	// GetAll returns a copy.
+	book.GetAll("Ann")[0] = "changed"
+	if book.GetAll("Ann")[0] != "555-1" {
+		t.Error("changing GetAll's result changed the multimap")
+	}
+
+	// RemoveValue removes the first copy and keeps the others in order.
+	if !book.RemoveValue("Ann", "555-1") || book.RemoveValue("Ann", "555-9") || book.RemoveValue("Cid", "555-1") {
+		t.Error("RemoveValue reported the wrong values")
+	}
+	if got, want := book.GetAll("Ann"), []string{"555-3", "555-1", "555-4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAll(Ann) = %v after RemoveValue, want %v", got, want)
+	}
+	if !book.ContainsValue("Ann", "555-1") || book.ContainsValue("Bob", "555-1") {
+		t.Error("ContainsValue is wrong")
+	}
+
+	// The order survives a resize.
+	book.Resize(13)
+	if got, want := book.GetAll("Ann"), []string{"555-3", "555-1", "555-4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAll(Ann) = %v after Resize, want %v", got, want)
+	}
+
+	// Removing the last value removes the key.
+	if !book.RemoveValue("Bob", "555-2") || book.Contains("Bob") || book.GetAll("Bob") != nil {
+		t.Error("Bob is still there without values")
+	}
+	if removed := book.RemoveAll("Ann"); removed != 3 || book.Contains("Ann") {
+		t.Errorf("RemoveAll(Ann) removed %d", removed)
+	}
+	if book.NumKeys() != 0 || book.NumValues() != 0 || book.RemoveAll("Ann") != 0 {
+		t.Errorf("%d keys and %d values left", book.NumKeys(), book.NumValues())
+	}
+}
+
+func TestMultiMapRange(t *testing.T) {
+	multiMap := hashtables.NewChainingMultiMap[string, int](3, hashtables.CaseInsensitiveKeys(nil))
+	multiMap.Add("Ann", 1)
+	multiMap.Add("ANN", 2)
+	multiMap.Add("Bob", 3)
+	got := make(map[string][]int)
+	multiMap.Range(func(key string, values []int) bool {
+		got[key] = append([]int(nil), values...)
+		return true
+	})
+	if want := map[string][]int{"Ann": {1, 2}, "Bob": {3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Range visited %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,665 @@
+package hashtables
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// openAddressing holds the slots and operations shared by the open addressing strategies.
+// Each strategy supplies its own probe sequence.
+type openAddressing[K any, V any] struct {
+	capacity int
+	entries  []*Entry[K, V]
+	count    int
+	keys     KeyHasher[K]
+	stepHash func(key K) int // Only double hashing uses a second hash function.
+	sequence func(hash int, step int, i int, capacity int) int
+	recorder Recorder
+	clock    Clock
+
+	// True if the sequence reaches every slot within capacity probes.
+	// Quadratic probing and double hashing with a step that shares a factor
+	// with the capacity can cycle through only some of the slots.
+	exhaustive bool
+
+	// During an incremental resize, old holds the previous slots and moved counts
+	// how many of them have been migrated. migrateSlots is how many old slots each
+	// operation migrates, or 0 if Resize rebuilds the table at once.
+	old          *openAddressing[K, V]
+	moved        int
+	migrateSlots int
+}
+
+func newOpenAddressing[K any, V any](capacity int, keys KeyHasher[K], stepHash func(key K) int,
+	sequence func(hash int, step int, i int, capacity int) int) openAddressing[K, V] {
+	return openAddressing[K, V]{
+		capacity: capacity,
+		// Allocate the slice of entries
+		entries:  make([]*Entry[K, V], capacity),
+		keys:     keys,
+		stepHash: stepHash,
+		sequence: sequence,
+	}
+}
+
+// Return the key's home slot and, for double hashing, its step size.
+func (hashTable *openAddressing[K, V]) home(key K) (int, int) {
+	hash := reduce(hashTable.keys.Hash(key), hashTable.capacity)
+	step := 0
+	if hashTable.stepHash != nil {
+		step = reduce(hashTable.stepHash(key), hashTable.capacity)
+
+		// A step of 0 would probe the home slot forever.
+		if step == 0 {
+			step = 1
+		}
+	}
+	return hash, step
+}
+
+// Return the i-th slot in the probe sequence. After capacity probes, a sequence
+// that may have skipped slots continues linearly so every slot is eventually tried.
+func (hashTable *openAddressing[K, V]) probeIndex(hash int, step int, i int) int {
+	if i < hashTable.capacity {
+		return hashTable.sequence(hash, step, i, hashTable.capacity)
+	}
+	return (hash + i) % hashTable.capacity
+}
+
+// Return the number of probes it takes to be sure every slot has been tried.
+func (hashTable *openAddressing[K, V]) probeLimit() int {
+	if hashTable.exhaustive {
+		return hashTable.capacity
+	}
+	return 2 * hashTable.capacity
+}
+
+// Return the number of live entries.
+func (hashTable *openAddressing[K, V]) Len() int {
+	if hashTable.old != nil {
+		return hashTable.count + hashTable.old.count
+	}
+	return hashTable.count
+}
+
+// Return the number of slots.
+func (hashTable *openAddressing[K, V]) Capacity() int {
+	return hashTable.capacity
+}
+
+// Return the key's index or where it would be if present and
+// the probe sequence length.
+// If the key is not present and the table is full, return -1 for the index.
+// During an incremental resize, Find only searches the new slots, so inspecting
+// the table doesn't migrate anything.
+func (hashTable *openAddressing[K, V]) Find(key K) (int, int) {
+	return hashTable.searchSlots(key, nil)
+}
+
+// Return the indices of the slots that Find visits for this key, in order.
+func (hashTable *openAddressing[K, V]) ProbePath(key K) []int {
+	var path []int
+	hashTable.searchSlots(key, func(kind EventKind, index int, step int) {
+		if kind == EventVisit {
+			path = append(path, index)
+		}
+	})
+	return path
+}
+
+// Follow the key's probe sequence like Find. If observe is not nil, call it
+// for each slot visited and for the first deleted slot remembered.
+// During an incremental resize, a key that is still in the old slots
+// is moved to the slot the search found for it.
+func (hashTable *openAddressing[K, V]) search(key K,
+	observe func(kind EventKind, index int, step int)) (int, int) {
+	index, probeLength := hashTable.searchSlots(key, observe)
+	if hashTable.old == nil || index < 0 || hashTable.live(index) != nil {
+		return index, probeLength
+	}
+	oldIndex, _ := hashTable.old.searchSlots(key, nil)
+	if hashTable.old.live(oldIndex) != nil {
+		hashTable.moveFromOld(oldIndex, index)
+	}
+	return index, probeLength
+}
+
+// Follow the key's probe sequence through this table's own slots.
+func (hashTable *openAddressing[K, V]) searchSlots(key K,
+	observe func(kind EventKind, index int, step int)) (int, int) {
+	hash, step := hashTable.home(key)
+
+	// This will be the index of the first deleted item we come across (if we find one).
+	deletedIndex := -1
+
+	// Follow the probe sequence
+	for i := 0; i < hashTable.probeLimit(); i++ {
+		index := hashTable.probeIndex(hash, step, i)
+		if observe != nil {
+			observe(EventVisit, index, i)
+		}
+
+		// If this spot is empty, then the target is not in the table.
+		// Return the first deleted spot if we saw one so it can be reused.
+		if hashTable.entries[index] == nil {
+			if deletedIndex >= 0 {
+				return deletedIndex, i + 1
+			}
+			return index, i + 1
+		}
+
+		// Lazily turn an entry whose time is up into a tombstone.
+		if hashTable.entries[index].expires != 0 && !hashTable.entries[index].deleted {
+			hashTable.expire(index)
+		}
+
+		// Remember the first deleted spot. Otherwise, if this spot contains the target, return its index.
+		if hashTable.entries[index].deleted {
+			if deletedIndex < 0 {
+				deletedIndex = index
+				if observe != nil {
+					observe(EventTombstone, index, i)
+				}
+			}
+		} else if hashTable.keys.Equal(hashTable.entries[index].Key, key) {
+			return index, i + 1
+		}
+	}
+
+	// The key is not in the table and there are no empty spots.
+	// Reuse a deleted spot if we found one. Otherwise return -1.
+	return deletedIndex, hashTable.probeLimit()
+}
+
+// Record events with this recorder. A nil recorder stops recording.
+func (hashTable *openAddressing[K, V]) SetRecorder(recorder Recorder) {
+	hashTable.recorder = recorder
+}
+
+// Record an event if there is a recorder.
+func (hashTable *openAddressing[K, V]) record(event Event) {
+	if hashTable.recorder != nil {
+		hashTable.recorder(event)
+	}
+}
+
+// Record an event about an entry if there is a recorder.
+// The key and value are only formatted when something is recording.
+func (hashTable *openAddressing[K, V]) recordEntry(kind EventKind, key K, value V, slot int) {
+	if hashTable.recorder != nil {
+		hashTable.recorder(Event{Kind: kind, Key: format(key), Value: format(value), Slot: slot})
+	}
+}
+
+// Record an event about a key if there is a recorder.
+func (hashTable *openAddressing[K, V]) recordKey(kind EventKind, key K, slot int) {
+	if hashTable.recorder != nil {
+		hashTable.recorder(Event{Kind: kind, Key: format(key), Slot: slot})
+	}
+}
+
+// Start recording an operation on this key and return the observer
+// that records its probe sequence, or nil if nothing is recording.
+func (hashTable *openAddressing[K, V]) trace(kind EventKind, key K, value string) func(EventKind, int, int) {
+	if hashTable.recorder == nil {
+		return nil
+	}
+	return hashTable.traceEvent(Event{Kind: kind, Key: format(key), Value: value})
+}
+
+// Record the event that starts an operation and return the observer that records its steps.
+func (hashTable *openAddressing[K, V]) traceEvent(start Event) func(EventKind, int, int) {
+	start.Slot = -1
+	hashTable.record(start)
+	return func(kind EventKind, index int, step int) {
+		hashTable.record(Event{Kind: kind, Key: start.Key, Slot: index, Step: step})
+	}
+}
+
+// Start recording a set of this key and value, or return nil if nothing is recording.
+func (hashTable *openAddressing[K, V]) traceSet(key K, value V, ttl time.Duration) func(EventKind, int, int) {
+	if hashTable.recorder == nil {
+		return nil
+	}
+	return hashTable.traceEvent(Event{Kind: EventSet, Key: format(key), Value: format(value), TTL: ttl})
+}
+
+// Set where the table gets the time for expiring entries. A nil clock means time.Now.
+func (hashTable *openAddressing[K, V]) SetClock(clock Clock) {
+	hashTable.clock = clock
+}
+
+// If the entry at this index has expired, mark it as deleted like Delete does.
+// Return true if it expired.
+func (hashTable *openAddressing[K, V]) expire(index int) bool {
+	entry := hashTable.entries[index]
+	if entry.deleted || !hashTable.clock.expired(entry.expires) {
+		return false
+	}
+	entry.deleted = true
+	hashTable.count--
+	hashTable.recordKey(EventExpire, entry.Key, index)
+	return true
+}
+
+// Turn every expired entry into a tombstone. Return how many there were.
+func (hashTable *openAddressing[K, V]) RemoveExpired() int {
+	removed := 0
+	for index, entry := range hashTable.entries {
+		if entry != nil && entry.expires != 0 && hashTable.expire(index) {
+			removed++
+		}
+	}
+	if hashTable.old != nil {
+		removed += hashTable.old.RemoveExpired()
+	}
+	return removed
+}
+
+// Add an item to the hash table.
+func (hashTable *openAddressing[K, V]) Set(key K, value V) {
+	hashTable.set(key, value, 0, 0)
+}
+
+// Add an item that expires once ttl has passed. A ttl of 0 or less means it never expires.
+func (hashTable *openAddressing[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	hashTable.set(key, value, hashTable.clock.expiry(ttl), ttl)
+}
+
+// Add an item that expires at this time in Unix nanoseconds, or never if expires is 0.
+// The ttl it was set with is only recorded, so a replay can set it again.
+func (hashTable *openAddressing[K, V]) set(key K, value V, expires int64, ttl time.Duration) {
+	hashTable.migrate(hashTable.migrateSlots)
+
+	// Call find to get the index where the key belongs
+	index, _ := hashTable.search(key, hashTable.traceSet(key, value, ttl))
+
+	// If the index is less than 0, the key is not in the table and the table is full.
+	// During an incremental resize, the new slots must also keep room for the old entries,
+	// not counting expired ones.
+	if index >= 0 && hashTable.live(index) == nil && hashTable.old != nil &&
+		hashTable.count+hashTable.old.count >= hashTable.capacity {
+		hashTable.RemoveExpired()
+	}
+	if index < 0 || hashTable.live(index) == nil && hashTable.old != nil &&
+		hashTable.count+hashTable.old.count >= hashTable.capacity {
+		hashTable.recordKey(EventFull, key, -1)
+		panic("Hash table is full")
+	}
+
+	// If the slice entry at the key's index is nil or deleted, create a new Entry struct
+	if hashTable.entries[index] == nil || hashTable.entries[index].deleted {
+		hashTable.entries[index] = &Entry[K, V]{Key: key, Value: value, expires: expires}
+		hashTable.count++
+		hashTable.recordEntry(EventClaim, key, value, index)
+	} else {
+		// Otherwise, find found the target key. Update its value.
+		hashTable.entries[index].Value = value
+		hashTable.entries[index].expires = expires
+		hashTable.recordEntry(EventUpdate, key, value, index)
+	}
+}
+
+// Return the live entry at this index or nil.
+func (hashTable *openAddressing[K, V]) live(index int) *Entry[K, V] {
+	if index < 0 || hashTable.entries[index] == nil || hashTable.entries[index].deleted {
+		return nil
+	}
+	return hashTable.entries[index]
+}
+
+// Return an item from the hash table, or the zero value if it is not present.
+func (hashTable *openAddressing[K, V]) Get(key K) V {
+	value, _ := hashTable.Lookup(key)
+	return value
+}
+
+// Return an item from the hash table and whether it was present.
+func (hashTable *openAddressing[K, V]) Lookup(key K) (V, bool) {
+	hashTable.migrate(hashTable.migrateSlots)
+	index, _ := hashTable.search(key, hashTable.trace(EventLookup, key, ""))
+	if entry := hashTable.live(index); entry != nil {
+		return entry.Value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Return true if the key is in the hash table.
+func (hashTable *openAddressing[K, V]) Contains(key K) bool {
+	hashTable.migrate(hashTable.migrateSlots)
+	index, _ := hashTable.search(key, hashTable.trace(EventLookup, key, ""))
+	return hashTable.live(index) != nil
+}
+
+// Delete an item from the hash table.
+func (hashTable *openAddressing[K, V]) Delete(key K) {
+	hashTable.migrate(hashTable.migrateSlots)
+	index, _ := hashTable.search(key, hashTable.trace(EventDelete, key, ""))
+
+	// If we found the Entry struct, mark it as deleted.
+	if entry := hashTable.live(index); entry != nil {
+		entry.deleted = true
+		hashTable.count--
+		hashTable.recordKey(EventRemove, key, index)
+	}
+}
+
+// Call fn for each live entry in slot order until fn returns false.
+func (hashTable *openAddressing[K, V]) Range(fn func(key K, value V) bool) {
+	for _, entry := range hashTable.entries {
+		if entry == nil || entry.deleted || hashTable.clock.expired(entry.expires) {
+			continue
+		}
+		if !fn(entry.Key, entry.Value) {
+			return
+		}
+	}
+	if hashTable.old != nil {
+		hashTable.old.Range(fn)
+	}
+}
+
+// Rebuild the table with a new capacity, dropping deleted and expired entries.
+// If the entries do not fit, Resize panics and leaves the table unchanged.
+// In incremental mode, Resize finishes any migration in progress, then starts
+// a new one instead of moving the entries at once.
+func (hashTable *openAddressing[K, V]) Resize(capacity int) {
+	hashTable.record(Event{Kind: EventResize, Slot: -1, Capacity: capacity, Migrate: hashTable.migrateSlots})
+	hashTable.FinishResize()
+
+	resized := newOpenAddressing[K, V](capacity, hashTable.keys, hashTable.stepHash, hashTable.sequence)
+	resized.exhaustive = hashTable.exhaustive
+	resized.clock = hashTable.clock
+	resized.migrateSlots = hashTable.migrateSlots
+	if hashTable.migrateSlots > 0 {
+		// Expired entries won't be migrated, so they don't need room.
+		hashTable.RemoveExpired()
+		if hashTable.count > capacity {
+			panic("Hash table is full")
+		}
+		old := *hashTable
+		old.recorder = nil
+		resized.old = &old
+		resized.recorder = hashTable.recorder
+		*hashTable = resized
+		return
+	}
+
+	var moves []Event
+	for i, entry := range hashTable.entries {
+		if entry != nil && !entry.deleted && !hashTable.clock.expired(entry.expires) {
+			resized.set(entry.Key, entry.Value, entry.expires, 0)
+			if hashTable.recorder != nil {
+				index, _ := resized.Find(entry.Key)
+				moves = append(moves, Event{Kind: EventMove, Key: format(entry.Key), From: i, Slot: index})
+			}
+		}
+	}
+	resized.recorder = hashTable.recorder
+	*hashTable = resized
+
+	// Only report the moves once every entry has fit.
+	for _, move := range moves {
+		hashTable.record(move)
+	}
+}
+
+// Make later resizes incremental, migrating this many old slots during each Set, Get,
+// Contains and Delete, the way Redis rehashes its dictionaries. Until the migration
+// is done, the table keeps both arrays and searches them both. A slotsPerOp of 0
+// makes Resize rebuild the table at once again.
+func (hashTable *openAddressing[K, V]) SetIncrementalResize(slotsPerOp int) {
+	hashTable.migrateSlots = max(0, slotsPerOp)
+}
+
+// Migrate every old slot that is left from an incremental resize.
+func (hashTable *openAddressing[K, V]) FinishResize() {
+	if hashTable.old != nil {
+		hashTable.migrate(hashTable.old.capacity - hashTable.moved)
+	}
+}
+
+// Migrate up to n old slots during an incremental resize.
+func (hashTable *openAddressing[K, V]) migrate(n int) {
+	for ; n > 0 && hashTable.old != nil; n-- {
+		oldIndex := hashTable.moved
+		if entry := hashTable.old.entries[oldIndex]; entry != nil && !entry.deleted && !hashTable.old.expire(oldIndex) {
+			index, _ := hashTable.searchSlots(entry.Key, nil)
+			hashTable.moveFromOld(oldIndex, index)
+		}
+		hashTable.moved++
+		if hashTable.moved == hashTable.old.capacity {
+			hashTable.old = nil
+			hashTable.moved = 0
+		}
+	}
+}
+
+// Move the live entry in an old slot to a free slot. The old slot becomes a tombstone
+// so the old probe sequences that pass through it still work.
+func (hashTable *openAddressing[K, V]) moveFromOld(oldIndex int, index int) {
+	entry := hashTable.old.entries[oldIndex]
+	hashTable.entries[index] = &Entry[K, V]{Key: entry.Key, Value: entry.Value, expires: entry.expires}
+	hashTable.count++
+	entry.deleted = true
+	hashTable.old.count--
+	if hashTable.recorder != nil {
+		hashTable.record(Event{Kind: EventMove, Key: format(entry.Key), From: oldIndex, Slot: index})
+	}
+}
+
+// Return the progress of an incremental resize, or nil if there is none.
+func (hashTable *openAddressing[K, V]) migration() *Migration {
+	if hashTable.old == nil {
+		return nil
+	}
+	return &Migration{OldCapacity: hashTable.old.capacity, Moved: hashTable.moved, Left: hashTable.old.count}
+}
+
+// Describe each slot for display.
+func (hashTable *openAddressing[K, V]) Slots() []Slot {
+	slots := make([]Slot, hashTable.capacity)
+	for i, entry := range hashTable.entries {
+		switch {
+		case entry == nil:
+			slots[i] = Slot{State: SlotEmpty}
+		case entry.deleted:
+			slots[i] = Slot{State: SlotDeleted}
+		default:
+			home, _ := hashTable.home(entry.Key)
+			slots[i] = Slot{State: SlotLive, Name: format(entry.Key), Home: home, Entries: 1}
+		}
+	}
+	return slots
+}
+
+// Display the hash table's contents.
+func (hashTable *openAddressing[K, V]) Dump(w io.Writer) {
+	for i, entry := range hashTable.entries {
+		if entry == nil {
+			fmt.Fprintf(w, "%d: ---\n", i)
+		} else if entry.deleted {
+			fmt.Fprintf(w, "%d: xxx\n", i)
+		} else {
+			fmt.Fprintf(w, "%d: %s\t%s\n", i, format(entry.Key), format(entry.Value))
+		}
+	}
+	if migration := hashTable.migration(); migration != nil {
+		migration.Dump(w)
+		hashTable.old.Dump(w)
+	}
+}
+
+// Make a display showing whether each array entry is nil.
+func (hashTable *openAddressing[K, V]) DumpConcise(w io.Writer) {
+	for i, entry := range hashTable.entries {
+		if entry == nil {
+			// This spot is empty.
+			fmt.Fprint(w, ".")
+		} else if entry.deleted {
+			// This spot is deleted.
+			fmt.Fprint(w, "x")
+		} else {
+			// Display this entry.
+			fmt.Fprint(w, "O")
+		}
+		if i%50 == 49 {
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintln(w)
+
+	// Show the old slots too, with the ones already migrated as '>'.
+	if migration := hashTable.migration(); migration != nil {
+		migration.Dump(w)
+		for i, entry := range hashTable.old.entries {
+			switch {
+			case i < hashTable.moved:
+				fmt.Fprint(w, ">")
+			case entry == nil:
+				fmt.Fprint(w, ".")
+			case entry.deleted:
+				fmt.Fprint(w, "x")
+			default:
+				fmt.Fprint(w, "O")
+			}
+			if i%50 == 49 {
+				fmt.Fprintln(w)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// Show this key's probe sequence.
+func (hashTable *openAddressing[K, V]) Probe(w io.Writer, key K) int {
+	hash, step := hashTable.home(key)
+	if hashTable.stepHash != nil {
+		fmt.Fprintf(w, "Probing %s (%d, %d)\n", format(key), hash, step)
+	} else {
+		fmt.Fprintf(w, "Probing %s (%d)\n", format(key), hash)
+	}
+
+	// Show each slot that Find visits.
+	index, _ := hashTable.searchSlots(key, func(kind EventKind, index int, step int) {
+		if kind != EventVisit {
+			return
+		}
+		fmt.Fprintf(w, "    %d: ", index)
+		if hashTable.entries[index] == nil {
+			fmt.Fprintf(w, "---\n")
+		} else if hashTable.entries[index].deleted {
+			fmt.Fprintf(w, "xxx\n")
+		} else {
+			fmt.Fprintf(w, "%s\n", format(hashTable.entries[index].Key))
+		}
+	})
+
+	switch {
+	case index < 0:
+		// There's nowhere to put a new entry.
+		fmt.Fprintf(w, "    Table is full\n")
+	case hashTable.entries[index] == nil:
+		fmt.Fprintf(w, "    Returning nil index %d\n", index)
+	case hashTable.entries[index].deleted:
+		fmt.Fprintf(w, "    Returning deleted index %d\n", index)
+	default:
+		fmt.Fprintf(w, "    Returning found index %d\n", index)
+	}
+	if hashTable.live(index) == nil && hashTable.old != nil {
+		if oldIndex, _ := hashTable.old.searchSlots(key, nil); hashTable.old.live(oldIndex) != nil {
+			fmt.Fprintf(w, "    Not migrated yet, old index %d\n", oldIndex)
+		}
+	}
+	return index
+}
+
+// Return the average probe sequence length for the items in the table.
+func (hashTable *openAddressing[K, V]) AveProbeSequenceLength() float32 {
+	totalLength := 0
+	numValues := 0
+	for _, entry := range hashTable.entries {
+		if entry != nil && !entry.deleted {
+			_, probeLength := hashTable.Find(entry.Key)
+			totalLength += probeLength
+			numValues++
+		}
+	}
+
+	// An empty table has no probe sequences to average.
+	if numValues == 0 {
+		return 0
+	}
+	return float32(totalLength) / float32(numValues)
+}
+
+// Collect the stats for the table.
+func (hashTable *openAddressing[K, V]) Stats() Stats {
+	stats := Stats{Capacity: hashTable.capacity}
+
+	// Count the slots and measure each live key's probe sequence.
+	var successful []int
+	steps := []int{0}
+	for index, entry := range hashTable.entries {
+		if entry == nil {
+			stats.Empty++
+			continue
+		}
+		if entry.deleted {
+			stats.Deleted++
+			continue
+		}
+		stats.Live++
+
+		_, probeLength := hashTable.Find(entry.Key)
+		successful = append(successful, probeLength)
+
+		home, step := hashTable.home(entry.Key)
+		displacement := (index - home + hashTable.capacity) % hashTable.capacity
+		if displacement > stats.MaxDisplacement {
+			stats.MaxDisplacement = displacement
+		}
+		if hashTable.stepHash != nil {
+			steps = append(steps, step)
+		}
+	}
+	stats.LoadFactor = float64(stats.Live) / float64(hashTable.capacity)
+	stats.Successful = newProbeStats(successful)
+
+	// A miss keeps probing until it reaches an empty spot. The probe sequence depends only
+	// on the home slot and step, so start a miss from each slot. For double hashing,
+	// pair each slot with the step of one of the table's keys.
+	if len(steps) > 1 {
+		steps = steps[1:]
+	} else if hashTable.stepHash != nil {
+		steps[0] = 1
+	}
+	var unsuccessful []int
+	for home := 0; home < hashTable.capacity; home++ {
+		step := steps[home%len(steps)]
+		probeLength := hashTable.probeLimit()
+		for i := 0; i < hashTable.probeLimit(); i++ {
+			if hashTable.entries[hashTable.probeIndex(home, step, i)] == nil {
+				probeLength = i + 1
+				break
+			}
+		}
+		unsuccessful = append(unsuccessful, probeLength)
+	}
+	stats.Unsuccessful = newProbeStats(unsuccessful)
+
+	stats.ClusterSizes, stats.MaxCluster = clusterSizes(hashTable.entries)
+	stats.Migration = hashTable.migration()
+	return stats
+}
+
+// Reduce a hash code to an index from 0 to n-1. Hash codes from a KeyHasher may be negative.
+func reduce(hash int, n int) int {
+	index := hash % n
+	if index < 0 {
+		index += n
+	}
+	return index
+}
@@ -0,0 +1,82 @@
+package hashtables
+
+import "fmt"
+
+// packedInts stores unsigned integers of a fixed bit width back to back.
+type packedInts struct {
+	width uint
+	words []uint64
+}
+
+func newPackedInts(n int, width uint) packedInts {
+	return packedInts{width: width, words: make([]uint64, (n*int(width)+63)/64)}
+}
+
+// Return the number of bytes that hold n integers.
+func (packed packedInts) byteLen(n int) int {
+	return (n*int(packed.width) + 7) / 8
+}
+
+// Return true if n integers of this width take exactly this many bytes. Decoders
+// check a count read from their input this way before allocating for it, since
+// a corrupt count can overflow n*width.
+func packedByteLenIs(n uint64, width uint, bytes int) bool {
+	if width == 0 {
+		return bytes == 0
+	}
+	return n <= uint64(bytes)*8/uint64(width) && (n*uint64(width)+7)/8 == uint64(bytes)
+}
+
+func (packed packedInts) get(i int) uint64 {
+	if packed.width == 0 {
+		return 0
+	}
+	bit := uint(i) * packed.width
+	word, offset := bit/64, bit%64
+	value := packed.words[word] >> offset
+	if offset+packed.width > 64 {
+		value |= packed.words[word+1] << (64 - offset)
+	}
+	return value & packed.mask()
+}
+
+func (packed packedInts) set(i int, value uint64) {
+	if packed.width == 0 {
+		return
+	}
+	mask := packed.mask()
+	value &= mask
+	bit := uint(i) * packed.width
+	word, offset := bit/64, bit%64
+	packed.words[word] = packed.words[word]&^(mask<<offset) | value<<offset
+	if offset+packed.width > 64 {
+		packed.words[word+1] = packed.words[word+1]&^(mask>>(64-offset)) | value>>(64-offset)
+	}
+}
+
+// Return the largest value that fits in the width.
+func (packed packedInts) mask() uint64 {
+	return ^uint64(0) >> (64 - packed.width)
+}
+
+// Append the bytes holding the first n integers, least significant first.
+func (packed packedInts) appendBytes(data []byte, n int) []byte {
+	for i := 0; i < packed.byteLen(n); i++ {
+		data = append(data, byte(packed.words[i/8]>>(8*(i%8))))
+	}
+	return data
+}
+
+// Fill the integers from bytes written by appendBytes for n integers.
+func (packed packedInts) readBytes(data []byte, n int) error {
+	if len(data) != packed.byteLen(n) {
+		return fmt.Errorf("got %d bytes, want %d", len(data), packed.byteLen(n))
+	}
+	for i := range packed.words {
+		packed.words[i] = 0
+	}
+	for i, b := range data {
+		packed.words[i/8] |= uint64(b) << (8 * (i % 8))
+	}
+	return nil
+}
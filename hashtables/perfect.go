@@ -0,0 +1,357 @@
+package hashtables
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// DefaultKeysPerBucket is the average number of keys a perfect hash puts in each bucket.
+// Bigger buckets mean fewer displacements to store but longer builds.
+const DefaultKeysPerBucket = 5
+
+// PerfectHash maps each key of a fixed set to its own index from 0 to n-1, using the
+// hash and displace method (CHD). Keys are split into buckets by one hash, and each bucket
+// stores a displacement that picks the second hash sending its keys to free indices.
+// Finding a key's index takes its hash code, one displacement and no search, so a table
+// built on it answers every lookup with a single probe. Keys that are not in the set get
+// some index too, so the table must still compare keys.
+type PerfectHash[K any] struct {
+	keys          KeyHasher[K]
+	n             int
+	numBuckets    int
+	displacements packedInts
+}
+
+// PerfectBuild reports how building a perfect hash went.
+type PerfectBuild struct {
+	Keys            int
+	Buckets         int
+	MaxDisplacement int
+	BuildTime       time.Duration
+	Bytes           int     // The size of the serialized hash function.
+	BitsPerKey      float64 // Bytes in bits, divided by the number of keys.
+}
+
+// Display the build report on one line.
+func (build PerfectBuild) Dump(w io.Writer) {
+	fmt.Fprintf(w, "Perfect hash: %d keys in %d buckets, max displacement %d, built in %v, %d bytes (%.2f bits/key)\n",
+		build.Keys, build.Buckets, build.MaxDisplacement, build.BuildTime, build.Bytes, build.BitsPerKey)
+}
+
+// Build a minimal perfect hash for a set of distinct keys.
+// A keysPerBucket of 0 means DefaultKeysPerBucket.
+// Two keys with the same hash code can't be told apart, so they are an error.
+func BuildPerfectHash[K any](keyList []K, keys KeyHasher[K], keysPerBucket int) (*PerfectHash[K], PerfectBuild, error) {
+	start := time.Now()
+	if keysPerBucket <= 0 {
+		keysPerBucket = DefaultKeysPerBucket
+	}
+	n := len(keyList)
+	hash := &PerfectHash[K]{keys: keys, n: n, numBuckets: max(1, (n+keysPerBucket-1)/keysPerBucket)}
+
+	// Put each key in its bucket, hashing it only once.
+	codes := make([]int, n)
+	buckets := make([][]int, hash.numBuckets)
+	for i, key := range keyList {
+		code := keys.Hash(key)
+		codes[i] = code
+		bucket := hash.bucket(code)
+		for _, j := range buckets[bucket] {
+			if code != codes[j] {
+				continue
+			}
+			if keys.Equal(key, keyList[j]) {
+				return nil, PerfectBuild{}, fmt.Errorf("duplicate key %s", format(key))
+			}
+			return nil, PerfectBuild{}, fmt.Errorf("keys %s and %s have the same hash code",
+				format(keyList[j]), format(key))
+		}
+		buckets[bucket] = append(buckets[bucket], i)
+	}
+
+	// Place the biggest buckets first, while most indices are free.
+	order := make([]int, hash.numBuckets)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return len(buckets[order[a]]) > len(buckets[order[b]]) })
+
+	displacements := make([]uint64, hash.numBuckets)
+	taken := make([]bool, n)
+	slots := make([]int, 0, keysPerBucket)
+	limit := max(1<<20, 64*n)
+	for _, bucket := range order {
+		if len(buckets[bucket]) == 0 {
+			break
+		}
+		displacement := 0
+		for ; displacement < limit; displacement++ {
+			if hash.fits(codes, buckets[bucket], displacement, taken, &slots) {
+				break
+			}
+		}
+		if displacement == limit {
+			return nil, PerfectBuild{}, fmt.Errorf("no displacement places bucket %d after %d tries", bucket, limit)
+		}
+		for _, slot := range slots {
+			taken[slot] = true
+		}
+		displacements[bucket] = uint64(displacement)
+	}
+
+	// Store the displacements in as few bits as the largest one needs.
+	maxDisplacement := uint64(0)
+	for _, displacement := range displacements {
+		maxDisplacement = max(maxDisplacement, displacement)
+	}
+	hash.displacements = newPackedInts(hash.numBuckets, uint(bits.Len64(maxDisplacement)))
+	for i, displacement := range displacements {
+		hash.displacements.set(i, displacement)
+	}
+
+	build := PerfectBuild{Keys: n, Buckets: hash.numBuckets, MaxDisplacement: int(maxDisplacement),
+		Bytes: len(hash.marshal())}
+	if n > 0 {
+		build.BitsPerKey = float64(8*build.Bytes) / float64(n)
+	}
+	build.BuildTime = time.Since(start)
+	return hash, build, nil
+}
+
+// Return true if this displacement sends a bucket's keys to distinct free indices,
+// which it leaves in slots.
+func (hash *PerfectHash[K]) fits(codes []int, bucket []int, displacement int, taken []bool, slots *[]int) bool {
+	*slots = (*slots)[:0]
+	for _, i := range bucket {
+		slot := hash.slot(codes[i], displacement)
+		if taken[slot] {
+			return false
+		}
+		for _, other := range *slots {
+			if other == slot {
+				return false
+			}
+		}
+		*slots = append(*slots, slot)
+	}
+	return true
+}
+
+// Return the bucket for a hash code.
+func (hash *PerfectHash[K]) bucket(code int) int {
+	return reduce(remix(code^0x5851f42d4c957f2d), hash.numBuckets)
+}
+
+// Return the index a displacement gives a hash code.
+func (hash *PerfectHash[K]) slot(code int, displacement int) int {
+	return reduce(remix(int(uint64(code)+uint64(displacement+1)*0x9e3779b97f4a7c15)), hash.n)
+}
+
+// Return the number of keys the hash was built for.
+func (hash *PerfectHash[K]) Len() int {
+	return hash.n
+}
+
+// Return the key's index. Each key in the set has its own index from 0 to Len()-1.
+// A key outside the set gets one of those indices too.
+func (hash *PerfectHash[K]) Index(key K) int {
+	if hash.n == 0 {
+		return -1
+	}
+	code := hash.keys.Hash(key)
+	return hash.slot(code, int(hash.displacements.get(hash.bucket(code))))
+}
+
+// The serialized form starts with these bytes and a version.
+var perfectMagic = []byte("MPH\x01")
+
+// Encode the hash function compactly: the key count, the bucket count,
+// the displacement width and the packed displacements.
+// The keys and the KeyHasher are not included.
+func (hash *PerfectHash[K]) MarshalBinary() ([]byte, error) {
+	return hash.marshal(), nil
+}
+
+func (hash *PerfectHash[K]) marshal() []byte {
+	data := append([]byte(nil), perfectMagic...)
+	data = binary.AppendUvarint(data, uint64(hash.n))
+	data = binary.AppendUvarint(data, uint64(hash.numBuckets))
+	data = append(data, byte(hash.displacements.width))
+	return hash.displacements.appendBytes(data, hash.numBuckets)
+}
+
+// Decode a hash function written by MarshalBinary. The keys must be hashed
+// with the same KeyHasher it was built with.
+func UnmarshalPerfectHash[K any](data []byte, keys KeyHasher[K]) (*PerfectHash[K], error) {
+	if len(data) < len(perfectMagic) || string(data[:len(perfectMagic)]) != string(perfectMagic) {
+		return nil, errors.New("not a serialized perfect hash")
+	}
+	data = data[len(perfectMagic):]
+	n, read := binary.Uvarint(data)
+	if read <= 0 || n > math.MaxInt {
+		return nil, errors.New("bad perfect hash key count")
+	}
+	data = data[read:]
+	// A build never makes more buckets than keys.
+	numBuckets, read := binary.Uvarint(data)
+	if read <= 0 || numBuckets == 0 || numBuckets > max(1, n) {
+		return nil, errors.New("bad perfect hash bucket count")
+	}
+	data = data[read:]
+	if len(data) == 0 || data[0] > 64 {
+		return nil, errors.New("bad perfect hash displacement width")
+	}
+	if !packedByteLenIs(numBuckets, uint(data[0]), len(data)-1) {
+		return nil, fmt.Errorf("perfect hash displacements: got %d bytes for %d buckets of %d bits",
+			len(data)-1, numBuckets, data[0])
+	}
+	hash := &PerfectHash[K]{keys: keys, n: int(n), numBuckets: int(numBuckets),
+		displacements: newPackedInts(int(numBuckets), uint(data[0]))}
+	if err := hash.displacements.readBytes(data[1:], hash.numBuckets); err != nil {
+		return nil, fmt.Errorf("perfect hash displacements: %w", err)
+	}
+	return hash, nil
+}
+
+// PerfectMap is a read-only map built once from a fixed set of entries, such as the day's
+// org chart. Its entries sit in the order a PerfectHash gives their keys, so every lookup,
+// hit or miss, examines exactly one entry.
+type PerfectMap[K any, V any] struct {
+	hash    *PerfectHash[K]
+	entries []Entry[K, V]
+}
+
+// Build a perfect hash for the entries' keys and a map that holds them.
+// A keysPerBucket of 0 means DefaultKeysPerBucket.
+func BuildPerfectMap[K any, V any](entries []Entry[K, V], keys KeyHasher[K],
+	keysPerBucket int) (*PerfectMap[K, V], PerfectBuild, error) {
+	keyList := make([]K, len(entries))
+	for i, entry := range entries {
+		keyList[i] = entry.Key
+	}
+	hash, build, err := BuildPerfectHash(keyList, keys, keysPerBucket)
+	if err != nil {
+		return nil, build, err
+	}
+	perfectMap, err := NewPerfectMap(hash, entries)
+	return perfectMap, build, err
+}
+
+// Place the entries with a perfect hash built for their keys, such as one read back
+// with UnmarshalPerfectHash.
+func NewPerfectMap[K any, V any](hash *PerfectHash[K], entries []Entry[K, V]) (*PerfectMap[K, V], error) {
+	if len(entries) != hash.n {
+		return nil, fmt.Errorf("perfect hash is for %d keys, got %d entries", hash.n, len(entries))
+	}
+	perfectMap := &PerfectMap[K, V]{hash: hash, entries: make([]Entry[K, V], len(entries))}
+	placed := make([]bool, len(entries))
+	for _, entry := range entries {
+		index := hash.Index(entry.Key)
+		if placed[index] {
+			return nil, fmt.Errorf("perfect hash sends %s and %s to index %d",
+				format(perfectMap.entries[index].Key), format(entry.Key), index)
+		}
+		placed[index] = true
+		perfectMap.entries[index] = Entry[K, V]{Key: entry.Key, Value: entry.Value}
+	}
+	return perfectMap, nil
+}
+
+// Return the map's perfect hash.
+func (perfectMap *PerfectMap[K, V]) Hash() *PerfectHash[K] {
+	return perfectMap.hash
+}
+
+// Return the number of entries.
+func (perfectMap *PerfectMap[K, V]) Len() int {
+	return len(perfectMap.entries)
+}
+
+// Return the key's entry or nil.
+func (perfectMap *PerfectMap[K, V]) entry(key K) *Entry[K, V] {
+	if len(perfectMap.entries) == 0 {
+		return nil
+	}
+	entry := &perfectMap.entries[perfectMap.hash.Index(key)]
+	if !perfectMap.hash.keys.Equal(entry.Key, key) {
+		return nil
+	}
+	return entry
+}
+
+// Return an item from the map, or the zero value if it is not present.
+func (perfectMap *PerfectMap[K, V]) Get(key K) V {
+	value, _ := perfectMap.Lookup(key)
+	return value
+}
+
+// Return an item from the map and whether it was present.
+func (perfectMap *PerfectMap[K, V]) Lookup(key K) (V, bool) {
+	if entry := perfectMap.entry(key); entry != nil {
+		return entry.Value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Return true if the key is in the map.
+func (perfectMap *PerfectMap[K, V]) Contains(key K) bool {
+	return perfectMap.entry(key) != nil
+}
+
+// Call fn for each entry in index order until fn returns false.
+func (perfectMap *PerfectMap[K, V]) Range(fn func(key K, value V) bool) {
+	for _, entry := range perfectMap.entries {
+		if !fn(entry.Key, entry.Value) {
+			return
+		}
+	}
+}
+
+// Show the bucket, displacement and single entry examined while looking for this key.
+// Return the key's index, or -1 if it is not present.
+func (perfectMap *PerfectMap[K, V]) Probe(w io.Writer, key K) int {
+	hash := perfectMap.hash
+	if hash.n == 0 {
+		fmt.Fprintf(w, "Probing %s\n    Not found\n", format(key))
+		return -1
+	}
+	bucket := hash.bucket(hash.keys.Hash(key))
+	index := hash.Index(key)
+	fmt.Fprintf(w, "Probing %s (bucket %d, displacement %d)\n", format(key), bucket, hash.displacements.get(bucket))
+	fmt.Fprintf(w, "    %d: %s\n", index, format(perfectMap.entries[index].Key))
+	if !hash.keys.Equal(perfectMap.entries[index].Key, key) {
+		fmt.Fprintf(w, "    Not found\n")
+		return -1
+	}
+	fmt.Fprintf(w, "    Returning found index %d\n", index)
+	return index
+}
+
+// Return the average probe sequence length for the items in the map, which is always 1.
+func (perfectMap *PerfectMap[K, V]) AveProbeSequenceLength() float32 {
+	if len(perfectMap.entries) == 0 {
+		return 0
+	}
+	return 1
+}
+
+// Collect the stats for the map. Every hit and every miss takes one probe.
+func (perfectMap *PerfectMap[K, V]) Stats() Stats {
+	n := len(perfectMap.entries)
+	ones := make([]int, n)
+	for i := range ones {
+		ones[i] = 1
+	}
+	stats := Stats{Capacity: n, Live: n, Successful: newProbeStats(ones), Unsuccessful: newProbeStats(ones)}
+	if n > 0 {
+		stats.LoadFactor = 1
+	}
+	return stats
+}
@@ -0,0 +1,150 @@
+package hashtables_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"hashtables"
+)
+
+// Return n distinct names.
+func perfectKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("employee-%d", i)
+	}
+	return keys
+}
+
+func TestPerfectHash(t *testing.T) {
+	for _, n := range []int{1, 2, 7, 100, 5000} {
+		for _, keysPerBucket := range []int{0, 1, 3} {
+			keys := perfectKeys(n)
+			hash, build, err := hashtables.BuildPerfectHash(keys, hashtables.StringKeys(nil), keysPerBucket)
+			if err != nil {
+				t.Fatalf("%d keys, %d per bucket: %v", n, keysPerBucket, err)
+			}
+			if hash.Len() != n || build.Keys != n || build.Bytes == 0 {
+				t.Errorf("%d keys, %d per bucket: Len %d, build %+v", n, keysPerBucket, hash.Len(), build)
+			}
+
+			// Every key gets its own index from 0 to n-1.
+			seen := make([]bool, n)
+			for _, key := range keys {
+				index := hash.Index(key)
+				if index < 0 || index >= n || seen[index] {
+					t.Fatalf("%d keys, %d per bucket: %q has index %d, which is out of range or taken",
+						n, keysPerBucket, key, index)
+				}
+				seen[index] = true
+			}
+
+			// The serialized hash gives the same indices.
+			data, err := hash.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) != build.Bytes {
+				t.Errorf("%d keys: %d bytes, but the build reported %d", n, len(data), build.Bytes)
+			}
+			loaded, err := hashtables.UnmarshalPerfectHash(data, hashtables.StringKeys(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range append(keys, "stranger") {
+				if loaded.Index(key) != hash.Index(key) {
+					t.Errorf("%d keys: the loaded hash moved %q from %d to %d",
+						n, key, hash.Index(key), loaded.Index(key))
+				}
+			}
+		}
+	}
+
+	empty, _, err := hashtables.BuildPerfectHash(nil, hashtables.StringKeys(nil), 0)
+	if err != nil || empty.Len() != 0 || empty.Index("Ann") != -1 {
+		t.Errorf("an empty hash has Len %d and Index %d: %v", empty.Len(), empty.Index("Ann"), err)
+	}
+}
+
+// Return a serialized perfect hash with this header and bytes of zero displacements.
+func perfectHeader(n uint64, numBuckets uint64, width byte, bytes int) []byte {
+	data := []byte("MPH\x01")
+	data = binary.AppendUvarint(data, n)
+	data = binary.AppendUvarint(data, numBuckets)
+	data = append(data, width)
+	return append(data, make([]byte, bytes)...)
+}
+
+func TestPerfectHashErrors(t *testing.T) {
+	if _, _, err := hashtables.BuildPerfectHash([]string{"Ann", "Bob", "Ann"}, hashtables.StringKeys(nil), 0); err == nil ||
+		!strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("duplicate keys returned %v", err)
+	}
+	constant := hashtables.StringKeys(func(key string) int { return 1 })
+	if _, _, err := hashtables.BuildPerfectHash([]string{"Ann", "Bob"}, constant, 0); err == nil ||
+		!strings.Contains(err.Error(), "same hash code") {
+		t.Errorf("colliding keys returned %v", err)
+	}
+
+	// A header that fits its data decodes.
+	if loaded, err := hashtables.UnmarshalPerfectHash(perfectHeader(10, 2, 8, 2), hashtables.StringKeys(nil)); err != nil ||
+		loaded.Index("Ann") < 0 || loaded.Index("Ann") >= 10 {
+		t.Errorf("a small header gives an error %v", err)
+	}
+
+	hash, _, _ := hashtables.BuildPerfectHash(perfectKeys(50), hashtables.StringKeys(nil), 0)
+	data, _ := hash.MarshalBinary()
+	for name, bad := range map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte("XXX"), data[3:]...),
+		"truncated": data[:len(data)-1],
+		"header":    data[:5],
+
+		// Counts too large for the data, which must fail before allocating.
+		"huge buckets": perfectHeader(1<<61, 1<<60, 64, 0),
+		"huge keys":    perfectHeader(math.MaxUint64, 1, 8, 1),
+		"more buckets": perfectHeader(10, 11, 8, 11),
+		"no bits":      perfectHeader(10, 1<<40, 0, 0),
+		"extra bytes":  perfectHeader(10, 2, 8, 3),
+	} {
+		if _, err := hashtables.UnmarshalPerfectHash(bad, hashtables.StringKeys(nil)); err == nil {
+			t.Errorf("unmarshaled %s data", name)
+		}
+	}
+}
+
+func TestPerfectMap(t *testing.T) {
+	var entries []hashtables.Entry[string, int]
+	for i, key := range perfectKeys(300) {
+		entries = append(entries, hashtables.Entry[string, int]{Key: key, Value: i})
+	}
+	perfectMap, _, err := hashtables.BuildPerfectMap(entries, hashtables.StringKeys(nil), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if value, ok := perfectMap.Lookup(entry.Key); !ok || value != entry.Value {
+			t.Errorf("Lookup(%q) = %d, %v", entry.Key, value, ok)
+		}
+	}
+	if perfectMap.Contains("stranger") || perfectMap.Len() != 300 {
+		t.Error("the map has the wrong keys")
+	}
+	if psl := perfectMap.AveProbeSequenceLength(); psl != 1 {
+		t.Errorf("average probe sequence length %v", psl)
+	}
+
+	// A map can be rebuilt from a loaded hash, but only with its own number of entries.
+	data, _ := perfectMap.Hash().MarshalBinary()
+	loaded, _ := hashtables.UnmarshalPerfectHash(data, hashtables.StringKeys(nil))
+	rebuilt, err := hashtables.NewPerfectMap(loaded, entries)
+	if err != nil || rebuilt.Get("employee-7") != 7 {
+		t.Errorf("rebuilt map has employee-7 = %d: %v", rebuilt.Get("employee-7"), err)
+	}
+	if _, err := hashtables.NewPerfectMap(loaded, entries[1:]); err == nil {
+		t.Error("placed 299 entries with a hash for 300")
+	}
+}
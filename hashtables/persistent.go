@@ -0,0 +1,446 @@
+package hashtables
+
+import "math/bits"
+
+// PersistentMap is an immutable hash array mapped trie (HAMT). Each level of the trie
+// uses 5 bits of a key's hash code to pick one of up to 32 children, so operations
+// take O(log32 n) steps. Set and Delete return a new map that shares every untouched
+// node with the old one, so old versions stay valid and cost nothing to keep, which
+// makes them cheap snapshots. Keys whose whole hash codes collide share a leaf.
+type PersistentMap[K any, V any] struct {
+	root  *hamtNode[K, V]
+	count int
+	keys  KeyHasher[K]
+}
+
+// TransientMap is a mutable builder for a PersistentMap. It changes the nodes it has
+// already copied in place, so bulk loads don't copy a path for every key.
+// Call Persistent to finish; the builder can't be used after that.
+type TransientMap[K any, V any] struct {
+	root  *hamtNode[K, V]
+	count int
+	keys  KeyHasher[K]
+	owner *hamtOwner
+}
+
+// hamtOwner marks the nodes a transient created, which it may change in place.
+type hamtOwner struct{ _ byte }
+
+const (
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+)
+
+// hamtNode holds the children whose bit is set in bitmap, in bit order.
+type hamtNode[K any, V any] struct {
+	bitmap uint32
+	slots  []hamtSlot[K, V]
+	owner  *hamtOwner
+}
+
+// hamtSlot holds either a child node or a leaf.
+type hamtSlot[K any, V any] struct {
+	node *hamtNode[K, V]
+	leaf *hamtLeaf[K, V]
+}
+
+// hamtLeaf holds the entries for one full hash code. Leaves are never changed in place.
+type hamtLeaf[K any, V any] struct {
+	hash    uint64
+	entries []Entry[K, V]
+}
+
+// Make an empty persistent map.
+func NewPersistentMap[K any, V any](keys KeyHasher[K]) *PersistentMap[K, V] {
+	return &PersistentMap[K, V]{root: &hamtNode[K, V]{}, keys: keys}
+}
+
+// Return the number of entries.
+func (m *PersistentMap[K, V]) Len() int {
+	return m.count
+}
+
+// Return the key's value or the zero value if it is not present.
+func (m *PersistentMap[K, V]) Get(key K) V {
+	value, _ := m.Lookup(key)
+	return value
+}
+
+// Return the key's value and whether it is present.
+func (m *PersistentMap[K, V]) Lookup(key K) (V, bool) {
+	return m.root.lookup(m.keys, uint64(m.keys.Hash(key)), key)
+}
+
+// Return true if the key is present.
+func (m *PersistentMap[K, V]) Contains(key K) bool {
+	_, ok := m.Lookup(key)
+	return ok
+}
+
+// Return a map with the key set to value. The receiver is unchanged.
+func (m *PersistentMap[K, V]) Set(key K, value V) *PersistentMap[K, V] {
+	root, added := m.root.set(nil, m.keys, 0, uint64(m.keys.Hash(key)), key, value)
+	result := &PersistentMap[K, V]{root: root, count: m.count, keys: m.keys}
+	if added {
+		result.count++
+	}
+	return result
+}
+
+// Return a map without the key, or the receiver itself if the key is not present.
+func (m *PersistentMap[K, V]) Delete(key K) *PersistentMap[K, V] {
+	slot, removed := m.root.delete(nil, m.keys, 0, uint64(m.keys.Hash(key)), key)
+	if !removed {
+		return m
+	}
+	return &PersistentMap[K, V]{root: rootNode(slot), count: m.count - 1, keys: m.keys}
+}
+
+// Call fn for each entry in trie order until fn returns false.
+func (m *PersistentMap[K, V]) Range(fn func(key K, value V) bool) {
+	m.root.rangeEntries(fn)
+}
+
+// Start a builder from this map. The map itself is unchanged.
+func (m *PersistentMap[K, V]) Transient() *TransientMap[K, V] {
+	return &TransientMap[K, V]{root: m.root, count: m.count, keys: m.keys, owner: &hamtOwner{}}
+}
+
+// Return true if both maps hold the same keys with equal values.
+// A nil valueEqual compares values with reflect.DeepEqual.
+// Subtrees the maps share are not visited.
+func (m *PersistentMap[K, V]) Equal(other *PersistentMap[K, V], valueEqual func(a, b V) bool) bool {
+	if m.count != other.count {
+		return false
+	}
+	equal := true
+	m.diff(other, valueEqual, func(Entry[K, V], Entry[K, V], bool, bool) bool {
+		equal = false
+		return false
+	})
+	return equal
+}
+
+// Return what changed from this map to other. A nil valueEqual compares values with
+// reflect.DeepEqual. Subtrees the maps share are not visited, so diffing a version
+// against its recent ancestor only costs as much as the changes between them.
+func (m *PersistentMap[K, V]) Diff(other *PersistentMap[K, V], valueEqual func(a, b V) bool) MapDiff[K, V] {
+	var diff MapDiff[K, V]
+	m.diff(other, valueEqual, diff.record)
+	return diff
+}
+
+// Call report for each key that is missing from one map or whose values differ,
+// until report returns false.
+func (m *PersistentMap[K, V]) diff(other *PersistentMap[K, V], valueEqual func(a, b V) bool,
+	report func(old, new Entry[K, V], inOld, inNew bool) bool) {
+	valueEqual = defaultValueEqual(valueEqual)
+	differ := &hamtDiffer[K, V]{keys: m.keys, valueEqual: valueEqual, report: report}
+	if sameKeyHasher(m.keys, other.keys) {
+		differ.slots(hamtSlot[K, V]{node: m.root}, hamtSlot[K, V]{node: other.root}, 0)
+		return
+	}
+
+	// Maps that hash differently have different shapes, so compare them key by key.
+	diffByKey[K, V](m, other, valueEqual, report)
+}
+
+// Return the number of entries.
+func (t *TransientMap[K, V]) Len() int {
+	return t.count
+}
+
+// Return the key's value and whether it is present.
+func (t *TransientMap[K, V]) Lookup(key K) (V, bool) {
+	return t.root.lookup(t.keys, uint64(t.keys.Hash(key)), key)
+}
+
+// Set the key to value.
+func (t *TransientMap[K, V]) Set(key K, value V) {
+	t.check()
+	root, added := t.root.set(t.owner, t.keys, 0, uint64(t.keys.Hash(key)), key, value)
+	t.root = root
+	if added {
+		t.count++
+	}
+}
+
+// Delete the key. Return true if it was present.
+func (t *TransientMap[K, V]) Delete(key K) bool {
+	t.check()
+	slot, removed := t.root.delete(t.owner, t.keys, 0, uint64(t.keys.Hash(key)), key)
+	if removed {
+		t.root = rootNode(slot)
+		t.count--
+	}
+	return removed
+}
+
+// Return the finished map. The builder can't be used after this.
+func (t *TransientMap[K, V]) Persistent() *PersistentMap[K, V] {
+	t.check()
+	t.owner = nil
+	return &PersistentMap[K, V]{root: t.root, count: t.count, keys: t.keys}
+}
+
+func (t *TransientMap[K, V]) check() {
+	if t.owner == nil {
+		panic("TransientMap used after Persistent")
+	}
+}
+
+// Return the node for a root slot returned by delete.
+func rootNode[K any, V any](slot hamtSlot[K, V]) *hamtNode[K, V] {
+	if slot.node == nil {
+		return &hamtNode[K, V]{}
+	}
+	return slot.node
+}
+
+// Return the bit for the hash code at this level and the child's position in slots.
+func (node *hamtNode[K, V]) position(hash uint64, shift uint) (uint32, int) {
+	bit := uint32(1) << (hash >> shift & hamtMask)
+	return bit, bits.OnesCount32(node.bitmap & (bit - 1))
+}
+
+// Return the node itself if owner may change it in place, or else a copy that owner may change.
+func (node *hamtNode[K, V]) editable(owner *hamtOwner) *hamtNode[K, V] {
+	if owner != nil && node.owner == owner {
+		return node
+	}
+	return &hamtNode[K, V]{
+		bitmap: node.bitmap,
+		slots:  append(make([]hamtSlot[K, V], 0, len(node.slots)+1), node.slots...),
+		owner:  owner,
+	}
+}
+
+func (node *hamtNode[K, V]) lookup(keys KeyHasher[K], hash uint64, key K) (V, bool) {
+	for shift := uint(0); ; shift += hamtBits {
+		bit, pos := node.position(hash, shift)
+		if node.bitmap&bit == 0 {
+			break
+		}
+		slot := node.slots[pos]
+		if slot.node != nil {
+			node = slot.node
+			continue
+		}
+		if slot.leaf.hash == hash {
+			for _, entry := range slot.leaf.entries {
+				if keys.Equal(entry.Key, key) {
+					return entry.Value, true
+				}
+			}
+		}
+		break
+	}
+	var zero V
+	return zero, false
+}
+
+// Set the key in this subtree. Return the subtree's new root and true if the key was added.
+func (node *hamtNode[K, V]) set(owner *hamtOwner, keys KeyHasher[K], shift uint,
+	hash uint64, key K, value V) (*hamtNode[K, V], bool) {
+	bit, pos := node.position(hash, shift)
+	newLeaf := &hamtLeaf[K, V]{hash: hash, entries: []Entry[K, V]{{Key: key, Value: value}}}
+	if node.bitmap&bit == 0 {
+		result := node.editable(owner)
+		result.bitmap |= bit
+		result.slots = append(result.slots, hamtSlot[K, V]{})
+		copy(result.slots[pos+1:], result.slots[pos:])
+		result.slots[pos] = hamtSlot[K, V]{leaf: newLeaf}
+		return result, true
+	}
+
+	var replacement hamtSlot[K, V]
+	added := true
+	slot := node.slots[pos]
+	switch {
+	case slot.node != nil:
+		var child *hamtNode[K, V]
+		child, added = slot.node.set(owner, keys, shift+hamtBits, hash, key, value)
+		replacement.node = child
+	case slot.leaf.hash == hash:
+		// Replace the key's entry or add it beside the keys whose hash codes collide with it.
+		entries := append([]Entry[K, V](nil), slot.leaf.entries...)
+		for i := range entries {
+			if keys.Equal(entries[i].Key, key) {
+				entries[i].Value = value
+				added = false
+				break
+			}
+		}
+		if added {
+			entries = append(entries, Entry[K, V]{Key: key, Value: value})
+		}
+		replacement.leaf = &hamtLeaf[K, V]{hash: hash, entries: entries}
+	default:
+		replacement.node = splitLeaves(owner, shift+hamtBits, slot.leaf, newLeaf)
+	}
+	result := node.editable(owner)
+	result.slots[pos] = replacement
+	return result, added
+}
+
+// Return a subtree holding two leaves with different hash codes.
+func splitLeaves[K any, V any](owner *hamtOwner, shift uint, a, b *hamtLeaf[K, V]) *hamtNode[K, V] {
+	indexA, indexB := a.hash>>shift&hamtMask, b.hash>>shift&hamtMask
+	if indexA == indexB {
+		child := splitLeaves(owner, shift+hamtBits, a, b)
+		return &hamtNode[K, V]{bitmap: 1 << indexA, slots: []hamtSlot[K, V]{{node: child}}, owner: owner}
+	}
+	if indexA > indexB {
+		a, b = b, a
+	}
+	return &hamtNode[K, V]{
+		bitmap: 1<<indexA | 1<<indexB,
+		slots:  []hamtSlot[K, V]{{leaf: a}, {leaf: b}},
+		owner:  owner,
+	}
+}
+
+// Delete the key from this subtree. Return what should replace the subtree and true
+// if the key was present. A subtree left with a single leaf collapses into that leaf,
+// and an empty one into an empty slot.
+func (node *hamtNode[K, V]) delete(owner *hamtOwner, keys KeyHasher[K], shift uint,
+	hash uint64, key K) (hamtSlot[K, V], bool) {
+	bit, pos := node.position(hash, shift)
+	if node.bitmap&bit == 0 {
+		return hamtSlot[K, V]{node: node}, false
+	}
+
+	var replacement hamtSlot[K, V]
+	slot := node.slots[pos]
+	if slot.node != nil {
+		var removed bool
+		replacement, removed = slot.node.delete(owner, keys, shift+hamtBits, hash, key)
+		if !removed {
+			return hamtSlot[K, V]{node: node}, false
+		}
+	} else {
+		if slot.leaf.hash != hash {
+			return hamtSlot[K, V]{node: node}, false
+		}
+		found := -1
+		for i, entry := range slot.leaf.entries {
+			if keys.Equal(entry.Key, key) {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			return hamtSlot[K, V]{node: node}, false
+		}
+		if len(slot.leaf.entries) > 1 {
+			entries := append([]Entry[K, V](nil), slot.leaf.entries[:found]...)
+			entries = append(entries, slot.leaf.entries[found+1:]...)
+			replacement.leaf = &hamtLeaf[K, V]{hash: hash, entries: entries}
+		}
+	}
+
+	result := node.editable(owner)
+	if replacement.node == nil && replacement.leaf == nil {
+		result.bitmap &^= bit
+		result.slots = append(result.slots[:pos], result.slots[pos+1:]...)
+	} else {
+		result.slots[pos] = replacement
+	}
+	switch {
+	case len(result.slots) == 0:
+		return hamtSlot[K, V]{}, true
+	case shift > 0 && len(result.slots) == 1 && result.slots[0].leaf != nil:
+		return result.slots[0], true
+	}
+	return hamtSlot[K, V]{node: result}, true
+}
+
+func (node *hamtNode[K, V]) rangeEntries(fn func(key K, value V) bool) bool {
+	for _, slot := range node.slots {
+		if !slot.rangeEntries(fn) {
+			return false
+		}
+	}
+	return true
+}
+
+func (slot hamtSlot[K, V]) rangeEntries(fn func(key K, value V) bool) bool {
+	if slot.node != nil {
+		return slot.node.rangeEntries(fn)
+	}
+	if slot.leaf != nil {
+		for _, entry := range slot.leaf.entries {
+			if !fn(entry.Key, entry.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hamtDiffer walks two tries built with the same KeyHasher side by side.
+type hamtDiffer[K any, V any] struct {
+	keys       KeyHasher[K]
+	valueEqual func(a, b V) bool
+	report     func(old, new Entry[K, V], inOld, inNew bool) bool
+}
+
+// Compare the slots at the same place in both tries. Return false to stop.
+func (differ *hamtDiffer[K, V]) slots(old, new hamtSlot[K, V], shift uint) bool {
+	if old == new {
+		return true
+	}
+	if old.node != nil && new.node != nil {
+		for bitmap := old.node.bitmap | new.node.bitmap; bitmap != 0; bitmap &= bitmap - 1 {
+			bit := bitmap & -bitmap
+			if !differ.slots(old.node.child(bit), new.node.child(bit), shift+hamtBits) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// At least one side is a leaf or empty, so it holds few entries. Match them by key.
+	oldEntries, newEntries := old.entries(), new.entries()
+	matched := make([]bool, len(newEntries))
+	for _, oldEntry := range oldEntries {
+		found := false
+		for j, newEntry := range newEntries {
+			if !matched[j] && differ.keys.Equal(oldEntry.Key, newEntry.Key) {
+				matched[j], found = true, true
+				if !differ.valueEqual(oldEntry.Value, newEntry.Value) &&
+					!differ.report(oldEntry, newEntry, true, true) {
+					return false
+				}
+				break
+			}
+		}
+		if !found && !differ.report(oldEntry, Entry[K, V]{}, true, false) {
+			return false
+		}
+	}
+	for j, newEntry := range newEntries {
+		if !matched[j] && !differ.report(Entry[K, V]{}, newEntry, false, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the child slot for a bit, or an empty slot.
+func (node *hamtNode[K, V]) child(bit uint32) hamtSlot[K, V] {
+	if node.bitmap&bit == 0 {
+		return hamtSlot[K, V]{}
+	}
+	return node.slots[bits.OnesCount32(node.bitmap&(bit-1))]
+}
+
+// Return every entry under the slot.
+func (slot hamtSlot[K, V]) entries() []Entry[K, V] {
+	var entries []Entry[K, V]
+	slot.rangeEntries(func(key K, value V) bool {
+		entries = append(entries, Entry[K, V]{Key: key, Value: value})
+		return true
+	})
+	return entries
+}
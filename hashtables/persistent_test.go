@@ -0,0 +1,142 @@
+package hashtables_test
+
+import (
+	"fmt"
+	"testing"
+
+	"hashtables"
+)
+
+// Return the map's entries as a Go map.
+func persistentEntries(m *hashtables.PersistentMap[string, int]) map[string]int {
+	entries := make(map[string]int)
+	m.Range(func(key string, value int) bool {
+		entries[key] = value
+		return true
+	})
+	return entries
+}
+
+func TestPersistentVersions(t *testing.T) {
+	keys := hashtables.StringKeys(nil)
+	empty := hashtables.NewPersistentMap[string, int](keys)
+	v1 := empty.Set("Ann", 1).Set("Bob", 2)
+	v2 := v1.Set("Ann", 10).Set("Cid", 3)
+	v3 := v2.Delete("Bob")
+
+	// Every version keeps its own entries.
+	for _, test := range []struct {
+		m    *hashtables.PersistentMap[string, int]
+		want map[string]int
+	}{
+		{empty, map[string]int{}},
+		{v1, map[string]int{"Ann": 1, "Bob": 2}},
+		{v2, map[string]int{"Ann": 10, "Bob": 2, "Cid": 3}},
+		{v3, map[string]int{"Ann": 10, "Cid": 3}},
+	} {
+		if got := persistentEntries(test.m); fmt.Sprint(got) != fmt.Sprint(test.want) || test.m.Len() != len(test.want) {
+			t.Errorf("version has %v (Len %d), want %v", got, test.m.Len(), test.want)
+		}
+	}
+	if v3.Delete("Bob") != v3 {
+		t.Error("deleting a missing key made a new map")
+	}
+	if v3.Get("Bob") != 0 || v3.Contains("Bob") || v2.Get("Bob") != 2 {
+		t.Error("Get and Contains disagree with Range")
+	}
+}
+
+// Diffing a version against its ancestor only visits the subtrees that changed.
+func TestPersistentSharing(t *testing.T) {
+	m := hashtables.NewPersistentMap[string, int](hashtables.StringKeys(hashtables.Jenkins)).Transient()
+	for i := 0; i < 10000; i++ {
+		m.Set(fmt.Sprint(i), i)
+	}
+	base := m.Persistent()
+	changed := base.Set("5", -5).Delete("7").Set("new", 1)
+
+	compared := 0
+	valueEqual := func(a, b int) bool {
+		compared++
+		return a == b
+	}
+	diff := base.Diff(changed, valueEqual)
+	if len(diff.Added) != 1 || len(diff.Removed) != 1 || len(diff.Changed) != 1 {
+		t.Errorf("diff %+v", diff)
+	}
+	if compared > 100 {
+		t.Errorf("compared %d values to find 3 changes", compared)
+	}
+
+	compared = 0
+	if !base.Equal(base.Set("5", 5), valueEqual) || base.Equal(changed, valueEqual) {
+		t.Error("Equal is wrong")
+	}
+	if compared > 100 {
+		t.Errorf("compared %d values to check equality", compared)
+	}
+}
+
+func TestTransient(t *testing.T) {
+	keys := hashtables.StringKeys(nil)
+	base := hashtables.NewPersistentMap[string, int](keys).Set("Ann", 1).Set("Bob", 2)
+
+	// A transient changes its own copies in place, never the map it started from.
+	builder := base.Transient()
+	for i := 0; i < 1000; i++ {
+		builder.Set(fmt.Sprint(i), i)
+	}
+	builder.Set("Ann", 100)
+	if !builder.Delete("Bob") || builder.Delete("Bob") {
+		t.Error("Delete(Bob) did not remove Bob once")
+	}
+	if value, ok := builder.Lookup("Ann"); !ok || value != 100 || builder.Len() != 1001 {
+		t.Errorf("the transient has Ann = %d and Len %d", value, builder.Len())
+	}
+	built := builder.Persistent()
+	if base.Len() != 2 || base.Get("Ann") != 1 || base.Get("Bob") != 2 {
+		t.Errorf("the transient changed its base: %v", persistentEntries(base))
+	}
+
+	// A second transient from the result doesn't change it either.
+	again := built.Transient()
+	for i := 0; i < 1000; i++ {
+		again.Set(fmt.Sprint(i), -i)
+	}
+	if built.Get("999") != 999 || again.Persistent().Get("999") != -999 {
+		t.Error("a second transient changed the map it started from")
+	}
+
+	// Building with a transient gives the same map as setting keys one at a time.
+	oneByOne := base.Delete("Bob").Set("Ann", 100)
+	for i := 0; i < 1000; i++ {
+		oneByOne = oneByOne.Set(fmt.Sprint(i), i)
+	}
+	if !built.Equal(oneByOne, nil) || !oneByOne.Equal(built, nil) {
+		t.Errorf("the maps differ: %+v", built.Diff(oneByOne, nil))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("a finished transient could still be changed")
+		}
+	}()
+	builder.Set("late", 1)
+}
+
+// Keys whose full hash codes collide share a leaf.
+func TestPersistentCollisions(t *testing.T) {
+	constant := hashtables.OrderedKeys(func(key int) int { return 7 })
+	m := hashtables.NewPersistentMap[int, string](constant)
+	for i := 0; i < 10; i++ {
+		m = m.Set(i, fmt.Sprint(i))
+	}
+	smaller := m.Delete(3).Delete(8)
+	if m.Len() != 10 || smaller.Len() != 8 || smaller.Contains(3) || !smaller.Contains(4) || m.Get(3) != "3" {
+		t.Errorf("Len %d and %d after deleting colliding keys", m.Len(), smaller.Len())
+	}
+	diff := m.Diff(smaller.Set(4, "four"), nil)
+	if len(diff.Removed) != 2 || len(diff.Changed) != 1 || diff.Changed[0].Key != 4 {
+		t.Errorf("diff %+v", diff)
+	}
+}
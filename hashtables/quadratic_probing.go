@@ -0,0 +1,26 @@
+package hashtables
+
+// QuadraticProbingMap probes slots at square offsets from the key's home slot.
+type QuadraticProbingMap[K any, V any] struct {
+	openAddressing[K, V]
+}
+
+// QuadraticProbingHashTable is the liveProject's quadratic probing table of names and phone numbers.
+type QuadraticProbingHashTable = QuadraticProbingMap[string, string]
+
+// NewQuadraticProbingMap Initialize a QuadraticProbingMap and return a pointer to it.
+func NewQuadraticProbingMap[K any, V any](capacity int, keys KeyHasher[K]) *QuadraticProbingMap[K, V] {
+	return &QuadraticProbingMap[K, V]{
+		openAddressing: newOpenAddressing[K, V](capacity, keys, nil, quadraticIndex),
+	}
+}
+
+// NewQuadraticProbingHashTable Initialize a QuadraticProbingHashTable and return a pointer to it.
+func NewQuadraticProbingHashTable(capacity int, hash HashFunc) *QuadraticProbingHashTable {
+	return NewQuadraticProbingMap[string, string](capacity, StringKeys(hash))
+}
+
+// quadraticIndex returns the index of the quadratic probe sequence.
+func quadraticIndex(hash int, step int, i int, capacity int) int {
+	return (hash + i*i) % capacity
+}
@@ -0,0 +1,254 @@
+// Package quality measures how well hash functions spread a set of keys: how evenly
+// they fill a table's slots, how often they collide, how much each input bit affects
+// each output bit, and, for double hashing, how independent two functions are.
+//
+// The tables reduce hash codes modulo capacities far below 2^32, so the avalanche
+// test looks only at the low 32 bits of each hash.
+package quality
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+
+	"hashtables"
+)
+
+const (
+	// avalancheBits is the number of low output bits the avalanche test watches.
+	avalancheBits = 32
+	// avalancheBytes is the number of leading bytes of each key whose bits are flipped.
+	avalancheBytes = 16
+	// avalancheMinTrials is the number of flips an input and output bit pair needs
+	// before its bias counts, so sampling noise doesn't swamp the worst bias.
+	avalancheMinTrials = 100
+)
+
+// Avalanche is the result of flipping single input bits and counting the output bits
+// that change. A good hash changes each output bit half the time.
+type Avalanche struct {
+	Flips    int     // Input bits flipped.
+	Mean     float64 // Fraction of output bits that changed, ideally 0.5.
+	MeanBias float64 // Average distance from 0.5 over input bit and output bit pairs, ideally near 0.
+	// WorstBias is the largest distance from 0.5 for any pair. It is 0.5 when flipping
+	// an input bit always flips some output bit, as it does for the last byte of a key
+	// in hash functions without a final mixing step.
+	WorstBias float64
+}
+
+// Uniformity is a chi-squared test of how evenly keys fill capacity buckets when hash
+// codes are reduced with hash % capacity, as the tables do.
+type Uniformity struct {
+	Capacity   int
+	ChiSquared float64
+	// PValue is the chance a truly random hash spreads the keys at least this unevenly.
+	// Values near 0 mean the hash favors some buckets, and values near 1 that it
+	// spreads keys more evenly than chance, as sequential keys can.
+	PValue    float64
+	MaxBucket int // The most keys in any bucket.
+}
+
+// Collisions counts keys that share a full hash code with an earlier key.
+// No table can tell those keys apart without comparing them.
+type Collisions struct {
+	Keys       int
+	Collisions int
+	Expected   float64 // The number a random 63-bit hash would give.
+}
+
+// Report holds the measurements of one hash function.
+type Report struct {
+	Name       string
+	Avalanche  Avalanche
+	Uniformity Uniformity
+	Collisions Collisions
+}
+
+// Pair measures two hash functions used together for double hashing, where hash1
+// picks the home slot and hash2 the step. If they are correlated, keys that share a
+// home slot tend to share a step too, and then they probe the same slots.
+type Pair struct {
+	Hash1, Hash2 string
+	// Correlation is the Pearson correlation of the home slots and steps, ideally near 0.
+	Correlation float64
+	// SharedSequences counts pairs of keys with the same home slot and step, whose
+	// probe sequences are identical, and Expected is the count for independent hashes.
+	SharedSequences int
+	Expected        float64
+}
+
+// Measure one hash function on the keys with a table of this capacity.
+func Analyze(name string, hash hashtables.HashFunc, keys []string, capacity int) Report {
+	return Report{
+		Name:       name,
+		Avalanche:  MeasureAvalanche(hash, keys),
+		Uniformity: MeasureUniformity(hash, keys, capacity),
+		Collisions: CountCollisions(hash, keys),
+	}
+}
+
+// Flip each of the low 7 bits of each key's first bytes, which keeps ASCII keys valid
+// UTF-8, and count how often each of the low output bits changes.
+func MeasureAvalanche(hash hashtables.HashFunc, keys []string) Avalanche {
+	var changed, trials [avalancheBytes * 7][avalancheBits]int
+	result := Avalanche{}
+	totalChanged := 0
+	for _, key := range keys {
+		original := uint64(hash(key))
+		bytes := []byte(key)
+		for i := 0; i < min(len(bytes), avalancheBytes); i++ {
+			for bit := 0; bit < 7; bit++ {
+				bytes[i] ^= 1 << bit
+				diff := original ^ uint64(hash(string(bytes)))
+				bytes[i] ^= 1 << bit
+
+				input := i*7 + bit
+				for output := 0; output < avalancheBits; output++ {
+					trials[input][output]++
+					if diff>>output&1 != 0 {
+						changed[input][output]++
+						totalChanged++
+					}
+				}
+				result.Flips++
+			}
+		}
+	}
+	if result.Flips == 0 {
+		return result
+	}
+	result.Mean = float64(totalChanged) / float64(result.Flips*avalancheBits)
+	pairs := 0
+	for input := range trials {
+		for output, n := range trials[input] {
+			if n >= avalancheMinTrials {
+				bias := math.Abs(float64(changed[input][output])/float64(n) - 0.5)
+				result.MeanBias += bias
+				result.WorstBias = max(result.WorstBias, bias)
+				pairs++
+			}
+		}
+	}
+	if pairs > 0 {
+		result.MeanBias /= float64(pairs)
+	}
+	return result
+}
+
+// Count the keys in each of capacity buckets and compare the counts with the
+// even spread a random hash would give.
+func MeasureUniformity(hash hashtables.HashFunc, keys []string, capacity int) Uniformity {
+	counts := make([]int, capacity)
+	for _, key := range keys {
+		counts[reduce(hash(key), capacity)]++
+	}
+	result := Uniformity{Capacity: capacity}
+	expected := float64(len(keys)) / float64(capacity)
+	for _, count := range counts {
+		result.ChiSquared += (float64(count) - expected) * (float64(count) - expected) / expected
+		result.MaxBucket = max(result.MaxBucket, count)
+	}
+	result.PValue = chiSquaredPValue(result.ChiSquared, capacity-1)
+	return result
+}
+
+// Return the chance of a chi-squared value at least this large with these degrees of
+// freedom, using the Wilson-Hilferty normal approximation, which is close for the
+// hundreds of degrees of freedom a table has.
+func chiSquaredPValue(chiSquared float64, degrees int) float64 {
+	if degrees <= 0 {
+		return 1
+	}
+	k := float64(degrees)
+	z := (math.Cbrt(chiSquared/k) - (1 - 2/(9*k))) / math.Sqrt(2/(9*k))
+	return 0.5 * math.Erfc(z/math.Sqrt2)
+}
+
+// Count the keys whose hash code matches an earlier key's.
+func CountCollisions(hash hashtables.HashFunc, keys []string) Collisions {
+	seen := make(map[int]bool, len(keys))
+	result := Collisions{Keys: len(keys)}
+	for _, key := range keys {
+		code := hash(key)
+		if seen[code] {
+			result.Collisions++
+		}
+		seen[code] = true
+	}
+	n := float64(len(keys))
+	result.Expected = n * (n - 1) / 2 / math.Exp2(63)
+	return result
+}
+
+// Measure how independent two hash functions are when hash1 picks the home slot and
+// hash2 the step in a double hashing table of this capacity.
+func MeasurePair(name1 string, hash1 hashtables.HashFunc, name2 string, hash2 hashtables.HashFunc,
+	keys []string, capacity int) Pair {
+	type sequence struct{ home, step int }
+	sequences := make(map[sequence]int)
+	var sumHome, sumStep, sumHomeHome, sumStepStep, sumHomeStep float64
+	for _, key := range keys {
+		home, step := reduce(hash1(key), capacity), reduce(hash2(key), capacity)
+		// A step of 0 would probe the home slot forever, so the tables use 1.
+		if step == 0 {
+			step = 1
+		}
+		sequences[sequence{home, step}]++
+
+		h, s := float64(home), float64(step)
+		sumHome += h
+		sumStep += s
+		sumHomeHome += h * h
+		sumStepStep += s * s
+		sumHomeStep += h * s
+	}
+
+	result := Pair{Hash1: name1, Hash2: name2}
+	for _, count := range sequences {
+		result.SharedSequences += count * (count - 1) / 2
+	}
+	n := float64(len(keys))
+	if capacity > 1 {
+		result.Expected = n * (n - 1) / 2 / (float64(capacity) * float64(capacity-1))
+	}
+	covariance := n*sumHomeStep - sumHome*sumStep
+	spread := math.Sqrt(n*sumHomeHome-sumHome*sumHome) * math.Sqrt(n*sumStepStep-sumStep*sumStep)
+	if spread > 0 {
+		result.Correlation = covariance / spread
+	}
+	return result
+}
+
+// Reduce a hash code to an index from 0 to n-1, as the tables do.
+func reduce(hash int, n int) int {
+	index := hash % n
+	if index < 0 {
+		index += n
+	}
+	return index
+}
+
+// Write the reports as a table with one row per hash function, then a table of the pairs.
+func WriteReport(w io.Writer, reports []Report, pairs []Pair) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "hash\tavalanche\tmean bias\tworst bias\tchi-squared\tp-value\tmax bucket\tcollisions\texpected\t")
+	for _, report := range reports {
+		fmt.Fprintf(tw, "%s\t%.3f\t%.3f\t%.3f\t%.1f\t%.3f\t%d\t%d\t%.2g\t\n",
+			report.Name, report.Avalanche.Mean, report.Avalanche.MeanBias, report.Avalanche.WorstBias,
+			report.Uniformity.ChiSquared, report.Uniformity.PValue, report.Uniformity.MaxBucket,
+			report.Collisions.Collisions, report.Collisions.Expected)
+	}
+	if err := tw.Flush(); err != nil || len(pairs) == 0 {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "hash1\thash2\tcorrelation\tshared sequences\texpected\t")
+	for _, pair := range pairs {
+		fmt.Fprintf(tw, "%s\t%s\t%.3f\t%d\t%.1f\t\n",
+			pair.Hash1, pair.Hash2, pair.Correlation, pair.SharedSequences, pair.Expected)
+	}
+	return tw.Flush()
+}
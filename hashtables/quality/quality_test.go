@@ -0,0 +1,179 @@
+package quality
+
+import (
+	"bytes"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"hashtables"
+)
+
+// A hash that returns the key's number, so tests can place keys exactly.
+func number(key string) int {
+	n, err := strconv.Atoi(key)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func constant(key string) int {
+	return 42
+}
+
+var seed = maphash.MakeSeed()
+
+// A well-mixed hash to compare the known answers with.
+func random(key string) int {
+	return int(maphash.String(seed, key))
+}
+
+// Return the keys "0" to "n-1".
+func numberKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	return keys
+}
+
+func closeTo(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func TestCountCollisions(t *testing.T) {
+	keys := numberKeys(1000)
+	if got := CountCollisions(constant, keys); got.Keys != 1000 || got.Collisions != 999 {
+		t.Errorf("a constant hash gives %+v, want 999 collisions", got)
+	}
+	got := CountCollisions(number, keys)
+	if got.Collisions != 0 || !closeTo(got.Expected, 1000*999/2/math.Exp2(63), 1e-20) {
+		t.Errorf("a distinct hash gives %+v", got)
+	}
+}
+
+func TestMeasureUniformity(t *testing.T) {
+	// Each of 10 buckets gets exactly 100 keys.
+	perfect := MeasureUniformity(number, numberKeys(1000), 10)
+	if perfect.ChiSquared != 0 || perfect.MaxBucket != 100 || perfect.PValue < 0.999 {
+		t.Errorf("a perfect spread gives %+v", perfect)
+	}
+
+	// Every key in one bucket gives n (capacity - 1).
+	worst := MeasureUniformity(constant, numberKeys(1000), 10)
+	if !closeTo(worst.ChiSquared, 9000, 1e-9) || worst.MaxBucket != 1000 || worst.PValue > 1e-9 {
+		t.Errorf("a constant hash gives %+v", worst)
+	}
+
+	// Negative hash codes still land in range.
+	negative := MeasureUniformity(func(key string) int { return -number(key) }, numberKeys(1000), 10)
+	if negative.ChiSquared != 0 {
+		t.Errorf("negative hash codes give %+v", negative)
+	}
+
+	mixed := MeasureUniformity(random, numberKeys(100000), 1009)
+	if mixed.PValue < 0.0001 || mixed.PValue > 0.9999 {
+		t.Errorf("a random hash gives %+v", mixed)
+	}
+}
+
+func TestChiSquaredPValue(t *testing.T) {
+	// Critical values from a chi-squared table.
+	for _, test := range []struct {
+		chiSquared float64
+		degrees    int
+		want       float64
+	}{
+		{124.342, 100, 0.05},
+		{135.807, 100, 0.01},
+		{82.358, 100, 0.9},
+		{1106.669, 1000, 0.01},
+		{927.594, 1000, 0.95},
+	} {
+		if got := chiSquaredPValue(test.chiSquared, test.degrees); !closeTo(got, test.want, 0.002) {
+			t.Errorf("chiSquaredPValue(%v, %d) = %.4f, want %v", test.chiSquared, test.degrees, got, test.want)
+		}
+	}
+	if got := chiSquaredPValue(5, 0); got != 1 {
+		t.Errorf("no degrees of freedom gives %v", got)
+	}
+}
+
+func TestMeasurePair(t *testing.T) {
+	// Each of the steps 1 to 99 comes from two keys, 1 to 99 and 101 to 199.
+	var keys []string
+	for i := 1; i < 200; i++ {
+		if i != 100 {
+			keys = append(keys, strconv.Itoa(i))
+		}
+	}
+	same := MeasurePair("number", number, "number", number, keys, 100)
+	if !closeTo(same.Correlation, 1, 1e-9) || same.SharedSequences != 99 {
+		t.Errorf("a hash paired with itself gives %+v", same)
+	}
+	if want := 198.0 * 197 / 2 / (100 * 99); !closeTo(same.Expected, want, 1e-9) {
+		t.Errorf("Expected = %v, want %v", same.Expected, want)
+	}
+	negated := MeasurePair("number", number, "negated", func(key string) int { return -number(key) }, keys, 100)
+	if !closeTo(negated.Correlation, -1, 1e-9) {
+		t.Errorf("a hash paired with its negation gives %+v", negated)
+	}
+
+	// A step of 0 is taken as 1, so these keys share the home slot 0 and step 1.
+	zero := MeasurePair("constant", func(string) int { return 0 }, "number", number, []string{"0", "1", "100"}, 100)
+	if zero.SharedSequences != 3 || zero.Correlation != 0 {
+		t.Errorf("steps of 0 give %+v", zero)
+	}
+
+	independent := MeasurePair("number", number, "random", random, numberKeys(10000), 1009)
+	if !closeTo(independent.Correlation, 0, 0.05) {
+		t.Errorf("a random step gives %+v", independent)
+	}
+}
+
+func TestMeasureAvalanche(t *testing.T) {
+	keys := make([]string, 200)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("employee-%05d", i)
+	}
+
+	// No flip changes a constant hash.
+	got := MeasureAvalanche(constant, keys)
+	if got.Flips != 200*14*7 || got.Mean != 0 || got.MeanBias != 0.5 || got.WorstBias != 0.5 {
+		t.Errorf("a constant hash gives %+v", got)
+	}
+
+	// A hash that returns the key's first byte changes exactly the flipped bit,
+	// and only when the first byte is flipped.
+	first := MeasureAvalanche(func(key string) int { return int(key[0]) }, keys)
+	if want := 7.0 / (14 * 7 * 32); !closeTo(first.Mean, want, 1e-12) || first.WorstBias != 0.5 {
+		t.Errorf("the first byte gives %+v, want a mean of %v", first, want)
+	}
+
+	mixed := MeasureAvalanche(random, keys)
+	if !closeTo(mixed.Mean, 0.5, 0.01) || mixed.MeanBias > 0.05 || mixed.WorstBias > 0.2 {
+		t.Errorf("a random hash gives %+v", mixed)
+	}
+
+	if empty := MeasureAvalanche(constant, nil); empty != (Avalanche{}) {
+		t.Errorf("no keys give %+v", empty)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	keys := numberKeys(100)
+	var out bytes.Buffer
+	err := WriteReport(&out, []Report{Analyze("djb2", hashtables.DJB2, keys, 13)},
+		[]Pair{MeasurePair("djb2", hashtables.DJB2, "fnv1a", hashtables.FNV1a, keys, 13)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 || !strings.Contains(lines[1], "djb2") || !strings.Contains(lines[4], "fnv1a") {
+		t.Errorf("report:\n%s", out.String())
+	}
+}
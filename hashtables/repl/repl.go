@@ -0,0 +1,523 @@
+// Package repl runs an interactive command loop over a hash table.
+// The same loop reads scripts from any io.Reader, so a session can be replayed.
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"hashtables"
+)
+
+// Session holds the table being explored and the commands entered so far.
+type Session struct {
+	Table    hashtables.Table
+	Strategy string
+	HashName string
+	KeyMode  string // How names are compared, from hashtables.KeyModes.
+	Out      io.Writer
+	Prompt   string
+	History  []string
+
+	// The file and recorder for the record command, and the recorder's first error.
+	recordFile *os.File
+	recorder   hashtables.Recorder
+	recordErr  func() error
+}
+
+// errQuit stops the command loop.
+var errQuit = errors.New("quit")
+
+// Make a session around a new table.
+func NewSession(strategy string, capacity int, hashName string, keyMode string, out io.Writer) (*Session, error) {
+	session := &Session{Out: out}
+	if err := session.newTable(strategy, capacity, hashName, keyMode); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Replace the session's table with a new, empty one.
+func (session *Session) newTable(strategy string, capacity int, hashName string, keyMode string) error {
+	hash, err := hashtables.LookupHasher(hashName)
+	if err != nil {
+		return err
+	}
+	if keyMode == "" {
+		keyMode = "exact"
+	}
+	table, err := hashtables.NewKeyedTable(strategy, capacity, hash, keyMode)
+	if err != nil {
+		return err
+	}
+	session.Table = table
+	session.Strategy = strategy
+	session.HashName = hashName
+	session.KeyMode = keyMode
+
+	// Keep recording into the new table.
+	if session.recorder != nil {
+		session.recordNew()
+		table.SetRecorder(session.recorder)
+	}
+	return nil
+}
+
+// Record the event that describes the session's table.
+func (session *Session) recordNew() {
+	// Exact names are the default, so leave them out.
+	keyMode := session.KeyMode
+	if keyMode == "exact" {
+		keyMode = ""
+	}
+	session.recorder(hashtables.Event{
+		Kind:     hashtables.EventNew,
+		Slot:     -1,
+		Capacity: session.Table.Capacity(),
+		Strategy: session.Strategy,
+		Hash:     session.HashName,
+		Keys:     keyMode,
+	})
+}
+
+// Stop recording and close the record file.
+func (session *Session) stopRecording() error {
+	if session.recordFile == nil {
+		return nil
+	}
+	session.Table.SetRecorder(nil)
+	err := session.recordErr()
+	if closeErr := session.recordFile.Close(); err == nil {
+		err = closeErr
+	}
+	session.recordFile = nil
+	session.recorder = nil
+	session.recordErr = nil
+	return err
+}
+
+// Run reads commands until the input ends or a quit command.
+// Errors from individual commands are reported and the loop continues.
+func (session *Session) Run(in io.Reader) error {
+	defer session.stopRecording()
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(session.Out, session.Prompt)
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		err := session.Execute(scanner.Text())
+		if err == errQuit {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(session.Out, "error: %v\n", err)
+		}
+	}
+}
+
+// Execute runs one command line and records it in the history.
+func (session *Session) Execute(line string) (err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	// Expand history references before recording the line.
+	if strings.HasPrefix(line, "!") {
+		line, err = session.recall(line)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(session.Out, line)
+	}
+	session.History = append(session.History, line)
+
+	// Open addressing tables panic when they run out of slots. Anything else is a bug.
+	defer func() {
+		if p := recover(); p != nil {
+			if p != "Hash table is full" {
+				panic(p)
+			}
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+
+	args, err := split(line)
+	if err != nil {
+		return err
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q (try help)", args[0])
+	}
+	return command.run(session, args[1:])
+}
+
+// Return the history entry that a !! or !n reference names.
+func (session *Session) recall(line string) (string, error) {
+	if len(session.History) == 0 {
+		return "", errors.New("history is empty")
+	}
+	if line == "!!" {
+		return session.History[len(session.History)-1], nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(session.History) {
+		return "", fmt.Errorf("no history entry %q", line)
+	}
+	return session.History[n-1], nil
+}
+
+// Split a command line into words. Double quotes group words that contain spaces.
+func split(line string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord, quoted := false, false
+	for _, ch := range line {
+		switch {
+		case ch == '"':
+			quoted = !quoted
+			inWord = true
+		case ch == ' ' || ch == '\t':
+			if quoted {
+				word.WriteRune(ch)
+			} else if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(ch)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
+
+// command describes one REPL command.
+type command struct {
+	usage string
+	help  string
+	run   func(session *Session, args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"new":      {"new <strategy> <capacity> [hash] [keys]", "start over with an empty table", cmdNew},
+		"set":      {"set <name> <phone>", "add or update an entry", cmdSet},
+		"get":      {"get <name>", "show an entry's phone", cmdGet},
+		"contains": {"contains <name>", "report whether a name is present", cmdContains},
+		"del":      {"del <name>", "delete an entry", cmdDel},
+		"probe":    {"probe <name>", "show a name's probe sequence", cmdProbe},
+		"dump":     {"dump", "show every slot or bucket", cmdDump},
+		"concise":  {"concise", "show occupancy, 50 slots per row", cmdConcise},
+		"stats":    {"stats", "show probe and cluster statistics", cmdStats},
+		"resize":   {"resize <capacity>", "rehash into a new capacity", cmdResize},
+		"migrate":  {"migrate <slots>", "make later resizes move this many slots per operation (0: all at once)", cmdMigrate},
+		"grow":     {"grow <max-load>", "split chaining buckets one at a time past this load (0: off)", cmdGrow},
+		"buckets":  {"buckets <kind>", "store chaining buckets as a slice, list, sorted slice or tree", cmdBuckets},
+		"load":     {"load <file>", "set each \"name,phone\" or \"name\" line in a file", cmdLoad},
+		"record":   {"record <file>|off", "write later steps to a JSON lines file", cmdRecord},
+		"replay":   {"replay <file> [n]", "rebuild the table from the first n recorded operations", cmdReplay},
+		"info":     {"info", "show the table's strategy, hash and size", cmdInfo},
+		"history":  {"history", "list earlier commands; !n or !! repeats one", cmdHistory},
+		"help":     {"help", "list commands", cmdHelp},
+		"quit":     {"quit", "leave the REPL", cmdQuit},
+	}
+}
+
+// Join the arguments into a name, so names with spaces need no quotes.
+func nameArg(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", errors.New("missing name")
+	}
+	return strings.Join(args, " "), nil
+}
+
+func cmdNew(session *Session, args []string) error {
+	if len(args) < 2 || len(args) > 4 {
+		return errors.New("usage: " + commands["new"].usage)
+	}
+	capacity, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("bad capacity %q", args[1])
+	}
+	hashName := session.HashName
+	if len(args) >= 3 {
+		hashName = args[2]
+	}
+	keyMode := session.KeyMode
+	if len(args) == 4 {
+		keyMode = args[3]
+	}
+	return session.newTable(args[0], capacity, hashName, keyMode)
+}
+
+func cmdSet(session *Session, args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: " + commands["set"].usage)
+	}
+
+	// The last word is the phone. Everything before it is the name.
+	name := strings.Join(args[:len(args)-1], " ")
+	session.Table.Set(name, args[len(args)-1])
+	return nil
+}
+
+func cmdGet(session *Session, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+
+	phone, ok := session.Table.Lookup(name)
+	if !ok {
+		phone = "not found"
+	}
+	fmt.Fprintf(session.Out, "%s: %s\n", name, phone)
+	return nil
+}
+
+func cmdContains(session *Session, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(session.Out, "Table contains %s: %t\n", name, session.Table.Contains(name))
+	return nil
+}
+
+func cmdDel(session *Session, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	session.Table.Delete(name)
+	return nil
+}
+
+func cmdProbe(session *Session, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	session.Table.Probe(session.Out, name)
+	return nil
+}
+
+func cmdDump(session *Session, args []string) error {
+	session.Table.Dump(session.Out)
+	return nil
+}
+
+func cmdConcise(session *Session, args []string) error {
+	session.Table.DumpConcise(session.Out)
+	return nil
+}
+
+func cmdStats(session *Session, args []string) error {
+	session.Table.Stats().Dump(session.Out)
+	return nil
+}
+
+func cmdResize(session *Session, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + commands["resize"].usage)
+	}
+	capacity, err := strconv.Atoi(args[0])
+	if err != nil || capacity <= 0 {
+		return fmt.Errorf("bad capacity %q", args[0])
+	}
+	session.Table.Resize(capacity)
+	return nil
+}
+
+func cmdMigrate(session *Session, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + commands["migrate"].usage)
+	}
+	slotsPerOp, err := strconv.Atoi(args[0])
+	if err != nil || slotsPerOp < 0 {
+		return fmt.Errorf("bad slot count %q", args[0])
+	}
+	session.Table.SetIncrementalResize(slotsPerOp)
+	return nil
+}
+
+func cmdGrow(session *Session, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + commands["grow"].usage)
+	}
+	maxLoad, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || maxLoad < 0 {
+		return fmt.Errorf("bad load factor %q", args[0])
+	}
+	growing, ok := session.Table.(interface{ SetLinearHashing(maxLoad float64) })
+	if !ok {
+		return fmt.Errorf("%s tables can't grow by linear hashing", session.Strategy)
+	}
+	growing.SetLinearHashing(maxLoad)
+	return nil
+}
+
+func cmdBuckets(session *Session, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + commands["buckets"].usage)
+	}
+	kind, err := hashtables.ParseBucketKind(args[0])
+	if err != nil {
+		return err
+	}
+	chained, ok := session.Table.(interface {
+		SetBuckets(kind hashtables.BucketKind)
+	})
+	if !ok {
+		return fmt.Errorf("%s tables have no buckets", session.Strategy)
+	}
+	chained.SetBuckets(kind)
+	return nil
+}
+
+func cmdLoad(session *Session, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + commands["load"].usage)
+	}
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Each line is "name,phone". A bare name is stored as its own phone,
+	// like the keys in the clustering experiments.
+	numLoaded := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, phone, found := strings.Cut(line, ",")
+		name = strings.TrimSpace(name)
+		phone = strings.TrimSpace(phone)
+		if !found {
+			phone = name
+		}
+		session.Table.Set(name, phone)
+		numLoaded++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	fmt.Fprintf(session.Out, "Loaded %d entries\n", numLoaded)
+	return nil
+}
+
+func cmdRecord(session *Session, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + commands["record"].usage)
+	}
+	if err := session.stopRecording(); err != nil {
+		return err
+	}
+	if args[0] == "off" {
+		return nil
+	}
+
+	// A replay starts from an empty table, so recording must too.
+	if session.Table.Len() > 0 {
+		return errors.New("the table is not empty; use new before record")
+	}
+	file, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	session.recordFile = file
+	session.recorder, session.recordErr = hashtables.NewJSONRecorder(file)
+	session.recordNew()
+	session.Table.SetRecorder(session.recorder)
+	return nil
+}
+
+func cmdReplay(session *Session, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: " + commands["replay"].usage)
+	}
+	numOps := -1
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			return fmt.Errorf("bad operation count %q", args[1])
+		}
+		numOps = n
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	events, err := hashtables.ReadEvents(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	// Rebuild the recorded table, or an empty copy of the current one.
+	strategy, capacity, hashName, keyMode := session.Strategy, session.Table.Capacity(), session.HashName, session.KeyMode
+	if len(events) > 0 && events[0].Kind == hashtables.EventNew {
+		strategy, capacity, hashName, keyMode = events[0].Strategy, events[0].Capacity, events[0].Hash, events[0].Keys
+	}
+	if err := session.newTable(strategy, capacity, hashName, keyMode); err != nil {
+		return err
+	}
+	last := hashtables.Replay(session.Table, events, numOps)
+	if last.Kind != "" {
+		fmt.Fprintln(session.Out, strings.TrimSpace("Replayed through "+string(last.Kind)+" "+last.Key))
+	}
+	return nil
+}
+
+func cmdInfo(session *Session, args []string) error {
+	fmt.Fprintf(session.Out, "%s table, %s hash, %s keys, %d entries, capacity %d\n",
+		session.Strategy, session.HashName, session.KeyMode, session.Table.Len(), session.Table.Capacity())
+	return nil
+}
+
+func cmdHistory(session *Session, args []string) error {
+	for i, line := range session.History {
+		fmt.Fprintf(session.Out, "%4d  %s\n", i+1, line)
+	}
+	return nil
+}
+
+func cmdHelp(session *Session, args []string) error {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(session.Out, "  %-36s %s\n", commands[name].usage, commands[name].help)
+	}
+	return nil
+}
+
+func cmdQuit(session *Session, args []string) error {
+	return errQuit
+}
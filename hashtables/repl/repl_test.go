@@ -0,0 +1,126 @@
+package repl
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Run a script in a new session and return what it wrote.
+func runScript(t *testing.T, strategy string, capacity int, script string) string {
+	t.Helper()
+	var out bytes.Buffer
+	session, err := NewSession(strategy, capacity, "djb2", "", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Run(strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestScriptedSession(t *testing.T) {
+	script := `
+# Comments and blank lines are skipped.
+set Ann Smith 555-1234
+get Ann Smith
+set x ""
+get x
+get nobody
+contains x
+del x
+contains x
+probe Ann Smith
+resize 5
+!!
+history
+bogus
+set onlyname
+info
+quit
+get after
+`
+	// A blank phone is still found.
+	want := `Ann Smith: 555-1234
+x: ` + `
+nobody: not found
+Table contains x: true
+Table contains x: false
+Probing Ann Smith (2)
+    2: Ann Smith
+    Returning found index 2
+resize 5
+   1  set Ann Smith 555-1234
+   2  get Ann Smith
+   3  set x ""
+   4  get x
+   5  get nobody
+   6  contains x
+   7  del x
+   8  contains x
+   9  probe Ann Smith
+  10  resize 5
+  11  resize 5
+  12  history
+error: unknown command "bogus" (try help)
+error: usage: set <name> <phone>
+linear table, djb2 hash, exact keys, 1 entries, capacity 5
+`
+	if got := runScript(t, "linear", 7, script); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+// A full table reports an error instead of ending the session.
+func TestFullTable(t *testing.T) {
+	got := runScript(t, "linear", 2, "set a 1\nset b 2\nset c 3\nget a\n")
+	want := "error: Hash table is full\na: 1\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// Any other panic is a bug, so it isn't turned into an error.
+func TestCommandPanic(t *testing.T) {
+	commands["crash"] = command{"crash", "index past the end of a slice", func(*Session, []string) error {
+		var args []string
+		_ = args[1]
+		return nil
+	}}
+	defer delete(commands, "crash")
+	defer func() {
+		if recover() == nil {
+			t.Error("the panic became an error")
+		}
+	}()
+	runScript(t, "linear", 7, "crash\n")
+}
+
+// A recorded session replays into the same table.
+func TestRecordReplay(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "session.jsonl")
+	for _, strategy := range []string{"chaining", "linear", "double", "coalesced"} {
+		t.Run(strategy, func(t *testing.T) {
+			original := runScript(t, strategy, 7, "record "+file+`
+set Ann 1
+set Bob 2
+set Cid 3
+del Bob
+resize 11
+set Dee 4
+record off
+dump
+`)
+			replayed := runScript(t, "linear", 3, "replay "+file+"\ndump\n")
+			replayed, ok := strings.CutPrefix(replayed, "Replayed through set Dee\n")
+			if !ok || original != replayed {
+				t.Errorf("dump after replay\n%s\nwant\n%s", replayed, original)
+			}
+			if !strings.Contains(original, "Dee") || strings.Contains(original, "Bob") {
+				t.Errorf("unexpected dump\n%s", original)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package hashtables
+
+import (
+	"io"
+)
+
+// Set holds keys without values in a Map built with one of the table strategies.
+// Each key is stored next to its hash code, so operations between sets that share
+// a KeyHasher never hash a key twice.
+type Set[K any] struct {
+	strategy string
+	keys     KeyHasher[K]
+	table    Map[setEntry[K], setMember]
+}
+
+// setEntry is a key and its full hash code.
+type setEntry[K any] struct {
+	key  K
+	hash int
+}
+
+// Display the key alone in the table's dumps.
+func (entry setEntry[K]) String() string {
+	return format(entry.key)
+}
+
+// setMember is the value a set stores for each key. It displays as nothing.
+type setMember struct{}
+
+func (setMember) String() string {
+	return ""
+}
+
+// setEntryKeys hashes set entries with their stored hash codes and compares their keys.
+type setEntryKeys[K any] struct {
+	keys KeyHasher[K]
+}
+
+func (entryKeys *setEntryKeys[K]) Hash(entry setEntry[K]) int {
+	return entry.hash
+}
+
+func (entryKeys *setEntryKeys[K]) Equal(a setEntry[K], b setEntry[K]) bool {
+	return a.hash == b.hash && entryKeys.keys.Equal(a.key, b.key)
+}
+
+// Initialize a Set that uses chaining and return a pointer to it.
+func NewChainingSet[K any](numBuckets int, keys KeyHasher[K]) *Set[K] {
+	return newSet(keys, "chaining", NewChainingMap[setEntry[K], setMember](numBuckets, &setEntryKeys[K]{keys}))
+}
+
+// Initialize a Set that uses flat-array chaining and return a pointer to it.
+func NewFlatChainingSet[K any](numBuckets int, keys KeyHasher[K]) *Set[K] {
+	return newSet(keys, "flat", NewFlatChainingMap[setEntry[K], setMember](numBuckets, &setEntryKeys[K]{keys}))
+}
+
+// Initialize a Set that uses linear probing and return a pointer to it.
+func NewLinearProbingSet[K any](capacity int, keys KeyHasher[K]) *Set[K] {
+	return newSet(keys, "linear", NewLinearProbingMap[setEntry[K], setMember](capacity, &setEntryKeys[K]{keys}))
+}
+
+// Initialize a Set that uses quadratic probing and return a pointer to it.
+func NewQuadraticProbingSet[K any](capacity int, keys KeyHasher[K]) *Set[K] {
+	return newSet(keys, "quadratic", NewQuadraticProbingMap[setEntry[K], setMember](capacity, &setEntryKeys[K]{keys}))
+}
+
+// Initialize a Set that uses double hashing and return a pointer to it.
+// The step size comes from a remix of the key's hash.
+func NewDoubleHashSet[K any](capacity int, keys KeyHasher[K]) *Set[K] {
+	return newSet(keys, "double", NewDoubleHashMap[setEntry[K], setMember](capacity, &setEntryKeys[K]{keys}, nil))
+}
+
+// Initialize a Set that uses coalesced hashing with the default cellar and return a pointer to it.
+func NewCoalescedSet[K any](capacity int, keys KeyHasher[K]) *Set[K] {
+	return newSet(keys, "coalesced", NewCoalescedMap[setEntry[K], setMember](capacity, &setEntryKeys[K]{keys}, 0))
+}
+
+func newSet[K any](keys KeyHasher[K], strategy string, table Map[setEntry[K], setMember]) *Set[K] {
+	return &Set[K]{strategy: strategy, keys: keys, table: table}
+}
+
+// Build a set using the named strategy.
+func NewSet[K any](strategy string, capacity int, keys KeyHasher[K]) (*Set[K], error) {
+	table, err := NewMap[setEntry[K], setMember](strategy, capacity, &setEntryKeys[K]{keys})
+	if err != nil {
+		return nil, err
+	}
+	return newSet(keys, strategy, table), nil
+}
+
+// Return an empty set with the same strategy and KeyHasher and this capacity.
+func (set *Set[K]) empty(capacity int) *Set[K] {
+	result, _ := NewSet(set.strategy, capacity, set.keys)
+	return result
+}
+
+// Return true if the set's table holds any number of keys in each bucket.
+func (set *Set[K]) chained() bool {
+	return set.strategy == "chaining" || set.strategy == "flat"
+}
+
+// Return the number of keys.
+func (set *Set[K]) Len() int {
+	return set.table.Len()
+}
+
+// Return the number of slots or buckets.
+func (set *Set[K]) Capacity() int {
+	return set.table.Capacity()
+}
+
+// Return the name of the set's strategy.
+func (set *Set[K]) Strategy() string {
+	return set.strategy
+}
+
+// Add the key. Return true if it was not already present.
+// A set that doesn't use chaining panics if it is full.
+func (set *Set[K]) Add(key K) bool {
+	return set.add(key, set.keys.Hash(key))
+}
+
+func (set *Set[K]) add(key K, hash int) bool {
+	count := set.table.Len()
+	set.table.Set(setEntry[K]{key, hash}, setMember{})
+	return set.table.Len() > count
+}
+
+// Return true if the key is in the set.
+func (set *Set[K]) Contains(key K) bool {
+	return set.contains(key, set.keys.Hash(key))
+}
+
+func (set *Set[K]) contains(key K, hash int) bool {
+	return set.table.Contains(setEntry[K]{key, hash})
+}
+
+// Remove the key. Return true if it was present.
+func (set *Set[K]) Remove(key K) bool {
+	count := set.table.Len()
+	set.table.Delete(setEntry[K]{key, set.keys.Hash(key)})
+	return set.table.Len() < count
+}
+
+// Call fn for each key and its hash code until fn returns false.
+func (set *Set[K]) each(fn func(key K, hash int) bool) {
+	set.table.Range(func(entry setEntry[K], _ setMember) bool {
+		return fn(entry.key, entry.hash)
+	})
+}
+
+// Call fn for each key until fn returns false.
+func (set *Set[K]) Range(fn func(key K) bool) {
+	set.each(func(key K, hash int) bool { return fn(key) })
+}
+
+// Return the keys in slot order.
+func (set *Set[K]) Keys() []K {
+	keys := make([]K, 0, set.Len())
+	set.Range(func(key K) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Rebuild the set with a new capacity, dropping deleted slots.
+// The keys keep their hash codes, so nothing is hashed again.
+func (set *Set[K]) Resize(capacity int) {
+	set.table.Resize(capacity)
+}
+
+// Return a copy of the set.
+func (set *Set[K]) Clone() *Set[K] {
+	clone := set.empty(set.Capacity())
+	set.each(func(key K, hash int) bool {
+		clone.add(key, hash)
+		return true
+	})
+	return clone
+}
+
+// Return true if both sets use the same KeyHasher, so their hash codes agree.
+// This is the fast path for the set operations: each key's stored hash code is
+// reused instead of hashing the key again. Capacity doesn't matter, because
+// every table places keys by their hash code and not by a slot copied from
+// another table, so sets of different sizes take the fast path too.
+func (set *Set[K]) sharesKeys(other *Set[K]) bool {
+	return sameKeyHasher(set.keys, other.keys)
+}
+
+// Return a function that reports whether other holds a key from set.
+// It reuses the key's hash code when the sets share a KeyHasher.
+func (set *Set[K]) memberOf(other *Set[K]) func(key K, hash int) bool {
+	if set.sharesKeys(other) {
+		return other.contains
+	}
+	return func(key K, hash int) bool {
+		return other.Contains(key)
+	}
+}
+
+// Return a new set with the keys in either set. It has this set's strategy and KeyHasher,
+// and grows if a set that doesn't use chaining would run out of room. Keys from a set
+// with the same KeyHasher keep their hash codes, whatever its capacity.
+func (set *Set[K]) Union(other *Set[K]) *Set[K] {
+	capacity := set.Capacity()
+	if !set.chained() {
+		capacity = max(capacity, set.Len()+other.Len())
+	}
+	result := set.empty(capacity)
+	set.each(func(key K, hash int) bool {
+		result.add(key, hash)
+		return true
+	})
+
+	shared := set.sharesKeys(other)
+	other.each(func(key K, hash int) bool {
+		if !shared {
+			hash = result.keys.Hash(key)
+		}
+		result.add(key, hash)
+		return true
+	})
+	return result
+}
+
+// Return a new set with the keys in both sets.
+func (set *Set[K]) Intersect(other *Set[K]) *Set[K] {
+	result := set.empty(set.Capacity())
+	inOther := set.memberOf(other)
+
+	// When the hash codes agree, walk the smaller set.
+	if set.sharesKeys(other) && other.Len() < set.Len() {
+		inSet := other.memberOf(set)
+		other.each(func(key K, hash int) bool {
+			if inSet(key, hash) {
+				result.add(key, hash)
+			}
+			return true
+		})
+		return result
+	}
+
+	set.each(func(key K, hash int) bool {
+		if inOther(key, hash) {
+			result.add(key, hash)
+		}
+		return true
+	})
+	return result
+}
+
+// Return a new set with the keys in this set that are not in the other.
+func (set *Set[K]) Difference(other *Set[K]) *Set[K] {
+	result := set.empty(set.Capacity())
+	inOther := set.memberOf(other)
+	set.each(func(key K, hash int) bool {
+		if !inOther(key, hash) {
+			result.add(key, hash)
+		}
+		return true
+	})
+	return result
+}
+
+// Return true if every key in this set is in the other.
+func (set *Set[K]) IsSubset(other *Set[K]) bool {
+	if set.Len() > other.Len() {
+		return false
+	}
+	inOther := set.memberOf(other)
+	subset := true
+	set.each(func(key K, hash int) bool {
+		subset = inOther(key, hash)
+		return subset
+	})
+	return subset
+}
+
+// Return true if both sets hold the same keys.
+func (set *Set[K]) Equal(other *Set[K]) bool {
+	return set.Len() == other.Len() && set.IsSubset(other)
+}
+
+// Display the set's table, slot by slot or bucket by bucket.
+func (set *Set[K]) Dump(w io.Writer) {
+	set.table.(interface{ Dump(w io.Writer) }).Dump(w)
+}
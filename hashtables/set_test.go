@@ -0,0 +1,163 @@
+package hashtables_test
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"hashtables"
+)
+
+// Build a set of these keys.
+func newTestSet(t *testing.T, strategy string, capacity int, keys hashtables.KeyHasher[string],
+	members ...string) *hashtables.Set[string] {
+	t.Helper()
+	set, err := hashtables.NewSet(strategy, capacity, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range members {
+		set.Add(member)
+	}
+	return set
+}
+
+// Return the set's keys in order.
+func sortedKeys(set *hashtables.Set[string]) []string {
+	keys := set.Keys()
+	slices.Sort(keys)
+	return keys
+}
+
+func TestSetAddRemove(t *testing.T) {
+	for _, strategy := range hashtables.StrategyNames() {
+		t.Run(strategy, func(t *testing.T) {
+			set := newTestSet(t, strategy, 8, hashtables.StringKeys(hashtables.DJB2))
+			if !set.Add("Ann") || set.Add("Ann") || !set.Add("Bob") {
+				t.Error("Add reported the wrong keys as new")
+			}
+			if !set.Contains("Ann") || set.Contains("Cid") || set.Len() != 2 {
+				t.Error("the set has the wrong keys")
+			}
+			if !set.Remove("Ann") || set.Remove("Ann") || set.Contains("Ann") || set.Len() != 1 {
+				t.Error("Remove did not remove Ann once")
+			}
+
+			clone := set.Clone()
+			clone.Add("Cid")
+			if set.Contains("Cid") || !clone.Contains("Bob") {
+				t.Error("the clone shares keys with the set")
+			}
+			clone.Resize(16)
+			if clone.Capacity() != 16 || !reflect.DeepEqual(sortedKeys(clone), []string{"Bob", "Cid"}) {
+				t.Errorf("after Resize the clone has capacity %d and keys %v", clone.Capacity(), clone.Keys())
+			}
+		})
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	shared := hashtables.StringKeys(hashtables.DJB2)
+	for _, strategy := range hashtables.StrategyNames() {
+		for _, sharing := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/shared=%t", strategy, sharing), func(t *testing.T) {
+				otherKeys := shared
+				if !sharing {
+					otherKeys = hashtables.StringKeys(hashtables.Jenkins)
+				}
+				a := newTestSet(t, strategy, 6, shared, "1", "2", "3", "4", "5", "6")
+				b := newTestSet(t, strategy, 11, otherKeys, "4", "5", "6", "7", "8", "9")
+
+				// The union of two full sets has to grow unless it uses chaining.
+				union := a.Union(b)
+				if got := sortedKeys(union); !reflect.DeepEqual(got, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}) {
+					t.Errorf("Union = %v", got)
+				}
+				if union.Strategy() != strategy {
+					t.Errorf("Union has strategy %s", union.Strategy())
+				}
+				if got := sortedKeys(a.Intersect(b)); !reflect.DeepEqual(got, []string{"4", "5", "6"}) {
+					t.Errorf("Intersect = %v", got)
+				}
+				if got := sortedKeys(b.Intersect(a)); !reflect.DeepEqual(got, []string{"4", "5", "6"}) {
+					t.Errorf("Intersect the other way = %v", got)
+				}
+				if got := sortedKeys(a.Difference(b)); !reflect.DeepEqual(got, []string{"1", "2", "3"}) {
+					t.Errorf("Difference = %v", got)
+				}
+
+				if a.IsSubset(b) || !a.Intersect(b).IsSubset(b) || !a.IsSubset(union) {
+					t.Error("IsSubset is wrong")
+				}
+				same := newTestSet(t, strategy, 11, otherKeys, "6", "5", "4", "3", "2", "1")
+				if !a.Equal(same) || !same.Equal(a) || a.Equal(b) {
+					t.Error("Equal is wrong")
+				}
+			})
+		}
+	}
+}
+
+// Sets that share a KeyHasher combine without hashing any key again.
+func TestSetReusesHashes(t *testing.T) {
+	calls := 0
+	keys := hashtables.NewKeyHasher(func(key string) int {
+		calls++
+		return hashtables.DJB2(key)
+	}, func(a string, b string) bool { return a == b })
+	for _, strategy := range hashtables.StrategyNames() {
+		// The sets needn't have the same capacity.
+		a := newTestSet(t, strategy, 16, keys, "Ann", "Bob", "Cid")
+		b := newTestSet(t, strategy, 5, keys, "Bob", "Dee")
+		calls = 0
+		union := a.Union(b)
+		intersection := a.Intersect(b)
+		a.Difference(b)
+		a.IsSubset(b)
+		a.Clone().Resize(32)
+		if calls != 0 {
+			t.Errorf("%s: hashed %d keys again", strategy, calls)
+		}
+		if !union.Equal(newTestSet(t, strategy, 8, keys, "Ann", "Bob", "Cid", "Dee")) ||
+			!intersection.Equal(newTestSet(t, strategy, 8, keys, "Bob")) {
+			t.Errorf("%s: union %v and intersection %v", strategy, sortedKeys(union), sortedKeys(intersection))
+		}
+
+		// A set with another KeyHasher has its keys hashed again.
+		other := newTestSet(t, strategy, 16, hashtables.StringKeys(hashtables.DJB2), "Bob", "Dee")
+		calls = 0
+		a.Union(other)
+		if calls == 0 {
+			t.Errorf("%s: reused hash codes from another KeyHasher", strategy)
+		}
+	}
+}
+
+// The named constructors build the same sets as NewSet.
+func TestSetConstructors(t *testing.T) {
+	keys := hashtables.StringKeys(hashtables.DJB2)
+	for strategy, set := range map[string]*hashtables.Set[string]{
+		"chaining":  hashtables.NewChainingSet(8, keys),
+		"flat":      hashtables.NewFlatChainingSet(8, keys),
+		"linear":    hashtables.NewLinearProbingSet(8, keys),
+		"quadratic": hashtables.NewQuadraticProbingSet(8, keys),
+		"double":    hashtables.NewDoubleHashSet(8, keys),
+		"coalesced": hashtables.NewCoalescedSet(8, keys),
+	} {
+		set.Add("Ann")
+		set.Add("Bob")
+		if set.Strategy() != strategy || !set.Equal(newTestSet(t, strategy, 8, keys, "Bob", "Ann")) {
+			t.Errorf("%s: the constructor built a %s set with keys %v", strategy, set.Strategy(), set.Keys())
+		}
+	}
+}
+
+func TestSetErrors(t *testing.T) {
+	if _, err := hashtables.NewSet("bogus", 8, hashtables.StringKeys(hashtables.DJB2)); err == nil {
+		t.Error("built a set with an unknown strategy")
+	}
+	if _, err := hashtables.NewSet("linear", 0, hashtables.StringKeys(hashtables.DJB2)); err == nil {
+		t.Error("built a set with no slots")
+	}
+}
@@ -0,0 +1,474 @@
+package hashtables
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultVirtualNodes is how many points a HashRing gives each shard.
+const DefaultVirtualNodes = 100
+
+// Sharder maps names to shards, moving as few names as it can when shards come and go.
+type Sharder interface {
+	// Add adds a shard. It returns an error if the shard is already present.
+	Add(shard string) error
+	// Remove removes a shard. It returns an error if the shard is not present
+	// or the scheme can't remove it.
+	Remove(shard string) error
+	// Locate returns the shard for a name, or "" if there are no shards.
+	Locate(name string) string
+	// Shards returns the shards in the order they were added.
+	Shards() []string
+	// Clone returns a copy that can be changed without changing this one.
+	Clone() Sharder
+}
+
+// ShardingScheme names a Sharder and how to build one.
+type ShardingScheme struct {
+	Name string
+	New  func(hash HashFunc) Sharder
+}
+
+// ShardingSchemes lists the available ways of mapping names to shards.
+var ShardingSchemes = []ShardingScheme{
+	{"ring", func(hash HashFunc) Sharder { return NewHashRing(hash, DefaultVirtualNodes) }},
+	{"rendezvous", func(hash HashFunc) Sharder { return NewRendezvousHash(hash) }},
+	{"jump", func(hash HashFunc) Sharder { return NewJumpHash(hash) }},
+}
+
+// Return the names of the available sharding schemes.
+func ShardingSchemeNames() []string {
+	names := make([]string, len(ShardingSchemes))
+	for i, scheme := range ShardingSchemes {
+		names[i] = scheme.Name
+	}
+	return names
+}
+
+// Build a Sharder using the named scheme.
+// A nil hash function means DJB2, the hash used throughout the project.
+func NewSharder(scheme string, hash HashFunc) (Sharder, error) {
+	if hash == nil {
+		hash = DJB2
+	}
+	for _, s := range ShardingSchemes {
+		if s.Name == scheme {
+			return s.New(hash), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown sharding scheme %q (have %s)",
+		scheme, strings.Join(ShardingSchemeNames(), ", "))
+}
+
+// shardList is the shards in the order they were added, shared by the schemes.
+type shardList []string
+
+// Return the index of a shard, or -1.
+func (shards shardList) index(shard string) int {
+	for i, s := range shards {
+		if s == shard {
+			return i
+		}
+	}
+	return -1
+}
+
+// Return the shards in the order they were added.
+func (shards shardList) Shards() []string {
+	return append([]string(nil), shards...)
+}
+
+// Return a copy of the list that doesn't share its array.
+func (shards shardList) clone() shardList {
+	return append(shardList(nil), shards...)
+}
+
+// ringPoint is one of a shard's virtual nodes.
+type ringPoint struct {
+	position int
+	shard    string
+}
+
+// HashRing is consistent hashing, as in Karger et al.'s "Consistent Hashing and Random
+// Trees": each shard has virtual nodes at hashed positions on a ring, and a name
+// belongs to the first virtual node at or after its own position. Adding a shard only
+// takes names from the shards next to its virtual nodes, and removing one only gives
+// its names to them, so about 1/n of the names move.
+type HashRing struct {
+	shardList
+	hash         HashFunc
+	virtualNodes int
+	points       []ringPoint // Sorted by position.
+}
+
+// Build an empty ring that places each shard at this many positions. More virtual
+// nodes even out the shards' shares of the ring.
+func NewHashRing(hash HashFunc, virtualNodes int) *HashRing {
+	return &HashRing{hash: hash, virtualNodes: max(1, virtualNodes)}
+}
+
+// Return the position of a name on the ring. Remixing spreads similar names,
+// which DJB2 hashes to nearby values.
+func (ring *HashRing) position(name string) int {
+	return remix(ring.hash(name))
+}
+
+// Add a shard.
+func (ring *HashRing) Add(shard string) error {
+	if ring.index(shard) >= 0 {
+		return fmt.Errorf("shard %q is already on the ring", shard)
+	}
+	ring.shardList = append(ring.shardList, shard)
+	for i := 0; i < ring.virtualNodes; i++ {
+		ring.points = append(ring.points, ringPoint{ring.position(fmt.Sprintf("%s#%d", shard, i)), shard})
+	}
+	// Break ties by shard so the ring doesn't depend on the order shards were added.
+	sort.Slice(ring.points, func(i, j int) bool {
+		a, b := ring.points[i], ring.points[j]
+		return a.position < b.position || a.position == b.position && a.shard < b.shard
+	})
+	return nil
+}
+
+// Remove a shard.
+func (ring *HashRing) Remove(shard string) error {
+	i := ring.index(shard)
+	if i < 0 {
+		return fmt.Errorf("shard %q is not on the ring", shard)
+	}
+	ring.shardList = append(ring.shardList[:i], ring.shardList[i+1:]...)
+	points := ring.points[:0]
+	for _, point := range ring.points {
+		if point.shard != shard {
+			points = append(points, point)
+		}
+	}
+	ring.points = points
+	return nil
+}
+
+// Return the shard for a name.
+func (ring *HashRing) Locate(name string) string {
+	if len(ring.points) == 0 {
+		return ""
+	}
+	position := ring.position(name)
+	i := sort.Search(len(ring.points), func(i int) bool { return ring.points[i].position >= position })
+	if i == len(ring.points) {
+		i = 0 // Wrap around the ring.
+	}
+	return ring.points[i].shard
+}
+
+// Return a copy of the ring.
+func (ring *HashRing) Clone() Sharder {
+	clone := *ring
+	clone.shardList = ring.shardList.clone()
+	clone.points = append([]ringPoint(nil), ring.points...)
+	return &clone
+}
+
+// Return the fraction of the ring each shard owns, which is the share of names
+// it can expect.
+func (ring *HashRing) Shares() map[string]float64 {
+	shares := make(map[string]float64)
+	if len(ring.points) == 0 {
+		return shares
+	}
+	// Each point owns the arc back to the point before it.
+	const ringSize = float64(1 << 63)
+	previous := ring.points[len(ring.points)-1].position
+	for _, point := range ring.points {
+		arc := uint64(point.position-previous) & (1<<63 - 1)
+		if len(ring.points) == 1 {
+			arc = 1<<63 - 1
+		}
+		shares[point.shard] += float64(arc) / ringSize
+		previous = point.position
+	}
+	return shares
+}
+
+// RendezvousHash is highest random weight hashing, as in Thaler and Ravishankar's
+// "Using Name-Based Mappings to Increase Hit Rates": a name goes to the shard whose
+// hash combined with the name's scores highest. It needs no virtual nodes and moves
+// the fewest names possible, but Locate takes time proportional to the number of shards.
+type RendezvousHash struct {
+	shardList
+	hash   HashFunc
+	hashes []int // The hash of each shard.
+}
+
+// Build an empty rendezvous hash.
+func NewRendezvousHash(hash HashFunc) *RendezvousHash {
+	return &RendezvousHash{hash: hash}
+}
+
+// Add a shard.
+func (rendezvous *RendezvousHash) Add(shard string) error {
+	if rendezvous.index(shard) >= 0 {
+		return fmt.Errorf("shard %q is already present", shard)
+	}
+	rendezvous.shardList = append(rendezvous.shardList, shard)
+	rendezvous.hashes = append(rendezvous.hashes, remix(rendezvous.hash(shard)))
+	return nil
+}
+
+// Remove a shard.
+func (rendezvous *RendezvousHash) Remove(shard string) error {
+	i := rendezvous.index(shard)
+	if i < 0 {
+		return fmt.Errorf("shard %q is not present", shard)
+	}
+	rendezvous.shardList = append(rendezvous.shardList[:i], rendezvous.shardList[i+1:]...)
+	rendezvous.hashes = append(rendezvous.hashes[:i], rendezvous.hashes[i+1:]...)
+	return nil
+}
+
+// Return the shard for a name.
+func (rendezvous *RendezvousHash) Locate(name string) string {
+	hash := rendezvous.hash(name)
+	best, bestScore := "", -1
+	for i, shard := range rendezvous.shardList {
+		// Ties go to the lower shard name, so the result doesn't depend on order.
+		score := remix(hash ^ rendezvous.hashes[i])
+		if score > bestScore || score == bestScore && shard < best {
+			best, bestScore = shard, score
+		}
+	}
+	return best
+}
+
+// Return a copy of the rendezvous hash.
+func (rendezvous *RendezvousHash) Clone() Sharder {
+	clone := *rendezvous
+	clone.shardList = rendezvous.shardList.clone()
+	clone.hashes = append([]int(nil), rendezvous.hashes...)
+	return &clone
+}
+
+// JumpHash is Lamping and Veach's "A Fast, Minimal Memory, Consistent Hash Algorithm".
+// It computes a shard number from the name's hash with no table at all and moves only
+// the names the new shard takes, but shards are numbered, so only the most recently
+// added shard can be removed.
+type JumpHash struct {
+	shardList
+	hash HashFunc
+}
+
+// Build an empty jump hash.
+func NewJumpHash(hash HashFunc) *JumpHash {
+	return &JumpHash{hash: hash}
+}
+
+// Add a shard.
+func (jump *JumpHash) Add(shard string) error {
+	if jump.index(shard) >= 0 {
+		return fmt.Errorf("shard %q is already present", shard)
+	}
+	jump.shardList = append(jump.shardList, shard)
+	return nil
+}
+
+// Remove a shard.
+func (jump *JumpHash) Remove(shard string) error {
+	i := jump.index(shard)
+	if i < 0 {
+		return fmt.Errorf("shard %q is not present", shard)
+	}
+	if i != len(jump.shardList)-1 {
+		return fmt.Errorf("jump hash can only remove the last shard, %q, not %q",
+			jump.shardList[len(jump.shardList)-1], shard)
+	}
+	jump.shardList = jump.shardList[:i]
+	return nil
+}
+
+// Return the shard for a name.
+func (jump *JumpHash) Locate(name string) string {
+	if len(jump.shardList) == 0 {
+		return ""
+	}
+	return jump.shardList[jumpBucket(uint64(remix(jump.hash(name))), len(jump.shardList))]
+}
+
+// Return a copy of the jump hash.
+func (jump *JumpHash) Clone() Sharder {
+	clone := *jump
+	clone.shardList = jump.shardList.clone()
+	return &clone
+}
+
+// Return the bucket in [0, n) for a key. Each step jumps ahead to the next bucket
+// count at which the key would move, until it passes n.
+func jumpBucket(key uint64, n int) int {
+	bucket, next := -1, 0
+	for next < n {
+		bucket = next
+		key = key*2862933555777941757 + 1
+		next = int(float64(bucket+1) * (float64(1<<31) / float64(key>>33+1)))
+	}
+	return bucket
+}
+
+// Return how many of the names would change shards if change altered the sharder,
+// for planning a change without moving any data. The change is made to a clone,
+// so the sharder may be one a ShardedTable owns.
+func ShardMoves(sharder Sharder, names []string, change func(sharder Sharder) error) (int, error) {
+	planned := sharder.Clone()
+	if err := change(planned); err != nil {
+		return 0, err
+	}
+	moved := 0
+	for _, name := range names {
+		if planned.Locate(name) != sharder.Locate(name) {
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// ShardedTable spreads names over several tables, one per shard, with a Sharder
+// picking each name's table. The tables could live in other processes; here they
+// are in-process Tables. Adding or removing a shard moves the names that change
+// shards and reports how many moved. Moved names lose their TTLs.
+type ShardedTable struct {
+	sharder Sharder
+	tables  map[string]Table
+}
+
+// Build a sharded table with no shards.
+func NewShardedTable(sharder Sharder) *ShardedTable {
+	return &ShardedTable{sharder: sharder, tables: make(map[string]Table)}
+}
+
+// Return the table that holds this name, or nil if there are no shards.
+func (sharded *ShardedTable) table(name string) Table {
+	return sharded.tables[sharded.sharder.Locate(name)]
+}
+
+// Add a shard backed by the table, moving the names that now belong to it,
+// and return how many moved.
+func (sharded *ShardedTable) AddShard(shard string, table Table) (int, error) {
+	if err := sharded.sharder.Add(shard); err != nil {
+		return 0, err
+	}
+	sharded.tables[shard] = table
+	return sharded.rebalance(), nil
+}
+
+// Remove a shard, moving its names to the other shards, and return how many moved
+// and the shard's table, which is then empty. Removing the last shard fails.
+func (sharded *ShardedTable) RemoveShard(shard string) (int, Table, error) {
+	if len(sharded.tables) == 1 && sharded.tables[shard] != nil {
+		return 0, nil, fmt.Errorf("can't remove the last shard, %q", shard)
+	}
+	if err := sharded.sharder.Remove(shard); err != nil {
+		return 0, nil, err
+	}
+	table := sharded.tables[shard]
+	moved := sharded.rebalance()
+	delete(sharded.tables, shard)
+	return moved, table, nil
+}
+
+// Move every name that is not in its shard's table there. Return how many moved.
+func (sharded *ShardedTable) rebalance() int {
+	type move struct{ name, phone string }
+	moved := 0
+	for shard, table := range sharded.tables {
+		var moves []move
+		table.Range(func(name string, phone string) bool {
+			if sharded.sharder.Locate(name) != shard {
+				moves = append(moves, move{name, phone})
+			}
+			return true
+		})
+		for _, m := range moves {
+			table.Delete(m.name)
+			sharded.table(m.name).Set(m.name, m.phone)
+		}
+		moved += len(moves)
+	}
+	return moved
+}
+
+// Return the sharder.
+func (sharded *ShardedTable) Sharder() Sharder {
+	return sharded.sharder
+}
+
+// Return a shard's table, or nil if there is no such shard.
+func (sharded *ShardedTable) Shard(shard string) Table {
+	return sharded.tables[shard]
+}
+
+// Add an item to its shard's table. It panics if there are no shards.
+func (sharded *ShardedTable) Set(name string, phone string) {
+	table := sharded.table(name)
+	if table == nil {
+		panic("Sharded table has no shards")
+	}
+	table.Set(name, phone)
+}
+
+// Return an item from its shard's table, or "" if it is not present.
+func (sharded *ShardedTable) Get(name string) string {
+	phone, _ := sharded.Lookup(name)
+	return phone
+}
+
+// Return an item from its shard's table and whether it was present.
+func (sharded *ShardedTable) Lookup(name string) (string, bool) {
+	if table := sharded.table(name); table != nil {
+		return table.Lookup(name)
+	}
+	return "", false
+}
+
+// Return true if the name is in its shard's table.
+func (sharded *ShardedTable) Contains(name string) bool {
+	table := sharded.table(name)
+	return table != nil && table.Contains(name)
+}
+
+// Delete the name from its shard's table.
+func (sharded *ShardedTable) Delete(name string) {
+	if table := sharded.table(name); table != nil {
+		table.Delete(name)
+	}
+}
+
+// Return the number of live entries in all shards.
+func (sharded *ShardedTable) Len() int {
+	total := 0
+	for _, table := range sharded.tables {
+		total += table.Len()
+	}
+	return total
+}
+
+// Return the number of live entries in each shard.
+func (sharded *ShardedTable) ShardLens() map[string]int {
+	lens := make(map[string]int, len(sharded.tables))
+	for shard, table := range sharded.tables {
+		lens[shard] = table.Len()
+	}
+	return lens
+}
+
+// Call fn for each entry, one shard at a time in the order shards were added,
+// until fn returns false.
+func (sharded *ShardedTable) Range(fn func(name string, phone string) bool) {
+	for _, shard := range sharded.sharder.Shards() {
+		stopped := false
+		sharded.tables[shard].Range(func(name string, phone string) bool {
+			stopped = !fn(name, phone)
+			return !stopped
+		})
+		if stopped {
+			return
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package hashtables_test
+
+import (
+	"fmt"
+	"testing"
+
+	"hashtables"
+)
+
+const (
+	shardNames     = 20000
+	shardStartSize = 4
+)
+
+// Build a sharded table of in-process chaining tables holding shardNames names.
+func newShardedTable(t *testing.T, scheme string, hash hashtables.HashFunc) *hashtables.ShardedTable {
+	t.Helper()
+	sharder, err := hashtables.NewSharder(scheme, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharded := hashtables.NewShardedTable(sharder)
+	for i := 0; i < shardStartSize; i++ {
+		if _, err := sharded.AddShard(fmt.Sprintf("shard-%d", i), hashtables.NewChainingHashTable(64, hash)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < shardNames; i++ {
+		sharded.Set(fmt.Sprintf("name%d", i), fmt.Sprintf("%d", i))
+	}
+	return sharded
+}
+
+// Check that every name is present and in the table its shard's Locate picks.
+func checkSharded(t *testing.T, sharded *hashtables.ShardedTable) {
+	t.Helper()
+	if sharded.Len() != shardNames {
+		t.Fatalf("Len = %d, want %d", sharded.Len(), shardNames)
+	}
+	for i := 0; i < shardNames; i++ {
+		name := fmt.Sprintf("name%d", i)
+		if phone, ok := sharded.Lookup(name); !ok || phone != fmt.Sprintf("%d", i) {
+			t.Fatalf("Lookup(%q) = %q, %v", name, phone, ok)
+		}
+		if !sharded.Shard(sharded.Sharder().Locate(name)).Contains(name) {
+			t.Fatalf("%q is not in shard %q", name, sharded.Sharder().Locate(name))
+		}
+	}
+}
+
+func TestShardedTable(t *testing.T) {
+	for _, scheme := range hashtables.ShardingSchemeNames() {
+		for _, hasher := range []string{"djb2", "jenkins", "fnv1a"} {
+			t.Run(scheme+"/"+hasher, func(t *testing.T) {
+				hash, err := hashtables.LookupHasher(hasher)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sharded := newShardedTable(t, scheme, hash)
+				checkSharded(t, sharded)
+
+				// Each shard should get close to its share.
+				fair := shardNames / shardStartSize
+				for shard, n := range sharded.ShardLens() {
+					if n < fair*6/10 || n > fair*14/10 {
+						t.Errorf("shard %s has %d names, want about %d", shard, n, fair)
+					}
+				}
+
+				// Adding a shard should only move names to it, about 1/5 of them.
+				moved, err := sharded.AddShard("shard-new", hashtables.NewChainingHashTable(64, hash))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := sharded.Shard("shard-new").Len(); moved != got {
+					t.Errorf("moved %d names but the new shard has %d", moved, got)
+				}
+				if want := shardNames / (shardStartSize + 1); moved < want*6/10 || moved > want*14/10 {
+					t.Errorf("adding a shard moved %d names, want about %d", moved, want)
+				}
+				checkSharded(t, sharded)
+
+				// Removing a shard should move exactly its names.
+				shard := "shard-new"
+				if scheme != "jump" {
+					shard = "shard-1"
+				}
+				want := sharded.Shard(shard).Len()
+				moved, table, err := sharded.RemoveShard(shard)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if moved != want || table.Len() != 0 {
+					t.Errorf("removing a shard with %d names moved %d and left %d", want, moved, table.Len())
+				}
+				checkSharded(t, sharded)
+			})
+		}
+	}
+}
+
+func TestShardMoves(t *testing.T) {
+	names := make([]string, shardNames)
+	for i := range names {
+		names[i] = fmt.Sprintf("name%d", i)
+	}
+	for _, scheme := range hashtables.ShardingSchemes {
+		sharder := scheme.New(hashtables.Jenkins)
+		for i := 0; i < shardStartSize; i++ {
+			sharder.Add(fmt.Sprintf("shard-%d", i))
+		}
+		moved, err := hashtables.ShardMoves(sharder, names, func(sharder hashtables.Sharder) error {
+			return sharder.Add("shard-new")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sharder.Shards()) != shardStartSize {
+			t.Errorf("%s: planning a change made it", scheme.Name)
+		}
+		sharder.Add("shard-new")
+		placed := 0
+		for _, name := range names {
+			if sharder.Locate(name) == "shard-new" {
+				placed++
+			}
+		}
+		if moved != placed {
+			t.Errorf("%s: %d names moved but %d are on the new shard", scheme.Name, moved, placed)
+		}
+
+		// A table's own sharder can be planned with and keeps working.
+		sharded := hashtables.NewShardedTable(scheme.New(hashtables.Jenkins))
+		sharded.AddShard("a", hashtables.NewChainingHashTable(64, hashtables.Jenkins))
+		if _, err := hashtables.ShardMoves(sharded.Sharder(), names, func(sharder hashtables.Sharder) error {
+			return sharder.Add("b")
+		}); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names[:100] {
+			sharded.Set(name, "1")
+		}
+		if sharded.Len() != 100 || sharded.ShardLens()["a"] != 100 {
+			t.Errorf("%s: shards hold %v after planning a change", scheme.Name, sharded.ShardLens())
+		}
+	}
+}
+
+func TestShardErrors(t *testing.T) {
+	for _, scheme := range hashtables.ShardingSchemes {
+		sharder := scheme.New(hashtables.DJB2)
+		if sharder.Locate("Ann") != "" {
+			t.Errorf("%s: an empty sharder located a shard", scheme.Name)
+		}
+		sharder.Add("a")
+		sharder.Add("b")
+		if sharder.Add("a") == nil {
+			t.Errorf("%s: added a shard twice", scheme.Name)
+		}
+		if sharder.Remove("c") == nil {
+			t.Errorf("%s: removed a missing shard", scheme.Name)
+		}
+	}
+
+	jump := hashtables.NewJumpHash(hashtables.DJB2)
+	jump.Add("a")
+	jump.Add("b")
+	if jump.Remove("a") == nil {
+		t.Error("jump hash removed a shard other than the last")
+	}
+
+	sharded := hashtables.NewShardedTable(hashtables.NewHashRing(hashtables.DJB2, 10))
+	sharded.AddShard("only", hashtables.NewChainingHashTable(8, hashtables.DJB2))
+	if _, _, err := sharded.RemoveShard("only"); err == nil {
+		t.Error("removed the last shard")
+	}
+}
@@ -0,0 +1,210 @@
+package hashtables
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ProbeStats summarizes a set of probe sequence lengths.
+type ProbeStats struct {
+	Count     int
+	Mean      float64
+	Median    int
+	P99       int
+	Max       int
+	Histogram []int // Histogram[n] is the number of probe sequences of length n.
+}
+
+// Stats describes the occupancy and probe behavior of a hash table.
+// Open addressing tables fill in the slot and cluster fields.
+// Chaining tables fill in the bucket and chain fields.
+// Coalesced tables fill in the slot and chain fields and Cellar.
+type Stats struct {
+	Capacity   int // Slots or buckets.
+	Live       int
+	Deleted    int
+	Empty      int     // Empty slots or empty buckets.
+	LoadFactor float64 // Live entries divided by capacity.
+
+	Successful   ProbeStats // Probes needed to find each live key.
+	Unsuccessful ProbeStats // Probes needed to miss, one for each slot or bucket.
+
+	MaxDisplacement int   // Largest distance between a live key's home slot and its slot.
+	ClusterSizes    []int // ClusterSizes[n] is the number of runs of n consecutive non-empty slots.
+	MaxCluster      int
+
+	ChainLengths []int // ChainLengths[n] is the number of buckets holding n entries.
+	MaxChain     int
+
+	Migration     *Migration     // The progress of an incremental resize, or nil if there is none.
+	LinearHashing *LinearHashing // A growing chaining table's split state, or nil.
+	Cellar        *Cellar        // A coalesced table's overflow slots, or nil.
+}
+
+// Migration is the progress of an incremental resize.
+// The other stats describe only the new slots or buckets.
+type Migration struct {
+	OldCapacity int // Old slots or buckets.
+	Moved       int // Old slots or buckets migrated so far.
+	Left        int // Live entries still in the old slots or buckets.
+}
+
+// LinearHashing is the state of a chaining table that grows by splitting one bucket at a time.
+type LinearHashing struct {
+	MaxLoad      float64 // The load factor that triggers a split, or 0 if growth is off.
+	Level        int     // The number of rounds of splits finished.
+	Split        int     // The next bucket to split.
+	RoundBuckets int     // The number of buckets when this round began.
+}
+
+// Cellar is the use of a coalesced table's overflow slots, which no key hashes to.
+type Cellar struct {
+	Size int
+	Used int // Cellar slots holding live or deleted entries.
+}
+
+// Display the cellar's use on one line.
+func (cellar *Cellar) Dump(w io.Writer) {
+	fmt.Fprintf(w, "Cellar: %d of %d slots used\n", cellar.Used, cellar.Size)
+}
+
+// Display the linear hashing state on one line.
+func (linearHashing *LinearHashing) Dump(w io.Writer) {
+	fmt.Fprintf(w, "Linear hashing: max load %.2f, level %d, next split %d of %d\n",
+		linearHashing.MaxLoad, linearHashing.Level, linearHashing.Split, linearHashing.RoundBuckets)
+}
+
+// Display the migration's progress on one line.
+func (migration *Migration) Dump(w io.Writer) {
+	fmt.Fprintf(w, "Migrating from %d: %d moved (%.1f%%), %d entries left\n", migration.OldCapacity,
+		migration.Moved, 100*float64(migration.Moved)/float64(migration.OldCapacity), migration.Left)
+}
+
+// Summarize a set of probe sequence lengths.
+func newProbeStats(lengths []int) ProbeStats {
+	probeStats := ProbeStats{Count: len(lengths)}
+	if len(lengths) == 0 {
+		return probeStats
+	}
+
+	sorted := append([]int(nil), lengths...)
+	sort.Ints(sorted)
+
+	total := 0
+	for _, length := range sorted {
+		total += length
+	}
+	probeStats.Mean = float64(total) / float64(len(sorted))
+	probeStats.Median = percentile(sorted, 50)
+	probeStats.P99 = percentile(sorted, 99)
+	probeStats.Max = sorted[len(sorted)-1]
+
+	probeStats.Histogram = make([]int, probeStats.Max+1)
+	for _, length := range sorted {
+		probeStats.Histogram[length]++
+	}
+	return probeStats
+}
+
+// Return the nearest-rank percentile of a sorted, non-empty slice.
+func percentile(sorted []int, p int) int {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Count the runs of consecutive non-empty slots, wrapping around the end of the slice.
+// Return the cluster size distribution and the largest cluster.
+func clusterSizes[K any, V any](entries []*Entry[K, V]) ([]int, int) {
+	// Start just after an empty slot so no cluster is split by the wrap.
+	start := -1
+	for i, entry := range entries {
+		if entry == nil {
+			start = i
+			break
+		}
+	}
+
+	// If there are no empty slots, the whole table is one cluster.
+	if start < 0 {
+		if len(entries) == 0 {
+			return nil, 0
+		}
+		sizes := make([]int, len(entries)+1)
+		sizes[len(entries)] = 1
+		return sizes, len(entries)
+	}
+
+	var sizes []int
+	maxSize := 0
+	run := 0
+	for i := 1; i <= len(entries); i++ {
+		if entries[(start+i)%len(entries)] != nil {
+			run++
+			continue
+		}
+		if run > 0 {
+			sizes = increment(sizes, run)
+			if run > maxSize {
+				maxSize = run
+			}
+		}
+		run = 0
+	}
+	return sizes, maxSize
+}
+
+// Add one to counts[n], growing the slice if needed.
+func increment(counts []int, n int) []int {
+	for len(counts) <= n {
+		counts = append(counts, 0)
+	}
+	counts[n]++
+	return counts
+}
+
+// Display the stats.
+func (stats Stats) Dump(w io.Writer) {
+	fmt.Fprintf(w, "Capacity: %d, live: %d, deleted: %d, empty: %d, load factor: %.3f\n",
+		stats.Capacity, stats.Live, stats.Deleted, stats.Empty, stats.LoadFactor)
+	if stats.Migration != nil {
+		stats.Migration.Dump(w)
+	}
+	if stats.LinearHashing != nil {
+		stats.LinearHashing.Dump(w)
+	}
+	if stats.Cellar != nil {
+		stats.Cellar.Dump(w)
+	}
+	stats.Successful.Dump(w, "Successful probes")
+	stats.Unsuccessful.Dump(w, "Unsuccessful probes")
+	if stats.ChainLengths != nil {
+		fmt.Fprintf(w, "Max chain: %d\n", stats.MaxChain)
+		dumpCounts(w, "Chain lengths (length:count):", stats.ChainLengths)
+		return
+	}
+	fmt.Fprintf(w, "Max displacement: %d\n", stats.MaxDisplacement)
+	fmt.Fprintf(w, "Max cluster: %d\n", stats.MaxCluster)
+	dumpCounts(w, "Cluster sizes (size:count):", stats.ClusterSizes)
+}
+
+// Display the probe stats with a label.
+func (probeStats ProbeStats) Dump(w io.Writer, label string) {
+	fmt.Fprintf(w, "%s: mean %.3f, median %d, p99 %d, max %d\n",
+		label, probeStats.Mean, probeStats.Median, probeStats.P99, probeStats.Max)
+	dumpCounts(w, "    Histogram (length:count):", probeStats.Histogram)
+}
+
+// Display the non-zero counts on one line.
+func dumpCounts(w io.Writer, label string, counts []int) {
+	fmt.Fprint(w, label)
+	for n, count := range counts {
+		if count > 0 {
+			fmt.Fprintf(w, " %d:%d", n, count)
+		}
+	}
+	fmt.Fprintln(w)
+}
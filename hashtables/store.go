@@ -0,0 +1,177 @@
+package hashtables
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnique is returned when a record would give a unique index a second owner for a value.
+var ErrUnique = errors.New("unique index violation")
+
+// Store holds records in a primary table keyed by one field and keeps any number
+// of secondary indexes on other fields, such as phone, in sync with it.
+type Store[R any] struct {
+	primary    Map[string, R]
+	keys       KeyHasher[string]
+	primaryKey func(record R) string
+	indexes    []*storeIndex[R]
+}
+
+// storeIndex maps one field's values to the primary keys of the records that hold them.
+type storeIndex[R any] struct {
+	name   string
+	field  func(record R) string
+	unique bool
+	owners *ChainingMultiMap[string, string]
+}
+
+// Build an empty store whose primary table uses the named strategy.
+// primaryKey returns the field that identifies a record, such as the name.
+func NewStore[R any](strategy string, capacity int, keys KeyHasher[string],
+	primaryKey func(record R) string) (*Store[R], error) {
+	primary, err := NewMap[string, R](strategy, capacity, keys)
+	if err != nil {
+		return nil, err
+	}
+	return &Store[R]{primary: primary, keys: keys, primaryKey: primaryKey}, nil
+}
+
+// Build a store of employees keyed by name with a unique index named "phone".
+func NewEmployeeStore(strategy string, capacity int, hash HashFunc) (*Store[Employee], error) {
+	store, err := NewStore[Employee](strategy, capacity, StringKeys(hash),
+		func(employee Employee) string { return employee.Key })
+	if err != nil {
+		return nil, err
+	}
+	if err := store.AddIndex("phone", true, func(employee Employee) string { return employee.Value }); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Add a secondary index on a field and fill it from the records already stored.
+// If unique is true, no two records may share a value for the field.
+func (store *Store[R]) AddIndex(name string, unique bool, field func(record R) string) error {
+	if store.index(name) != nil {
+		return fmt.Errorf("index %q already exists", name)
+	}
+	index := &storeIndex[R]{
+		name:   name,
+		field:  field,
+		unique: unique,
+		owners: NewChainingMultiMap[string, string](max(1, store.primary.Capacity()), StringKeys(nil)),
+	}
+
+	var err error
+	store.primary.Range(func(key string, record R) bool {
+		value := field(record)
+		if unique && index.owners.Contains(value) {
+			err = fmt.Errorf("%w: %s %q is used by %q and %q",
+				ErrUnique, name, value, index.owners.GetAll(value)[0], key)
+			return false
+		}
+		index.owners.Add(value, key)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	store.indexes = append(store.indexes, index)
+	return nil
+}
+
+// Return the index with this name or nil.
+func (store *Store[R]) index(name string) *storeIndex[R] {
+	for _, index := range store.indexes {
+		if index.name == name {
+			return index
+		}
+	}
+	return nil
+}
+
+// Return the number of records.
+func (store *Store[R]) Len() int {
+	return store.primary.Len()
+}
+
+// Add or replace the record with the same primary key, updating every index.
+// If a unique index already holds one of the record's values for another record,
+// return an error wrapping ErrUnique and leave the store unchanged.
+func (store *Store[R]) Set(record R) error {
+	key := store.primaryKey(record)
+	old, exists := store.primary.Lookup(key)
+
+	// Check every unique index before changing anything.
+	for _, index := range store.indexes {
+		if !index.unique {
+			continue
+		}
+		value := index.field(record)
+		for _, owner := range index.owners.GetAll(value) {
+			if !store.keys.Equal(owner, key) {
+				return fmt.Errorf("%w: %s %q is already used by %q", ErrUnique, index.name, value, owner)
+			}
+		}
+	}
+
+	// Set the primary record first, so a full table panics before the indexes change.
+	store.primary.Set(key, record)
+	for _, index := range store.indexes {
+		owner := key
+		if exists {
+			if indexed, ok := store.removeOwner(index, index.field(old), key); ok {
+				owner = indexed
+			}
+		}
+		index.owners.Add(index.field(record), owner)
+	}
+	return nil
+}
+
+// Remove the owner equal to key from an index value's owners. The indexes hold
+// the key as it was first stored, which may be spelled differently from this one
+// if the primary table's KeyHasher matches keys loosely. Return the key as the
+// index held it and true if it was there.
+func (store *Store[R]) removeOwner(index *storeIndex[R], value string, key string) (string, bool) {
+	for _, owner := range index.owners.GetAll(value) {
+		if store.keys.Equal(owner, key) {
+			return owner, index.owners.RemoveValue(value, owner)
+		}
+	}
+	return "", false
+}
+
+// Return the record with this primary key.
+func (store *Store[R]) Get(key string) (R, bool) {
+	return store.primary.Lookup(key)
+}
+
+// Delete the record with this primary key from the table and every index.
+// Return true if it was present.
+func (store *Store[R]) Delete(key string) bool {
+	record, exists := store.primary.Lookup(key)
+	if !exists {
+		return false
+	}
+	store.primary.Delete(key)
+	for _, index := range store.indexes {
+		store.removeOwner(index, index.field(record), key)
+	}
+	return true
+}
+
+// Return the records whose indexed field has this value, in the order they were indexed.
+func (store *Store[R]) GetBy(indexName string, value string) ([]R, error) {
+	index := store.index(indexName)
+	if index == nil {
+		return nil, fmt.Errorf("unknown index %q", indexName)
+	}
+	var records []R
+	for _, key := range index.owners.GetAll(value) {
+		if record, ok := store.primary.Lookup(key); ok {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
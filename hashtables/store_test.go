@@ -0,0 +1,168 @@
+package hashtables_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"hashtables"
+)
+
+type staffRecord struct {
+	Name, Phone, Dept string
+}
+
+// Build a store of staff keyed by name with a unique phone index and a dept index.
+func newStaffStore(t *testing.T, strategy string, keys hashtables.KeyHasher[string]) *hashtables.Store[staffRecord] {
+	t.Helper()
+	store, err := hashtables.NewStore(strategy, 16, keys, func(r staffRecord) string { return r.Name })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddIndex("phone", true, func(r staffRecord) string { return r.Phone }); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddIndex("dept", false, func(r staffRecord) string { return r.Dept }); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// Return the names of the records with this value in an index.
+func namesBy(t *testing.T, store *hashtables.Store[staffRecord], index string, value string) []string {
+	t.Helper()
+	records, err := store.GetBy(index, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, record := range records {
+		names = append(names, record.Name)
+	}
+	return names
+}
+
+func TestStoreIndexes(t *testing.T) {
+	for _, strategy := range hashtables.StrategyNames() {
+		t.Run(strategy, func(t *testing.T) {
+			store := newStaffStore(t, strategy, hashtables.StringKeys(nil))
+			for _, record := range []staffRecord{
+				{"Ann", "555-1", "sales"},
+				{"Bob", "555-2", "sales"},
+				{"Cid", "555-3", "ops"},
+			} {
+				if err := store.Set(record); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := namesBy(t, store, "dept", "sales"); !reflect.DeepEqual(got, []string{"Ann", "Bob"}) {
+				t.Errorf("sales = %v", got)
+			}
+
+			// Updating a record moves it in every index.
+			if err := store.Set(staffRecord{"Ann", "555-9", "ops"}); err != nil {
+				t.Fatal(err)
+			}
+			if got := namesBy(t, store, "phone", "555-1"); got != nil {
+				t.Errorf("the old phone still finds %v", got)
+			}
+			if got := namesBy(t, store, "phone", "555-9"); !reflect.DeepEqual(got, []string{"Ann"}) {
+				t.Errorf("the new phone finds %v", got)
+			}
+			if got := namesBy(t, store, "dept", "sales"); !reflect.DeepEqual(got, []string{"Bob"}) {
+				t.Errorf("sales = %v after Ann moved", got)
+			}
+			if got := namesBy(t, store, "dept", "ops"); !reflect.DeepEqual(got, []string{"Cid", "Ann"}) {
+				t.Errorf("ops = %v after Ann moved", got)
+			}
+
+			// A record can keep its own unique value, but not take another's.
+			if err := store.Set(staffRecord{"Ann", "555-9", "sales"}); err != nil {
+				t.Errorf("keeping a phone: %v", err)
+			}
+			err := store.Set(staffRecord{"Dee", "555-2", "ops"})
+			if !errors.Is(err, hashtables.ErrUnique) {
+				t.Errorf("taking Bob's phone returned %v", err)
+			}
+			if _, ok := store.Get("Dee"); ok || store.Len() != 3 {
+				t.Error("a failed Set changed the store")
+			}
+			if got := namesBy(t, store, "dept", "ops"); !reflect.DeepEqual(got, []string{"Cid"}) {
+				t.Errorf("ops = %v after a failed Set", got)
+			}
+
+			// Deleting removes the record from every index.
+			if !store.Delete("Bob") || store.Delete("Bob") {
+				t.Error("Delete(Bob) did not remove Bob once")
+			}
+			if got := namesBy(t, store, "phone", "555-2"); got != nil {
+				t.Errorf("Bob's phone still finds %v", got)
+			}
+			if got := namesBy(t, store, "dept", "sales"); !reflect.DeepEqual(got, []string{"Ann"}) {
+				t.Errorf("sales = %v after deleting Bob", got)
+			}
+			if err := store.Set(staffRecord{"Dee", "555-2", "ops"}); err != nil {
+				t.Errorf("reusing a deleted phone: %v", err)
+			}
+		})
+	}
+}
+
+// The indexes hold the primary key as first stored, even when a loose key updates it.
+func TestStoreLooseKeys(t *testing.T) {
+	store := newStaffStore(t, "linear", hashtables.CaseInsensitiveKeys(nil))
+	store.Set(staffRecord{"Ann", "555-1", "sales"})
+	if err := store.Set(staffRecord{"ANN", "555-2", "ops"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := namesBy(t, store, "phone", "555-2"); !reflect.DeepEqual(got, []string{"ANN"}) {
+		t.Errorf("the new phone finds %v", got)
+	}
+	if got := namesBy(t, store, "phone", "555-1"); got != nil {
+		t.Errorf("the old phone still finds %v", got)
+	}
+	if err := store.Set(staffRecord{"ann", "555-3", "ops"}); err != nil {
+		t.Fatal(err)
+	}
+	if !store.Delete("ANN") || store.Len() != 0 {
+		t.Error("Delete(ANN) did not remove the record")
+	}
+	if got := namesBy(t, store, "dept", "ops"); got != nil {
+		t.Errorf("ops = %v after deleting the record", got)
+	}
+
+	// Every phone the record held is free again.
+	for _, phone := range []string{"555-1", "555-2", "555-3"} {
+		if err := store.Set(staffRecord{"Bob " + phone, phone, "sales"}); err != nil {
+			t.Errorf("reusing %s: %v", phone, err)
+		}
+	}
+}
+
+func TestStoreAddIndex(t *testing.T) {
+	store, err := hashtables.NewEmployeeStore("chaining", 8, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set(hashtables.Employee{Key: "Ann", Value: "555-1"})
+	store.Set(hashtables.Employee{Key: "Bob", Value: "555-2"})
+	store.Set(hashtables.Employee{Key: "Cid", Value: "555-3"})
+
+	// An index added later is filled from the records already stored.
+	prefix := func(employee hashtables.Employee) string { return employee.Value[:3] }
+	if err := store.AddIndex("exchange", false, prefix); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := store.GetBy("exchange", "555"); len(records) != 3 {
+		t.Errorf("the new index found %d records", len(records))
+	}
+	if err := store.AddIndex("unique exchange", true, prefix); !errors.Is(err, hashtables.ErrUnique) {
+		t.Errorf("a unique index over shared values returned %v", err)
+	}
+	if err := store.AddIndex("phone", false, prefix); err == nil {
+		t.Error("added a second phone index")
+	}
+	if _, err := store.GetBy("bogus", "555"); err == nil {
+		t.Error("found records in a missing index")
+	}
+}
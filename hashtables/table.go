@@ -0,0 +1,203 @@
+// Package hashtables collects the hash table strategies from the liveProject steps
+// behind a common interface so they can be compared, explored and extended.
+package hashtables
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Entry is a key and value stored in a table.
+type Entry[K any, V any] struct {
+	Key     K
+	Value   V
+	deleted bool
+	expires int64 // When the entry expires in Unix nanoseconds, or 0 if it never does.
+}
+
+// Employee is an entry in the liveProject's tables of names and phone numbers.
+type Employee = Entry[string, string]
+
+// Map is the set of operations shared by every hash table strategy for any key and value types.
+// Keys are hashed and compared by the KeyHasher the map was built with.
+type Map[K any, V any] interface {
+	Set(key K, value V)
+	Get(key K) V
+	Lookup(key K) (V, bool)
+	Contains(key K) bool
+	Delete(key K)
+	Resize(capacity int)
+	SetIncrementalResize(slotsPerOp int)
+	Len() int
+	Capacity() int
+
+	// Range calls fn for each entry until fn returns false.
+	Range(fn func(key K, value V) bool)
+
+	// SetWithTTL sets an entry that is hidden once ttl has passed on the map's clock.
+	SetWithTTL(key K, value V, ttl time.Duration)
+	// RemoveExpired removes every expired entry and returns how many there were.
+	RemoveExpired() int
+	SetClock(clock Clock)
+}
+
+// Table is the set of operations shared by every hash table strategy.
+type Table interface {
+	Set(name string, phone string)
+	SetWithTTL(name string, phone string, ttl time.Duration)
+	Get(name string) string
+	Lookup(name string) (string, bool)
+	Contains(name string) bool
+	Delete(name string)
+
+	// Range calls fn for each live entry until fn returns false.
+	Range(fn func(name string, phone string) bool)
+
+	// Resize rehashes the live entries into a table with a new capacity.
+	// SetIncrementalResize makes later resizes migrate this many slots or buckets
+	// per operation instead, or rebuild at once again if it is 0.
+	Resize(capacity int)
+	SetIncrementalResize(slotsPerOp int)
+
+	// Len returns the number of live entries and Capacity the number of slots or buckets.
+	Len() int
+	Capacity() int
+
+	Dump(w io.Writer)
+	DumpConcise(w io.Writer)
+	Probe(w io.Writer, name string) int
+	ProbePath(name string) []int
+	Slots() []Slot
+
+	// RemoveExpired turns expired entries into tombstones (or drops them from their chains)
+	// and returns how many there were. SetClock sets where the table gets the time.
+	RemoveExpired() int
+	SetClock(clock Clock)
+
+	// SetRecorder sends the steps of later operations to the recorder.
+	SetRecorder(recorder Recorder)
+	AveProbeSequenceLength() float32
+	Stats() Stats
+}
+
+// SlotState tells what a slot or bucket holds.
+type SlotState int
+
+const (
+	SlotEmpty SlotState = iota
+	SlotLive
+	SlotDeleted
+)
+
+// Slot describes one slot or bucket for display.
+type Slot struct {
+	State   SlotState
+	Name    string // The live key in an open addressing slot, or the first key in a bucket.
+	Home    int    // The slot the key hashes to.
+	Entries int    // The number of live entries in the slot or bucket.
+	Chained bool   // The slot is a bucket that can hold several entries.
+}
+
+// Strategy names a hash table implementation and how to build one.
+type Strategy struct {
+	Name string
+	New  func(capacity int, hash HashFunc) Table
+}
+
+// Strategies lists the available hash table implementations.
+var Strategies = []Strategy{
+	{"chaining", func(capacity int, hash HashFunc) Table {
+		return NewChainingHashTable(capacity, hash)
+	}},
+	{"flat", func(capacity int, hash HashFunc) Table {
+		return NewFlatChainingHashTable(capacity, hash)
+	}},
+	{"linear", func(capacity int, hash HashFunc) Table {
+		return NewLinearProbingHashTable(capacity, hash)
+	}},
+	{"quadratic", func(capacity int, hash HashFunc) Table {
+		return NewQuadraticProbingHashTable(capacity, hash)
+	}},
+	{"double", func(capacity int, hash HashFunc) Table {
+		return NewDoubleHashTable(capacity, hash, Jenkins)
+	}},
+	{"coalesced", func(capacity int, hash HashFunc) Table {
+		return NewCoalescedHashTable(capacity, hash)
+	}},
+}
+
+// Return the names of the available strategies.
+func StrategyNames() []string {
+	names := make([]string, len(Strategies))
+	for i, strategy := range Strategies {
+		names[i] = strategy.Name
+	}
+	return names
+}
+
+// Build a map using the named strategy, hashing and comparing keys with the KeyHasher.
+// Double hashing takes its step size from a remix of the key's hash.
+func NewMap[K any, V any](strategy string, capacity int, keys KeyHasher[K]) (Map[K, V], error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("capacity must be positive, got %d", capacity)
+	}
+	switch strategy {
+	case "chaining":
+		return NewChainingMap[K, V](capacity, keys), nil
+	case "flat":
+		return NewFlatChainingMap[K, V](capacity, keys), nil
+	case "linear":
+		return NewLinearProbingMap[K, V](capacity, keys), nil
+	case "quadratic":
+		return NewQuadraticProbingMap[K, V](capacity, keys), nil
+	case "double":
+		return NewDoubleHashMap[K, V](capacity, keys, nil), nil
+	case "coalesced":
+		return NewCoalescedMap[K, V](capacity, keys, 0), nil
+	}
+	return nil, fmt.Errorf("unknown strategy %q (have %s)",
+		strategy, strings.Join(StrategyNames(), ", "))
+}
+
+// Build a table of names using the named strategy and a KeyHasher such as
+// CaseInsensitiveKeys, so names are matched loosely.
+func NewTableWithKeys(strategy string, capacity int, keys KeyHasher[string]) (Table, error) {
+	m, err := NewMap[string, string](strategy, capacity, keys)
+	if err != nil {
+		return nil, err
+	}
+	return m.(Table), nil
+}
+
+// Build a table using the named strategy.
+// A nil hash function means DJB2, the hash used throughout the project.
+func NewTable(strategy string, capacity int, hash HashFunc) (Table, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("capacity must be positive, got %d", capacity)
+	}
+	if hash == nil {
+		hash = DJB2
+	}
+	for _, s := range Strategies {
+		if s.Name == strategy {
+			return s.New(capacity, hash), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown strategy %q (have %s)",
+		strategy, strings.Join(StrategyNames(), ", "))
+}
+
+// Build a table using the named strategy and key mode, such as "fold" for case-insensitive names.
+// The "exact" mode and an empty mode build the same table as NewTable.
+func NewKeyedTable(strategy string, capacity int, hash HashFunc, keyMode string) (Table, error) {
+	if keyMode == "" || keyMode == "exact" {
+		return NewTable(strategy, capacity, hash)
+	}
+	keys, err := LookupKeyMode(keyMode)
+	if err != nil {
+		return nil, err
+	}
+	return NewTableWithKeys(strategy, capacity, keys(hash))
+}
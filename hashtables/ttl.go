@@ -0,0 +1,90 @@
+package hashtables
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells a table the current time, for entries set with a time to live.
+// A nil Clock means time.Now.
+type Clock func() time.Time
+
+// ManualClock is a Clock that only moves when told to, so expiry can be tested
+// without sleeping. Pass its Now method to SetClock.
+type ManualClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// Make a manual clock that starts at this time.
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Return the clock's current time.
+func (clock *ManualClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+// Move the clock forward.
+func (clock *ManualClock) Advance(d time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = clock.now.Add(d)
+}
+
+// Return the time from the clock in Unix nanoseconds.
+func (clock Clock) unixNano() int64 {
+	if clock == nil {
+		return time.Now().UnixNano()
+	}
+	return clock().UnixNano()
+}
+
+// Return when an entry set now with this ttl expires, or 0 if a ttl of 0 or less
+// means it never does.
+func (clock Clock) expiry(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return clock.unixNano() + int64(ttl)
+}
+
+// Return true if the entry has a time to live that has run out.
+func (clock Clock) expired(expires int64) bool {
+	return expires != 0 && expires <= clock.unixNano()
+}
+
+// StartJanitor calls the table's RemoveExpired every interval, so expired entries
+// don't wait for a probe to find them. The tables are not safe for concurrent use,
+// so the janitor holds lock while it works and every other use of the table must
+// hold it too. Call the returned function to stop the janitor.
+func StartJanitor(table interface{ RemoveExpired() int }, interval time.Duration, lock sync.Locker) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				lock.Lock()
+				table.RemoveExpired()
+				lock.Unlock()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+			<-stopped
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package hashtables_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"hashtables"
+)
+
+// Build a table of each strategy on a manual clock.
+func newClockedTables(t *testing.T, capacity int) (map[string]hashtables.Table, *hashtables.ManualClock) {
+	t.Helper()
+	clock := hashtables.NewManualClock(time.Unix(1000, 0))
+	tables := make(map[string]hashtables.Table)
+	for _, strategy := range hashtables.StrategyNames() {
+		table, err := hashtables.NewTable(strategy, capacity, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		table.SetClock(clock.Now)
+		tables[strategy] = table
+	}
+	return tables, clock
+}
+
+// Return the names Range visits.
+func rangeNames(table hashtables.Table) map[string]bool {
+	names := make(map[string]bool)
+	table.Range(func(name string, phone string) bool {
+		names[name] = true
+		return true
+	})
+	return names
+}
+
+func TestTTLLookup(t *testing.T) {
+	for _, strategy := range hashtables.StrategyNames() {
+		t.Run(strategy, func(t *testing.T) {
+			tables, clock := newClockedTables(t, 8)
+			table := tables[strategy]
+			table.SetWithTTL("Ann", "1", 10*time.Second)
+			table.Set("Bob", "2")
+			table.SetWithTTL("Cid", "3", 20*time.Second)
+			table.SetWithTTL("Dee", "4", 0) // Never expires.
+
+			clock.Advance(10*time.Second - 1)
+			if phone, ok := table.Lookup("Ann"); !ok || phone != "1" {
+				t.Errorf("Ann expired early: %q, %v", phone, ok)
+			}
+
+			clock.Advance(1)
+			if _, ok := table.Lookup("Ann"); ok {
+				t.Error("Lookup found Ann after it expired")
+			}
+			if table.Contains("Ann") || table.Get("Ann") != "" {
+				t.Error("Contains or Get found Ann after it expired")
+			}
+			for _, name := range []string{"Bob", "Cid", "Dee"} {
+				if !table.Contains(name) {
+					t.Errorf("%s is missing", name)
+				}
+			}
+			if names := rangeNames(table); len(names) != 3 || names["Ann"] {
+				t.Errorf("Range visited %v", names)
+			}
+
+			// Setting a key again without a TTL keeps it.
+			table.Set("Cid", "33")
+			clock.Advance(time.Hour)
+			if phone := table.Get("Cid"); phone != "33" {
+				t.Errorf("Cid = %q after it was set without a TTL", phone)
+			}
+
+			// An expired key can be set again.
+			table.SetWithTTL("Ann", "11", time.Second)
+			if phone := table.Get("Ann"); phone != "11" {
+				t.Errorf("Ann = %q after setting it again", phone)
+			}
+		})
+	}
+}
+
+func TestRemoveExpired(t *testing.T) {
+	for _, strategy := range hashtables.StrategyNames() {
+		t.Run(strategy, func(t *testing.T) {
+			tables, clock := newClockedTables(t, 16)
+			table := tables[strategy]
+			for i := 0; i < 5; i++ {
+				table.SetWithTTL(fmt.Sprintf("temp%d", i), "x", time.Duration(i+1)*time.Second)
+			}
+			for i := 0; i < 3; i++ {
+				table.Set(fmt.Sprintf("kept%d", i), "x")
+			}
+
+			clock.Advance(2 * time.Second)
+			if removed := table.RemoveExpired(); removed != 2 {
+				t.Errorf("RemoveExpired removed %d, want 2", removed)
+			}
+			clock.Advance(time.Hour)
+			if removed := table.RemoveExpired(); removed != 3 {
+				t.Errorf("RemoveExpired removed %d, want 3", removed)
+			}
+			if removed := table.RemoveExpired(); removed != 0 {
+				t.Errorf("RemoveExpired removed %d more", removed)
+			}
+			if table.Len() != 3 {
+				t.Errorf("Len = %d, want 3", table.Len())
+			}
+		})
+	}
+}
+
+func TestJanitor(t *testing.T) {
+	for _, strategy := range hashtables.StrategyNames() {
+		t.Run(strategy, func(t *testing.T) {
+			tables, clock := newClockedTables(t, 16)
+			table := tables[strategy]
+			var lock sync.Mutex
+			for i := 0; i < 4; i++ {
+				table.SetWithTTL(fmt.Sprintf("temp%d", i), "x", time.Second)
+			}
+			table.Set("kept", "x")
+
+			stop := hashtables.StartJanitor(table, time.Millisecond, &lock)
+			defer stop()
+
+			// The janitor runs on a real ticker, but nothing expires until the
+			// manual clock moves, so only the wait for it depends on timing.
+			lock.Lock()
+			clock.Advance(time.Second)
+			lock.Unlock()
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				lock.Lock()
+				n := table.Len()
+				lock.Unlock()
+				if n == 1 {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("Len = %d after the janitor had time to run", n)
+				}
+				time.Sleep(time.Millisecond)
+			}
+			stop()
+			if !table.Contains("kept") {
+				t.Error("the janitor removed a key without a TTL")
+			}
+		})
+	}
+}
+
+// A table whose only room is held by expired entries must reuse it.
+func TestTTLNearFull(t *testing.T) {
+	for _, strategy := range hashtables.StrategyNames() {
+		t.Run(strategy, func(t *testing.T) {
+			tables, clock := newClockedTables(t, 3)
+			table := tables[strategy]
+			table.SetWithTTL("k2", "2", 2)
+			table.Set("k5", "5")
+			table.Set("k8", "8")
+			clock.Advance(4)
+			table.Set("k13", "13")
+			for _, name := range []string{"k5", "k8", "k13"} {
+				if !table.Contains(name) {
+					t.Errorf("%s is missing", name)
+				}
+			}
+			if table.Contains("k2") {
+				t.Error("k2 survived expiring")
+			}
+			table.RemoveExpired()
+			if table.Len() != 3 {
+				t.Errorf("Len = %d, want 3", table.Len())
+			}
+		})
+	}
+}
+
+func TestTTLDuringResize(t *testing.T) {
+	for _, slotsPerOp := range []int{0, 1} {
+		for _, strategy := range hashtables.StrategyNames() {
+			t.Run(fmt.Sprintf("%s/migrate=%d", strategy, slotsPerOp), func(t *testing.T) {
+				// Expired entries don't need room in the resized table.
+				tables, clock := newClockedTables(t, 4)
+				table := tables[strategy]
+				table.SetIncrementalResize(slotsPerOp)
+				table.SetWithTTL("k2", "2", 3)
+				table.SetWithTTL("k4", "4", 3)
+				clock.Advance(4)
+				table.Resize(1)
+				table.Set("k6", "6")
+				if table.Get("k6") != "6" || table.Contains("k2") || table.Contains("k4") {
+					t.Error("the resized table has the wrong keys")
+				}
+
+				// Entries that expire partway through a migration are neither
+				// found nor migrated.
+				tables, clock = newClockedTables(t, 8)
+				table = tables[strategy]
+				table.SetIncrementalResize(slotsPerOp)
+				for i := 0; i < 6; i++ {
+					if i%2 == 0 {
+						table.SetWithTTL(fmt.Sprintf("k%d", i), "x", time.Second)
+					} else {
+						table.Set(fmt.Sprintf("k%d", i), "x")
+					}
+				}
+				table.Resize(16)
+				table.Get("k1")
+				clock.Advance(time.Second)
+				for i := 0; i < 6; i++ {
+					if got, want := table.Contains(fmt.Sprintf("k%d", i)), i%2 == 1; got != want {
+						t.Errorf("Contains(k%d) = %v during the migration", i, got)
+					}
+				}
+				for i := 0; i < 32; i++ {
+					table.Get("k1")
+				}
+				table.RemoveExpired()
+				if table.Len() != 3 || len(rangeNames(table)) != 3 {
+					t.Errorf("Len = %d and Range visits %d after the migration, want 3",
+						table.Len(), len(rangeNames(table)))
+				}
+				if table.Stats().Migration != nil {
+					t.Error("the migration did not finish")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package viz
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// Describe what the colors mean.
+func (grid Grid) legend() string {
+	what := "live fraction"
+	switch {
+	case grid.Chained:
+		what = fmt.Sprintf("chain length, log scale up to %d", grid.MaxValue)
+	case grid.Mode == Clusters:
+		what = fmt.Sprintf("cluster length, log scale up to %d", grid.MaxValue)
+	}
+	return fmt.Sprintf("%d slots, %d per cell, color shows %s", grid.Capacity, grid.SlotsPerCell, what)
+}
+
+// Describe the probe path by slot ranges.
+func (grid Grid) pathDescription() string {
+	steps := make([]string, len(grid.Path))
+	for i, cellIndex := range grid.Path {
+		cell := grid.Cells[cellIndex]
+		if cell.First == cell.Last {
+			steps[i] = fmt.Sprint(cell.First)
+		} else {
+			steps[i] = fmt.Sprintf("%d-%d", cell.First, cell.Last)
+		}
+	}
+	return fmt.Sprintf("Probe path for %s: %s", grid.Probe, strings.Join(steps, " -> "))
+}
+
+// Return the marker for cells on the probe path: H for the home cell,
+// @ where the probe stops and * in between.
+func (grid Grid) markers() map[int]byte {
+	markers := make(map[int]byte)
+	for i, cellIndex := range grid.Path {
+		switch {
+		case i == len(grid.Path)-1:
+			markers[cellIndex] = '@'
+		case i == 0:
+			markers[cellIndex] = 'H'
+		default:
+			if _, ok := markers[cellIndex]; !ok {
+				markers[cellIndex] = '*'
+			}
+		}
+	}
+	return markers
+}
+
+// Draw the grid with 24-bit ANSI background colors.
+func (grid Grid) ANSI(w io.Writer) {
+	markers := grid.markers()
+	for i, cell := range grid.Cells {
+		if i%grid.Width == 0 {
+			fmt.Fprintf(w, "%7d ", cell.First)
+		}
+		c := cell.color()
+		marker, ok := markers[i]
+		if !ok {
+			marker = ' '
+		}
+		fmt.Fprintf(w, "\x1b[48;2;%d;%d;%dm\x1b[30m%c\x1b[0m", c.r, c.g, c.b, marker)
+		if i%grid.Width == grid.Width-1 {
+			fmt.Fprintln(w)
+		}
+	}
+	if len(grid.Cells)%grid.Width != 0 {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, grid.legend())
+	if grid.Probe != "" {
+		fmt.Fprintln(w, grid.pathDescription())
+	}
+}
+
+const (
+	cellSize = 14
+	margin   = 56
+)
+
+// Draw the grid as a standalone SVG image with a tooltip for each cell.
+func (grid Grid) SVG(w io.Writer) {
+	rows := (len(grid.Cells) + grid.Width - 1) / grid.Width
+	width := margin + grid.Width*cellSize + 8
+	height := rows*cellSize + 48
+
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="10">`+"\n",
+		width, height, width, height)
+	for i, cell := range grid.Cells {
+		x, y := grid.position(i)
+		if i%grid.Width == 0 {
+			fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="end">%d</text>`+"\n", margin-4, y+cellSize-3, cell.First)
+		}
+		fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="#ffffff"><title>%s</title></rect>`+"\n",
+			x, y, cellSize, cellSize, cell.color().hex(), html.EscapeString(cell.Label))
+	}
+
+	// Overlay the probe path as a line through the cell centers.
+	if len(grid.Path) > 0 {
+		points := make([]string, len(grid.Path))
+		for i, cellIndex := range grid.Path {
+			x, y := grid.position(cellIndex)
+			points[i] = fmt.Sprintf("%d,%d", x+cellSize/2, y+cellSize/2)
+		}
+		fmt.Fprintf(w, `<polyline points="%s" fill="none" stroke="#2166ac" stroke-width="2" stroke-opacity="0.8"/>`+"\n",
+			strings.Join(points, " "))
+		for i, cellIndex := range grid.Path {
+			x, y := grid.position(cellIndex)
+			fill := "#2166ac"
+			if i == 0 {
+				fill = "#1a9850"
+			} else if i == len(grid.Path)-1 {
+				fill = "#000000"
+			}
+			fmt.Fprintf(w, `<circle cx="%d" cy="%d" r="3" fill="%s"><title>step %d: %s</title></circle>`+"\n",
+				x+cellSize/2, y+cellSize/2, fill, i+1, html.EscapeString(grid.Cells[cellIndex].Label))
+		}
+	}
+
+	fmt.Fprintf(w, `<text x="%d" y="%d">%s</text>`+"\n", margin, rows*cellSize+18, html.EscapeString(grid.legend()))
+	if grid.Probe != "" {
+		fmt.Fprintf(w, `<text x="%d" y="%d">%s</text>`+"\n", margin, rows*cellSize+34, html.EscapeString(grid.pathDescription()))
+	}
+	fmt.Fprintln(w, "</svg>")
+}
+
+// Return the top left corner of a cell in the SVG.
+func (grid Grid) position(cellIndex int) (int, int) {
+	return margin + (cellIndex%grid.Width)*cellSize, (cellIndex / grid.Width) * cellSize
+}
+
+// Write a self-contained HTML page holding the SVG image.
+func (grid Grid) HTML(w io.Writer, title string) {
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.swatch { display: inline-block; width: 1em; height: 1em; vertical-align: middle; margin: 0 0.3em 0 1em; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+<p>
+<span class="swatch" style="background: %s"></span>empty
+<span class="swatch" style="background: %s"></span>deleted
+<span class="swatch" style="background: %s"></span>low
+<span class="swatch" style="background: %s"></span>high
+</p>
+`, html.EscapeString(title), html.EscapeString(title),
+		emptyColor.hex(), deletedColor.hex(), lowColor.hex(), highColor.hex())
+	grid.SVG(w)
+	fmt.Fprintln(w, "</body>\n</html>")
+}
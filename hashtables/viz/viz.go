@@ -0,0 +1,235 @@
+// Package viz draws a hash table's occupancy and probe paths as a grid of colored cells,
+// either with ANSI colors for the terminal or as a self-contained SVG or HTML file.
+//
+// Large tables are scaled down so several slots share a cell.
+package viz
+
+import (
+	"fmt"
+	"math"
+
+	"hashtables"
+)
+
+// Mode selects what the cell colors show.
+type Mode int
+
+const (
+	// Occupancy colors each cell by the fraction of its slots holding live entries
+	// (or, for chaining, by its average chain length).
+	Occupancy Mode = iota
+	// Clusters colors each slot by the length of the run of non-empty slots it belongs to
+	// (or, for chaining, by its chain length).
+	Clusters
+)
+
+// Parse a mode name.
+func ParseMode(name string) (Mode, error) {
+	switch name {
+	case "occupancy", "heatmap":
+		return Occupancy, nil
+	case "clusters":
+		return Clusters, nil
+	}
+	return 0, fmt.Errorf("unknown mode %q (have occupancy, clusters)", name)
+}
+
+// Options control how a table is drawn.
+type Options struct {
+	Mode    Mode
+	Width   int    // Cells per row. The default is 50, like dumpConcise.
+	MaxRows int    // Rows before slots are grouped into cells. The default is 40.
+	Probe   string // If not empty, overlay this key's probe path.
+}
+
+// Cell is one drawn square, covering one or more slots.
+type Cell struct {
+	First, Last int     // The range of slots the cell covers.
+	Value       float64 // The color intensity from 0 to 1.
+	Live        int
+	Deleted     int
+	Longest     int // The longest cluster or chain in the cell.
+	Label       string
+}
+
+// Grid is a table laid out as rows of cells.
+type Grid struct {
+	Cells        []Cell
+	Width        int
+	SlotsPerCell int
+	Capacity     int
+	Mode         Mode
+	Chained      bool  // The slots are chaining buckets.
+	MaxValue     int   // The cluster or chain length that gets full color.
+	Path         []int // The cells on the probe path, in order.
+	Probe        string
+}
+
+// Build the grid for a table.
+func Build(table hashtables.Table, options Options) Grid {
+	width := options.Width
+	if width <= 0 {
+		width = 50
+	}
+	maxRows := options.MaxRows
+	if maxRows <= 0 {
+		maxRows = 40
+	}
+
+	slots := table.Slots()
+	slotsPerCell := max(1, (len(slots)+width*maxRows-1)/(width*maxRows))
+	grid := Grid{
+		Width:        width,
+		SlotsPerCell: slotsPerCell,
+		Capacity:     len(slots),
+		Mode:         options.Mode,
+		Probe:        options.Probe,
+		Chained:      isChaining(slots),
+	}
+
+	lengths := runLengths(slots)
+	for _, length := range lengths {
+		grid.MaxValue = max(grid.MaxValue, length)
+	}
+
+	for first := 0; first < len(slots); first += slotsPerCell {
+		last := min(first+slotsPerCell, len(slots)) - 1
+		cell := Cell{First: first, Last: last}
+		entries := 0
+		for i := first; i <= last; i++ {
+			switch slots[i].State {
+			case hashtables.SlotLive:
+				cell.Live++
+				entries += slots[i].Entries
+			case hashtables.SlotDeleted:
+				cell.Deleted++
+			}
+			cell.Longest = max(cell.Longest, lengths[i])
+		}
+
+		numSlots := float64(last - first + 1)
+		switch {
+		case options.Mode == Clusters:
+			cell.Value = logScale(cell.Longest, grid.MaxValue)
+		case grid.Chained:
+			cell.Value = logScale(int(math.Ceil(float64(entries)/numSlots)), grid.MaxValue)
+		default:
+			cell.Value = float64(cell.Live) / numSlots
+		}
+		cell.Label = label(slots, cell)
+		grid.Cells = append(grid.Cells, cell)
+	}
+
+	// Map the probe path's slots to cells, merging repeated visits to the same cell.
+	if options.Probe != "" {
+		for _, index := range table.ProbePath(options.Probe) {
+			cellIndex := index / slotsPerCell
+			if len(grid.Path) == 0 || grid.Path[len(grid.Path)-1] != cellIndex {
+				grid.Path = append(grid.Path, cellIndex)
+			}
+		}
+	}
+	return grid
+}
+
+// Return true if the slots are chaining buckets, which can hold several entries.
+func isChaining(slots []hashtables.Slot) bool {
+	return len(slots) > 0 && slots[0].Chained
+}
+
+// Return the length of the cluster each slot belongs to, or the chain length for buckets.
+// Clusters wrap around the end of the table, like the probe sequences.
+func runLengths(slots []hashtables.Slot) []int {
+	lengths := make([]int, len(slots))
+	if isChaining(slots) {
+		for i, slot := range slots {
+			lengths[i] = slot.Entries
+		}
+		return lengths
+	}
+
+	// Start just after an empty slot so no cluster is split by the wrap.
+	start := -1
+	for i, slot := range slots {
+		if slot.State == hashtables.SlotEmpty {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		for i := range lengths {
+			lengths[i] = len(slots)
+		}
+		return lengths
+	}
+
+	run := 0
+	for i := 1; i <= len(slots); i++ {
+		index := (start + i) % len(slots)
+		if slots[index].State != hashtables.SlotEmpty {
+			run++
+			continue
+		}
+
+		// Fill in the run that just ended.
+		for j := 1; j <= run; j++ {
+			lengths[(index-j+len(slots))%len(slots)] = run
+		}
+		run = 0
+	}
+	return lengths
+}
+
+// Map a length onto 0 to 1 with a log scale so short clusters still show.
+func logScale(length int, maxLength int) float64 {
+	if length <= 0 || maxLength <= 0 {
+		return 0
+	}
+	return math.Log1p(float64(length)) / math.Log1p(float64(maxLength))
+}
+
+// Describe a cell for tooltips.
+func label(slots []hashtables.Slot, cell Cell) string {
+	if cell.First == cell.Last {
+		slot := slots[cell.First]
+		switch {
+		case slot.State == hashtables.SlotDeleted:
+			return fmt.Sprintf("%d: deleted", cell.First)
+		case slot.State == hashtables.SlotEmpty:
+			return fmt.Sprintf("%d: empty", cell.First)
+		case slot.Chained:
+			return fmt.Sprintf("%d: %d entries", cell.First, slot.Entries)
+		}
+		return fmt.Sprintf("%d: %s (home %d, run %d)", cell.First, slot.Name, slot.Home, cell.Longest)
+	}
+	return fmt.Sprintf("%d-%d: %d live, %d deleted, longest run %d",
+		cell.First, cell.Last, cell.Live, cell.Deleted, cell.Longest)
+}
+
+// color is an RGB color.
+type color struct{ r, g, b uint8 }
+
+var (
+	emptyColor   = color{240, 240, 240}
+	deletedColor = color{150, 150, 150}
+	lowColor     = color{255, 237, 160}
+	highColor    = color{189, 0, 38}
+)
+
+// Return the fill color for a cell.
+func (cell Cell) color() color {
+	if cell.Value == 0 {
+		if cell.Deleted > 0 {
+			return deletedColor
+		}
+		return emptyColor
+	}
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*cell.Value)
+	}
+	return color{mix(lowColor.r, highColor.r), mix(lowColor.g, highColor.g), mix(lowColor.b, highColor.b)}
+}
+
+func (c color) hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.r, c.g, c.b)
+}
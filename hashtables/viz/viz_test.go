@@ -0,0 +1,197 @@
+package viz
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"hashtables"
+)
+
+// Hash a key like "7:Ann" to 7, so tests can place keys in chosen slots.
+func homeHash(key string) int {
+	home, _, _ := strings.Cut(key, ":")
+	n, err := strconv.Atoi(home)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Build a linear probing table and set keys of the form "home:name".
+func newTable(t *testing.T, capacity int, keys ...string) hashtables.Table {
+	t.Helper()
+	table := hashtables.NewLinearProbingHashTable(capacity, homeHash)
+	for _, key := range keys {
+		table.Set(key, "1")
+	}
+	return table
+}
+
+// Return slots that are live where the pattern has an x and empty elsewhere.
+func patternSlots(pattern string) []hashtables.Slot {
+	slots := make([]hashtables.Slot, len(pattern))
+	for i, c := range pattern {
+		if c == 'x' {
+			slots[i] = hashtables.Slot{State: hashtables.SlotLive, Entries: 1}
+		}
+	}
+	return slots
+}
+
+func TestRunLengths(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		want    []int
+	}{
+		{"..........", []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"xx..xx..xx", []int{4, 4, 0, 0, 2, 2, 0, 0, 4, 4}}, // The last run wraps into the first.
+		{"x.xxx....x", []int{2, 0, 3, 3, 3, 0, 0, 0, 0, 2}},
+		{"xxxxxxxx.x", []int{9, 9, 9, 9, 9, 9, 9, 9, 0, 9}},
+		{"xxxxx", []int{5, 5, 5, 5, 5}}, // No empty slot to start from.
+	} {
+		if got := runLengths(patternSlots(test.pattern)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("runLengths(%s) = %v, want %v", test.pattern, got, test.want)
+		}
+	}
+
+	// A deleted slot doesn't end a cluster.
+	slots := patternSlots("x.xx.")
+	slots[1].State = hashtables.SlotDeleted
+	if got := runLengths(slots); !reflect.DeepEqual(got, []int{4, 4, 4, 4, 0}) {
+		t.Errorf("runLengths with a deleted slot = %v", got)
+	}
+
+	// Buckets report their chain lengths.
+	buckets := []hashtables.Slot{{Chained: true, Entries: 3}, {Chained: true}, {Chained: true, Entries: 1}}
+	if got := runLengths(buckets); !reflect.DeepEqual(got, []int{3, 0, 1}) {
+		t.Errorf("runLengths of buckets = %v", got)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	// A full table has one cluster the size of the table.
+	full := Build(newTable(t, 5, "0:a", "0:b", "3:c", "3:d", "1:e"), Options{Mode: Clusters})
+	if full.MaxValue != 5 || full.SlotsPerCell != 1 || len(full.Cells) != 5 {
+		t.Fatalf("a full table gives %+v", full)
+	}
+	for _, cell := range full.Cells {
+		if cell.Longest != 5 || cell.Value != 1 || cell.Live != 1 {
+			t.Errorf("cell %+v in a full table", cell)
+		}
+	}
+
+	// More slots than cells puts several slots in each cell.
+	var keys []string
+	for i := 0; i < 100; i += 4 {
+		keys = append(keys, strconv.Itoa(i)+":k")
+	}
+	table := newTable(t, 100, keys...)
+	grid := Build(table, Options{Width: 5, MaxRows: 4})
+	if grid.SlotsPerCell != 5 || len(grid.Cells) != 20 || grid.Capacity != 100 {
+		t.Fatalf("100 slots in 20 cells give %d per cell and %d cells", grid.SlotsPerCell, len(grid.Cells))
+	}
+	live := 0
+	for i, cell := range grid.Cells {
+		if cell.First != i*5 || cell.Last != i*5+4 {
+			t.Errorf("cell %d covers %d-%d", i, cell.First, cell.Last)
+		}
+		live += cell.Live
+		if want := float64(cell.Live) / 5; cell.Value != want {
+			t.Errorf("cell %d has value %v, want %v", i, cell.Value, want)
+		}
+	}
+	if live != table.Len() {
+		t.Errorf("the cells hold %d live slots, want %d", live, table.Len())
+	}
+	if label := grid.Cells[0].Label; label != "0-4: 2 live, 0 deleted, longest run 1" {
+		t.Errorf("the first cell's label is %q", label)
+	}
+
+	// A table that doesn't divide evenly has a short last cell.
+	uneven := Build(newTable(t, 23), Options{Width: 2, MaxRows: 2})
+	if last := uneven.Cells[len(uneven.Cells)-1]; uneven.SlotsPerCell != 6 || last.First != 18 || last.Last != 22 {
+		t.Errorf("the last of 23 slots in cells of %d covers %d-%d", uneven.SlotsPerCell, last.First, last.Last)
+	}
+}
+
+func TestBuildProbePath(t *testing.T) {
+	// The probe for 98:z visits slots 98, 99, 0, 1 and 2, then stops at the empty slot 3.
+	table := newTable(t, 100, "98:a", "98:b", "98:c", "0:d", "0:e")
+	for _, test := range []struct {
+		width, maxRows int
+		want           []int
+	}{
+		{10, 10, []int{98, 99, 0, 1, 2, 3}},
+		{5, 10, []int{49, 0, 1}}, // Two slots per cell.
+		{5, 4, []int{19, 0}},     // Five slots per cell.
+	} {
+		grid := Build(table, Options{Width: test.width, MaxRows: test.maxRows, Probe: "98:z"})
+		if !reflect.DeepEqual(grid.Path, test.want) {
+			t.Errorf("%d slots per cell: path %v, want %v", grid.SlotsPerCell, grid.Path, test.want)
+		}
+	}
+
+	grid := Build(table, Options{Width: 5, MaxRows: 4, Probe: "98:z"})
+	if got := grid.pathDescription(); got != "Probe path for 98:z: 95-99 -> 0-4" {
+		t.Errorf("path description %q", got)
+	}
+	if markers := grid.markers(); !reflect.DeepEqual(markers, map[int]byte{19: 'H', 0: '@'}) {
+		t.Errorf("markers %q", markers)
+	}
+}
+
+// Check that data is well-formed XML.
+func checkXML(t *testing.T, data string) {
+	t.Helper()
+	decoder := xml.NewDecoder(strings.NewReader(data))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatalf("%v in\n%s", err, data)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	// Keys and titles with markup characters are escaped.
+	table := newTable(t, 30, `3:<Ann & "Bob">`, "3:Cid", "4:Dee")
+	table.Delete("3:Cid")
+	for _, mode := range []Mode{Occupancy, Clusters} {
+		grid := Build(table, Options{Mode: mode, Width: 7, Probe: `3:<Ann & "Bob">`})
+
+		var svg bytes.Buffer
+		grid.SVG(&svg)
+		checkXML(t, svg.String())
+		if n := strings.Count(svg.String(), "<rect "); n != 30 {
+			t.Errorf("the SVG has %d cells, want 30", n)
+		}
+		if !strings.Contains(svg.String(), "<polyline ") || strings.Count(svg.String(), "<circle ") != len(grid.Path) {
+			t.Errorf("the SVG doesn't draw the %d steps of the probe path", len(grid.Path))
+		}
+
+		var page bytes.Buffer
+		grid.HTML(&page, "Phones <draft>")
+		html := page.String()
+		start, end := strings.Index(html, "<svg "), strings.Index(html, "</svg>")
+		if !strings.HasPrefix(html, "<!DOCTYPE html>") || !strings.HasSuffix(html, "</html>\n") || start < 0 || end < start {
+			t.Fatalf("the page isn't an HTML document holding an SVG:\n%s", html)
+		}
+		checkXML(t, html[start:end+len("</svg>")])
+		if strings.Count(html, "<title>Phones &lt;draft&gt;</title>") != 1 || strings.Contains(html, "<draft>") {
+			t.Error("the page title isn't escaped")
+		}
+
+		var ansi bytes.Buffer
+		grid.ANSI(&ansi)
+		lines := strings.Split(strings.TrimSuffix(ansi.String(), "\n"), "\n")
+		if len(lines) != 7 || !strings.HasPrefix(lines[5], "30 slots, 1 per cell") || !strings.HasPrefix(lines[6], "Probe path") {
+			t.Errorf("the ANSI grid has %d lines:\n%s", len(lines), ansi.String())
+		}
+	}
+}
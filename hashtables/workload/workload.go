@@ -0,0 +1,148 @@
+// Package workload generates reproducible key sets for exercising the hash tables.
+package workload
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Generator returns n distinct keys. The same n and seed always give the same keys.
+type Generator func(n int, seed int64) []string
+
+// Generators holds the named key generators that tools can choose from.
+var Generators = map[string]Generator{
+	"pairs":      Pairs,
+	"prefix":     CommonPrefix,
+	"sequential": Sequential,
+	"uniform":    Uniform,
+}
+
+// Return the names of the registered generators in sorted order.
+func GeneratorNames() []string {
+	names := make([]string, 0, len(Generators))
+	for name := range Generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Return the registered generator with this name.
+func Lookup(name string) (Generator, error) {
+	generator, ok := Generators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown generator %q (have %v)", name, GeneratorNames())
+	}
+	return generator, nil
+}
+
+// Pairs makes keys like "17-482913", the keys used by the clustering experiments.
+func Pairs(n int, seed int64) []string {
+	random := rand.New(rand.NewSource(seed))
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%d-%d", i, random.Intn(1000000))
+	}
+	return keys
+}
+
+// Sequential makes the keys "0", "1", "2" and so on. The seed is ignored.
+func Sequential(n int, seed int64) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprint(i)
+	}
+	return keys
+}
+
+// Uniform makes random lowercase keys of 8 to 16 letters.
+func Uniform(n int, seed int64) []string {
+	random := rand.New(rand.NewSource(seed))
+	keys := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for len(keys) < n {
+		letters := make([]byte, 8+random.Intn(9))
+		for i := range letters {
+			letters[i] = byte('a' + random.Intn(26))
+		}
+		key := string(letters)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// CommonPrefix makes keys that share a long prefix and differ only in a short random suffix,
+// like paths or qualified names.
+func CommonPrefix(n int, seed int64) []string {
+	const prefix = "corp/engineering/platform/employees/"
+	suffixes := Uniform(n, seed)
+	keys := make([]string, n)
+	for i, suffix := range suffixes {
+		keys[i] = prefix + suffix[:6] + fmt.Sprint(i)
+	}
+	return keys
+}
+
+// Accesses returns numAccesses indices into a slice of numKeys keys.
+// If skew is greater than 1, the indices follow a Zipf distribution with that exponent,
+// so a few keys get most of the accesses. Otherwise they are uniform.
+// With no keys there is nothing to access, so it returns nil.
+func Accesses(numKeys int, numAccesses int, seed int64, skew float64) []int {
+	if numKeys <= 0 {
+		return nil
+	}
+	random := rand.New(rand.NewSource(seed))
+	accesses := make([]int, numAccesses)
+	if skew > 1 && numKeys > 1 {
+		zipf := rand.NewZipf(random, skew, 1, uint64(numKeys-1))
+		for i := range accesses {
+			accesses[i] = int(zipf.Uint64())
+		}
+		return accesses
+	}
+	for i := range accesses {
+		accesses[i] = random.Intn(numKeys)
+	}
+	return accesses
+}
+
+// Read one key per line, skipping blank lines and repeated keys.
+func ReadKeys(r io.Reader) ([]string, error) {
+	var keys []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key := strings.TrimSpace(scanner.Text())
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys, scanner.Err()
+}
+
+// Misses returns one key for each input key that is not among the input keys.
+func Misses(keys []string) []string {
+	present := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		present[key] = true
+	}
+
+	misses := make([]string, len(keys))
+	for i, key := range keys {
+		miss := key + "#"
+		for present[miss] {
+			miss += "#"
+		}
+		misses[i] = miss
+	}
+	return misses
+}
@@ -0,0 +1,103 @@
+package workload
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerators(t *testing.T) {
+	for _, name := range GeneratorNames() {
+		generator, err := Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range []int{0, 1, 1000} {
+			keys := generator(n, 7)
+			if len(keys) != n {
+				t.Fatalf("%s: %d keys, want %d", name, len(keys), n)
+			}
+			seen := make(map[string]bool)
+			for _, key := range keys {
+				if seen[key] {
+					t.Fatalf("%s: %q repeats", name, key)
+				}
+				seen[key] = true
+			}
+			if again := generator(n, 7); !reflect.DeepEqual(again, keys) {
+				t.Errorf("%s: the same seed gave different keys", name)
+			}
+		}
+		if name != "sequential" && reflect.DeepEqual(generator(100, 7), generator(100, 8)) {
+			t.Errorf("%s: different seeds gave the same keys", name)
+		}
+	}
+	if _, err := Lookup("bogus"); err == nil {
+		t.Error("found a bogus generator")
+	}
+}
+
+func TestAccesses(t *testing.T) {
+	for _, skew := range []float64{0, 1.2} {
+		accesses := Accesses(50, 10000, 7, skew)
+		if len(accesses) != 10000 || !reflect.DeepEqual(accesses, Accesses(50, 10000, 7, skew)) {
+			t.Fatalf("skew %v: %d accesses, or a different set for the same seed", skew, len(accesses))
+		}
+		counts := make([]int, 50)
+		for _, index := range accesses {
+			if index < 0 || index >= 50 {
+				t.Fatalf("skew %v: index %d is out of range", skew, index)
+			}
+			counts[index]++
+		}
+		// A skewed workload hits the first key far more than its share.
+		if skew > 1 && counts[0] < 10000/50*5 {
+			t.Errorf("skew %v: the first key got %d accesses", skew, counts[0])
+		}
+		if skew == 0 && (counts[0] < 100 || counts[0] > 300) {
+			t.Errorf("uniform: the first key got %d accesses", counts[0])
+		}
+	}
+	for _, numKeys := range []int{0, -1} {
+		if accesses := Accesses(numKeys, 10, 7, 1.2); accesses != nil {
+			t.Errorf("%d keys gave accesses %v", numKeys, accesses)
+		}
+	}
+	if accesses := Accesses(1, 5, 7, 1.2); !reflect.DeepEqual(accesses, []int{0, 0, 0, 0, 0}) {
+		t.Errorf("one key gave accesses %v", accesses)
+	}
+}
+
+func TestMisses(t *testing.T) {
+	keys := []string{"Ann", "Ann#", "Bob"}
+	misses := Misses(keys)
+	if len(misses) != len(keys) {
+		t.Fatalf("%d misses for %d keys", len(misses), len(keys))
+	}
+	for _, miss := range misses {
+		for _, key := range keys {
+			if miss == key {
+				t.Errorf("the miss %q is a key", miss)
+			}
+		}
+	}
+	if misses[0] != "Ann##" || misses[2] != "Bob#" {
+		t.Errorf("misses %v", misses)
+	}
+	if len(Misses(nil)) != 0 {
+		t.Error("no keys gave misses")
+	}
+}
+
+func TestReadKeys(t *testing.T) {
+	keys, err := ReadKeys(strings.NewReader("Ann\n\n  Bob  \r\nAnn\nCid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Ann", "Bob", "Cid"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("ReadKeys = %q, want %q", keys, want)
+	}
+	if keys, err := ReadKeys(strings.NewReader("")); err != nil || len(keys) != 0 {
+		t.Errorf("an empty file gave %q, %v", keys, err)
+	}
+}
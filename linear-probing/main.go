@@ -49,6 +49,7 @@ func main() {
 	bigHashTable.dumpConcise()
 	fmt.Printf("Average probe sequence length: %f\n",
 		bigHashTable.aveProbeSequenceLength())
+	bigHashTable.stats().dump()
 }
 
 // djb2 hash function. See http://www.cse.yorku.ca/~oz/hash.html.
@@ -212,5 +213,10 @@ func (hashTable *LinearProbingHashTable) aveProbeSequenceLength() float32 {
 			numValues++
 		}
 	}
+
+	// An empty table has no probe sequences to average.
+	if numValues == 0 {
+		return 0
+	}
 	return float32(totalLength) / float32(numValues)
 }
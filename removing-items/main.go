@@ -67,6 +67,7 @@ func main() {
 	bigHashTable.dumpConcise()
 	fmt.Printf("Average probe sequence length: %f\n",
 		bigHashTable.aveProbeSequenceLength())
+	bigHashTable.stats().dump()
 }
 
 // djb2 hash function. See http://www.cse.yorku.ca/~oz/hash.html.
@@ -253,12 +254,17 @@ func (hashTable *LinearProbingHashTable) aveProbeSequenceLength() float32 {
 	totalLength := 0
 	numValues := 0
 	for _, employee := range hashTable.employees {
-		if employee != nil {
+		if employee != nil && !employee.deleted {
 			_, probeLength := hashTable.find(employee.name)
 			totalLength += probeLength
 			numValues++
 		}
 	}
+
+	// An empty table has no probe sequences to average.
+	if numValues == 0 {
+		return 0
+	}
 	return float32(totalLength) / float32(numValues)
 }
 
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ProbeStats summarizes a set of probe sequence lengths.
+type ProbeStats struct {
+	Count     int
+	Mean      float64
+	Median    int
+	P99       int
+	Max       int
+	Histogram []int // Histogram[n] is the number of probe sequences of length n.
+}
+
+// Stats describes the occupancy and probe behavior of a hash table.
+type Stats struct {
+	Capacity   int
+	Live       int
+	Deleted    int
+	Empty      int
+	LoadFactor float64 // Live entries divided by capacity.
+
+	Successful   ProbeStats // Probes needed to find each live key.
+	Unsuccessful ProbeStats // Probes needed to miss, starting from each slot.
+
+	MaxDisplacement int   // Largest distance between a live key's home slot and its slot.
+	ClusterSizes    []int // ClusterSizes[n] is the number of runs of n consecutive non-empty slots.
+	MaxCluster      int
+}
+
+// Summarize a set of probe sequence lengths.
+func newProbeStats(lengths []int) ProbeStats {
+	probeStats := ProbeStats{Count: len(lengths)}
+	if len(lengths) == 0 {
+		return probeStats
+	}
+
+	sorted := append([]int(nil), lengths...)
+	sort.Ints(sorted)
+
+	total := 0
+	for _, length := range sorted {
+		total += length
+	}
+	probeStats.Mean = float64(total) / float64(len(sorted))
+	probeStats.Median = percentile(sorted, 50)
+	probeStats.P99 = percentile(sorted, 99)
+	probeStats.Max = sorted[len(sorted)-1]
+
+	probeStats.Histogram = make([]int, probeStats.Max+1)
+	for _, length := range sorted {
+		probeStats.Histogram[length]++
+	}
+	return probeStats
+}
+
+// Return the nearest-rank percentile of a sorted, non-empty slice.
+func percentile(sorted []int, p int) int {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Collect the stats for the table.
+func (hashTable *LinearProbingHashTable) stats() Stats {
+	stats := Stats{Capacity: hashTable.capacity}
+
+	// Count the slots and measure each live key's probe sequence.
+	var successful []int
+	for index, employee := range hashTable.employees {
+		if employee == nil {
+			stats.Empty++
+			continue
+		}
+		if employee.deleted {
+			stats.Deleted++
+			continue
+		}
+		stats.Live++
+
+		_, probeLength := hashTable.find(employee.name)
+		successful = append(successful, probeLength)
+
+		home := hash(employee.name) % hashTable.capacity
+		displacement := (index - home + hashTable.capacity) % hashTable.capacity
+		if displacement > stats.MaxDisplacement {
+			stats.MaxDisplacement = displacement
+		}
+	}
+	if hashTable.capacity > 0 {
+		stats.LoadFactor = float64(stats.Live) / float64(hashTable.capacity)
+	}
+	stats.Successful = newProbeStats(successful)
+
+	// A miss that starts at a slot keeps probing until it reaches an empty spot.
+	var unsuccessful []int
+	for home := 0; home < hashTable.capacity; home++ {
+		probeLength := hashTable.capacity
+		for i := 0; i < hashTable.capacity; i++ {
+			if hashTable.employees[(home+i)%hashTable.capacity] == nil {
+				probeLength = i + 1
+				break
+			}
+		}
+		unsuccessful = append(unsuccessful, probeLength)
+	}
+	stats.Unsuccessful = newProbeStats(unsuccessful)
+
+	stats.ClusterSizes, stats.MaxCluster = clusterSizes(hashTable.employees)
+	return stats
+}
+
+// Count the runs of consecutive non-empty slots, wrapping around the end of the slice.
+// Return the cluster size distribution and the largest cluster.
+func clusterSizes(employees []*Employee) ([]int, int) {
+	// Start just after an empty slot so no cluster is split by the wrap.
+	start := -1
+	for i, employee := range employees {
+		if employee == nil {
+			start = i
+			break
+		}
+	}
+
+	// If there are no empty slots, the whole table is one cluster.
+	if start < 0 {
+		if len(employees) == 0 {
+			return nil, 0
+		}
+		sizes := make([]int, len(employees)+1)
+		sizes[len(employees)] = 1
+		return sizes, len(employees)
+	}
+
+	var sizes []int
+	maxSize := 0
+	run := 0
+	for i := 1; i <= len(employees); i++ {
+		if employees[(start+i)%len(employees)] != nil {
+			run++
+			continue
+		}
+		if run > 0 {
+			for len(sizes) <= run {
+				sizes = append(sizes, 0)
+			}
+			sizes[run]++
+			if run > maxSize {
+				maxSize = run
+			}
+		}
+		run = 0
+	}
+	return sizes, maxSize
+}
+
+// Display the stats.
+func (stats Stats) dump() {
+	fmt.Printf("Capacity: %d, live: %d, deleted: %d, empty: %d, load factor: %.3f\n",
+		stats.Capacity, stats.Live, stats.Deleted, stats.Empty, stats.LoadFactor)
+	stats.Successful.dump("Successful probes")
+	stats.Unsuccessful.dump("Unsuccessful probes")
+	fmt.Printf("Max displacement: %d\n", stats.MaxDisplacement)
+	fmt.Printf("Max cluster: %d\n", stats.MaxCluster)
+	fmt.Printf("Cluster sizes (size:count):")
+	for size, count := range stats.ClusterSizes {
+		if count > 0 {
+			fmt.Printf(" %d:%d", size, count)
+		}
+	}
+	fmt.Println()
+}
+
+// Display the probe stats with a label.
+func (probeStats ProbeStats) dump(label string) {
+	fmt.Printf("%s: mean %.3f, median %d, p99 %d, max %d\n",
+		label, probeStats.Mean, probeStats.Median, probeStats.P99, probeStats.Max)
+	fmt.Printf("    Histogram (length:count):")
+	for length, count := range probeStats.Histogram {
+		if count > 0 {
+			fmt.Printf(" %d:%d", length, count)
+		}
+	}
+	fmt.Println()
+}